@@ -22,34 +22,121 @@ type Config struct {
 	Protocol       string   `yaml:"protocol"`       // 默认查询协议 udp/tcp
 	MySQLDSN       string   `yaml:"mysqlDSN"`       // MySQL 连接串
 
-	DefaultPageSize int `yaml:"pageSize"`
-	MaxPageSize     int `yaml:"maxPageSize"`
-	TokenTTLSeconds int `yaml:"tokenTTLSeconds"`
-
-	APIKeyRequired              bool   `yaml:"apiKeyRequired"`
-	BootstrapEnabled            bool   `yaml:"bootstrapEnabled"`
-	BootstrapToken              string `yaml:"bootstrapToken"`
-	RateLimitEnabled            bool   `yaml:"rateLimitEnabled"`
-	RateLimitWindowSeconds      int    `yaml:"rateLimitWindowSeconds"`
-	RateLimitMaxRequests        int    `yaml:"rateLimitMaxRequests"`
-	DNSRateLimitEnabled         bool   `yaml:"dnsRateLimitEnabled"`
-	DNSRateLimitWindowSeconds   int    `yaml:"dnsRateLimitWindowSeconds"`
-	DNSRateLimitMaxRequests     int    `yaml:"dnsRateLimitMaxRequests"`
-	RedisAddr                   string `yaml:"redisAddr"`
-	RedisPassword               string `yaml:"redisPassword"`
-	RedisDB                     int    `yaml:"redisDB"`
-	AuditEnabled                bool   `yaml:"auditEnabled"`
-	PublicConfig                bool   `yaml:"publicConfig"`
-	WebhookEnabled              bool   `yaml:"webhookEnabled"`
-	WebhookMaxRetries           int    `yaml:"webhookMaxRetries"`
-	WebhookRetryIntervalSeconds int    `yaml:"webhookRetryIntervalSeconds"`
-	WebhookSecretKey            string `yaml:"webhookSecretKey"`
-	MetricsEnabled              bool   `yaml:"metricsEnabled"`
-	MetricsPublic               bool   `yaml:"metricsPublic"`
-	RetentionEnabled            bool   `yaml:"retentionEnabled"`
-	RecordRetentionDays         int    `yaml:"recordRetentionDays"`
-	RetentionIntervalSeconds    int    `yaml:"retentionIntervalSeconds"`
-	RetentionBatchSize          int    `yaml:"retentionBatchSize"`
+	TLSEnabled        bool     `yaml:"tlsEnabled"` // HTTP API 直接提供 HTTPS
+	TLSCertFile       string   `yaml:"tlsCertFile"`
+	TLSKeyFile        string   `yaml:"tlsKeyFile"`
+	TLSMinVersion     string   `yaml:"tlsMinVersion"`     // 1.2 / 1.3
+	TLSReloadSeconds  int      `yaml:"tlsReloadSeconds"`  // 检查证书文件变化的间隔，0 表示仅 SIGHUP 时重新加载
+	HTTPRedirectAddr  string   `yaml:"httpRedirectAddr"`  // 非空时在该地址监听 HTTP 并跳转到 HTTPS
+	TLSClientCAFile   string   `yaml:"tlsClientCAFile"`   // 双向 TLS 校验客户端证书的 CA
+	TLSClientAuth     string   `yaml:"tlsClientAuth"`     // optional / require
+	TLSClientCertKeys []string `yaml:"tlsClientCertKeys"` // 客户端证书到 API Key 的映射
+
+	ACMEEnabled      bool   `yaml:"acmeEnabled"` // 通过 ACME DNS-01 自动申请 *.<rootDomain> 证书（需 tlsEnabled）
+	ACMEDirectoryURL string `yaml:"acmeDirectoryURL"`
+	ACMEEmail        string `yaml:"acmeEmail"`
+	ACMECacheDir     string `yaml:"acmeCacheDir"`  // 账户私钥与证书保存目录
+	ACMECAFile       string `yaml:"acmeCAFile"`    // 额外信任的 ACME 服务端 CA（如 Pebble 测试环境）
+	ACMERenewDays    int    `yaml:"acmeRenewDays"` // 距过期不足该天数时续期
+
+	CORSAllowedOrigins   []string `yaml:"corsAllowedOrigins"`   // 允许的跨域来源，为空表示不允许跨域
+	CORSGroupOrigins     []string `yaml:"corsGroupOrigins"`     // 按路由前缀覆盖，如 "/keys=https://admin.example.com"
+	CORSAllowCredentials bool     `yaml:"corsAllowCredentials"` // 跨域请求可携带 cookie
+	CORSMaxAgeSeconds    int      `yaml:"corsMaxAgeSeconds"`    // 预检结果缓存时间
+
+	HTTPCaptureEnabled   bool   `yaml:"httpCaptureEnabled"`   // HTTP/HTTPS 回连捕获（盲 SSRF、XXE 等）
+	HTTPCaptureAddr      string `yaml:"httpCaptureAddr"`      // HTTP 捕获监听地址
+	HTTPCaptureTLSAddr   string `yaml:"httpCaptureTLSAddr"`   // HTTPS 捕获监听地址，复用 API 的证书（需 tlsEnabled）
+	HTTPCaptureBodyLimit int    `yaml:"httpCaptureBodyLimit"` // 保存的请求体最大字节数
+
+	SMTPCaptureEnabled  bool   `yaml:"smtpCaptureEnabled"`  // SMTP 邮件捕获，接收发往 <任意>@<token>.<root> 的邮件
+	SMTPCaptureAddr     string `yaml:"smtpCaptureAddr"`     // SMTP 监听地址
+	SMTPCaptureHostname string `yaml:"smtpCaptureHostname"` // 问候语中的主机名，默认 rootDomain
+	SMTPCaptureMaxBytes int    `yaml:"smtpCaptureMaxBytes"` // 保存的单封邮件最大字节数
+
+	LDAPCaptureEnabled  bool   `yaml:"ldapCaptureEnabled"`  // LDAP 回连捕获（JNDI 注入）
+	LDAPCaptureAddr     string `yaml:"ldapCaptureAddr"`     // LDAP 监听地址
+	LDAPCaptureReferral string `yaml:"ldapCaptureReferral"` // 搜索返回的 referral，{token} 替换为 token；空表示返回空结果
+	RMICaptureEnabled   bool   `yaml:"rmiCaptureEnabled"`   // RMI 注册中心回连捕获
+	RMICaptureAddr      string `yaml:"rmiCaptureAddr"`      // RMI 监听地址
+
+	RawCaptureListeners        []string `yaml:"rawCaptureListeners"`        // 原始端口监听，如 "tcp/4444"、"udp/5000"、"tcp/2222=SSH-2.0-OpenSSH_8.9\r\n"
+	RawCaptureReadBytes        int      `yaml:"rawCaptureReadBytes"`        // 每个连接/数据报保存的前 N 字节
+	RawCaptureIdleSeconds      int      `yaml:"rawCaptureIdleSeconds"`      // TCP 连接无数据多久后断开
+	RawCaptureCorrelateSeconds int      `yaml:"rawCaptureCorrelateSeconds"` // 载荷中没有 token 时，按来源 IP 关联该时间内的 DNS 命中；0 表示不关联
+	RawCaptureRateLimit        int      `yaml:"rawCaptureRateLimit"`        // 每个来源 IP 每分钟最多处理的连接/数据报数；0 表示不限制
+
+	DefaultPageSize     int `yaml:"pageSize"`
+	MaxPageSize         int `yaml:"maxPageSize"`
+	TokenTTLSeconds     int `yaml:"tokenTTLSeconds"`
+	TokenWaitMaxSeconds int `yaml:"tokenWaitMaxSeconds"` // GET /tokens/:token/wait 允许的最长等待时间
+
+	APIKeyRequired              bool     `yaml:"apiKeyRequired"`
+	APIKeyRotationGraceSeconds  int      `yaml:"apiKeyRotationGraceSeconds"`
+	APIKeyTouchIntervalSeconds  int      `yaml:"apiKeyTouchIntervalSeconds"`
+	SessionTTLSeconds           int      `yaml:"sessionTTLSeconds"`
+	SessionCookieSecure         bool     `yaml:"sessionCookieSecure"`
+	OIDCEnabled                 bool     `yaml:"oidcEnabled"`
+	OIDCIssuer                  string   `yaml:"oidcIssuer"`
+	OIDCClientID                string   `yaml:"oidcClientID"`
+	OIDCClientSecret            string   `yaml:"oidcClientSecret"`
+	OIDCRedirectURL             string   `yaml:"oidcRedirectURL"` // 需与 IdP 登记的回调地址一致，如 https://dnslog.example.com/api/auth/oidc/callback
+	OIDCScopes                  []string `yaml:"oidcScopes"`
+	OIDCGroupsClaim             string   `yaml:"oidcGroupsClaim"`  // ID token 中的组声明名称
+	OIDCGroupRoles              []string `yaml:"oidcGroupRoles"`   // 组到角色的映射，格式 group=role
+	OIDCDefaultRole             string   `yaml:"oidcDefaultRole"`  // 未匹配任何组时授予的角色，空表示拒绝登录
+	OIDCTenant                  string   `yaml:"oidcTenant"`       // SSO 用户首次登录时归属的租户
+	OIDCPostLoginURL            string   `yaml:"oidcPostLoginURL"` // 登录成功后跳转地址
+	BootstrapEnabled            bool     `yaml:"bootstrapEnabled"`
+	BootstrapToken              string   `yaml:"bootstrapToken"`
+	RateLimitEnabled            bool     `yaml:"rateLimitEnabled"`
+	RateLimitWindowSeconds      int      `yaml:"rateLimitWindowSeconds"`
+	RateLimitMaxRequests        int      `yaml:"rateLimitMaxRequests"`
+	RateLimitGroupQuotas        []string `yaml:"rateLimitGroupQuotas"` // 路由分组额度，如 "/audit=10/60"
+	RateLimitKeyQuotas          []string `yaml:"rateLimitKeyQuotas"`   // key 专属额度，如 "5=600/60"
+	DNSRateLimitEnabled         bool     `yaml:"dnsRateLimitEnabled"`
+	DNSRateLimitWindowSeconds   int      `yaml:"dnsRateLimitWindowSeconds"`
+	DNSRateLimitMaxRequests     int      `yaml:"dnsRateLimitMaxRequests"`
+	BlacklistSyncSeconds        int      `yaml:"blacklistSyncSeconds"` // 从 MySQL 重建黑名单前缀树的间隔
+	AutoBanEnabled              bool     `yaml:"autoBanEnabled"`
+	AutoBanViolations           int      `yaml:"autoBanViolations"` // 窗口内限流违规次数达到该值即封禁
+	AutoBanWindowSeconds        int      `yaml:"autoBanWindowSeconds"`
+	AutoBanBaseSeconds          int      `yaml:"autoBanBaseSeconds"`  // 首次封禁时长，再犯时翻倍
+	AutoBanMaxSeconds           int      `yaml:"autoBanMaxSeconds"`   // 单次封禁时长上限
+	AutoBanResetSeconds         int      `yaml:"autoBanResetSeconds"` // 多久未再被封禁后重置累犯次数
+	RedisAddr                   string   `yaml:"redisAddr"`
+	RedisPassword               string   `yaml:"redisPassword"`
+	RedisDB                     int      `yaml:"redisDB"`
+	QueueBackend                string   `yaml:"queueBackend"`
+	QueueMemorySize             int      `yaml:"queueMemorySize"`
+	AuditEnabled                bool     `yaml:"auditEnabled"`
+	PublicConfig                bool     `yaml:"publicConfig"`
+	WebhookEnabled              bool     `yaml:"webhookEnabled"`
+	WebhookMaxRetries           int      `yaml:"webhookMaxRetries"`
+	WebhookRetryIntervalSeconds int      `yaml:"webhookRetryIntervalSeconds"`
+	WebhookSecretKey            string   `yaml:"webhookSecretKey"`
+	WebhookSecretOverlapSeconds int      `yaml:"webhookSecretOverlapSeconds"` // 轮换 secret 时旧 secret 继续签名的时长
+	WebhookLegacySignature      bool     `yaml:"webhookLegacySignature"`      // 是否继续发送旧版 X-Signature
+	WebhookAllowedSchemes       []string `yaml:"webhookAllowedSchemes"`       // webhook 允许的 URL scheme
+	WebhookDenyHosts            []string `yaml:"webhookDenyHosts"`            // webhook 禁止的主机名（含子域）
+	WebhookDenyCIDRs            []string `yaml:"webhookDenyCIDRs"`            // webhook 禁止的目标网段
+	WebhookWorkers              int      `yaml:"webhookWorkers"`              // webhook 投递并发 worker 数
+	WebhookEndpointConcurrency  int      `yaml:"webhookEndpointConcurrency"`  // 单个目标端点的最大并发投递数
+	WebhookRetryScheduleSeconds []int    `yaml:"webhookRetryScheduleSeconds"` // 第 N 次重试的等待秒数，超出后沿用最后一项
+	WebhookRetryJitterPercent   int      `yaml:"webhookRetryJitterPercent"`   // 重试等待的随机抖动百分比
+	WebhookRetryAfterMaxSeconds int      `yaml:"webhookRetryAfterMaxSeconds"` // Retry-After 可接受的最大等待
+	WebhookBreakerFailures      int      `yaml:"webhookBreakerFailures"`      // 连续失败多少次后熔断端点
+	WebhookBreakerOpenSeconds   int      `yaml:"webhookBreakerOpenSeconds"`   // 熔断持续时长，之后进入半开探测
+	WebhookBreakerProbes        int      `yaml:"webhookBreakerProbes"`        // 半开状态允许的并发探测数
+	AdminEventWebhookURL        string   `yaml:"adminEventWebhookURL"`        // 管理操作变更事件推送地址，空表示不推送
+	AdminEventWebhookSecret     string   `yaml:"adminEventWebhookSecret"`     // 管理事件 webhook 签名 secret
+	MetricsEnabled              bool     `yaml:"metricsEnabled"`
+	MetricsPublic               bool     `yaml:"metricsPublic"`
+	RetentionEnabled            bool     `yaml:"retentionEnabled"`
+	RecordRetentionDays         int      `yaml:"recordRetentionDays"`
+	AuditRetentionDays          int      `yaml:"auditRetentionDays"` // 审计日志保留天数，0 表示不清理
+	RetentionIntervalSeconds    int      `yaml:"retentionIntervalSeconds"`
+	RetentionBatchSize          int      `yaml:"retentionBatchSize"`
 
 	currentUpstream int
 	mu              sync.RWMutex
@@ -153,6 +240,23 @@ func mustInt(val string, def int) int {
 	return def
 }
 
+// splitInts 解析逗号分隔的整数列表，任一项非法则返回默认值
+func splitInts(val string, def []int) []int {
+	parts := splitAndTrim(val)
+	out := make([]int, 0, len(parts))
+	for _, p := range parts {
+		i, err := strconv.Atoi(p)
+		if err != nil {
+			return def
+		}
+		out = append(out, i)
+	}
+	if len(out) == 0 {
+		return def
+	}
+	return out
+}
+
 func defaultConfig() *Config {
 	return &Config{
 		RootDomain:                  "demo.com",
@@ -163,10 +267,37 @@ func defaultConfig() *Config {
 		UpstreamDNS:                 []string{"8.8.8.8", "223.5.5.5"},
 		Protocol:                    "udp",
 		MySQLDSN:                    "dnslog:dnslog@tcp(localhost:3306)/dnslog?parseTime=true&loc=Local&charset=utf8mb4",
+		TLSMinVersion:               "1.2",
+		TLSReloadSeconds:            30,
+		TLSClientAuth:               "optional",
+		ACMEDirectoryURL:            "https://acme-v02.api.letsencrypt.org/directory",
+		ACMECacheDir:                "data/acme",
+		ACMERenewDays:               30,
+		CORSMaxAgeSeconds:           600,
+		HTTPCaptureAddr:             ":8081",
+		HTTPCaptureBodyLimit:        8192,
+		SMTPCaptureAddr:             ":2525",
+		SMTPCaptureMaxBytes:         1 << 20,
+		LDAPCaptureAddr:             ":1389",
+		RMICaptureAddr:              ":1099",
+		RawCaptureReadBytes:         1024,
+		RawCaptureIdleSeconds:       10,
+		RawCaptureCorrelateSeconds:  300,
+		RawCaptureRateLimit:         60,
 		DefaultPageSize:             20,
 		MaxPageSize:                 100,
 		TokenTTLSeconds:             3600,
+		TokenWaitMaxSeconds:         60,
 		APIKeyRequired:              true,
+		APIKeyRotationGraceSeconds:  86400,
+		APIKeyTouchIntervalSeconds:  60,
+		SessionTTLSeconds:           43200,
+		SessionCookieSecure:         false,
+		OIDCEnabled:                 false,
+		OIDCScopes:                  []string{"openid", "profile", "email"},
+		OIDCGroupsClaim:             "groups",
+		OIDCTenant:                  "default",
+		OIDCPostLoginURL:            "/dnslog",
 		BootstrapEnabled:            false,
 		BootstrapToken:              "",
 		RateLimitEnabled:            true,
@@ -175,19 +306,42 @@ func defaultConfig() *Config {
 		DNSRateLimitEnabled:         true,
 		DNSRateLimitWindowSeconds:   60,
 		DNSRateLimitMaxRequests:     1000,
+		BlacklistSyncSeconds:        10,
+		AutoBanEnabled:              false,
+		AutoBanViolations:           10,
+		AutoBanWindowSeconds:        300,
+		AutoBanBaseSeconds:          600,
+		AutoBanMaxSeconds:           86400,
+		AutoBanResetSeconds:         604800,
 		RedisAddr:                   "127.0.0.1:6379",
 		RedisPassword:               "",
 		RedisDB:                     0,
+		QueueBackend:                "auto",
+		QueueMemorySize:             10000,
 		AuditEnabled:                true,
 		PublicConfig:                false,
 		WebhookEnabled:              true,
 		WebhookMaxRetries:           4,
 		WebhookRetryIntervalSeconds: 30,
 		WebhookSecretKey:            "",
+		WebhookSecretOverlapSeconds: 86400,
+		WebhookLegacySignature:      true,
+		WebhookAllowedSchemes:       []string{"https", "http"},
+		WebhookDenyHosts:            []string{"localhost", "metadata.google.internal"},
+		WebhookDenyCIDRs:            defaultWebhookDenyCIDRs(),
+		WebhookWorkers:              4,
+		WebhookEndpointConcurrency:  2,
+		WebhookRetryScheduleSeconds: []int{60, 300, 900, 3600},
+		WebhookRetryJitterPercent:   20,
+		WebhookRetryAfterMaxSeconds: 3600,
+		WebhookBreakerFailures:      5,
+		WebhookBreakerOpenSeconds:   60,
+		WebhookBreakerProbes:        1,
 		MetricsEnabled:              true,
 		MetricsPublic:               false,
 		RetentionEnabled:            true,
 		RecordRetentionDays:         30,
+		AuditRetentionDays:          90,
 		RetentionIntervalSeconds:    3600,
 		RetentionBatchSize:          1000,
 	}
@@ -209,31 +363,111 @@ func loadFromFile(cfg *Config) error {
 		UpstreamDNS                 []string `yaml:"upstreamDNS"`
 		Protocol                    string   `yaml:"protocol"`
 		MySQLDSN                    string   `yaml:"mysqlDSN"`
+		TLSEnabled                  *bool    `yaml:"tlsEnabled"`
+		TLSCertFile                 string   `yaml:"tlsCertFile"`
+		TLSKeyFile                  string   `yaml:"tlsKeyFile"`
+		TLSMinVersion               string   `yaml:"tlsMinVersion"`
+		TLSReloadSeconds            *int     `yaml:"tlsReloadSeconds"`
+		HTTPRedirectAddr            string   `yaml:"httpRedirectAddr"`
+		TLSClientCAFile             string   `yaml:"tlsClientCAFile"`
+		TLSClientAuth               string   `yaml:"tlsClientAuth"`
+		TLSClientCertKeys           []string `yaml:"tlsClientCertKeys"`
+		ACMEEnabled                 *bool    `yaml:"acmeEnabled"`
+		ACMEDirectoryURL            string   `yaml:"acmeDirectoryURL"`
+		ACMEEmail                   string   `yaml:"acmeEmail"`
+		ACMECacheDir                string   `yaml:"acmeCacheDir"`
+		ACMECAFile                  string   `yaml:"acmeCAFile"`
+		ACMERenewDays               int      `yaml:"acmeRenewDays"`
+		CORSAllowedOrigins          []string `yaml:"corsAllowedOrigins"`
+		CORSGroupOrigins            []string `yaml:"corsGroupOrigins"`
+		CORSAllowCredentials        *bool    `yaml:"corsAllowCredentials"`
+		CORSMaxAgeSeconds           int      `yaml:"corsMaxAgeSeconds"`
+		HTTPCaptureEnabled          *bool    `yaml:"httpCaptureEnabled"`
+		HTTPCaptureAddr             string   `yaml:"httpCaptureAddr"`
+		HTTPCaptureTLSAddr          string   `yaml:"httpCaptureTLSAddr"`
+		HTTPCaptureBodyLimit        int      `yaml:"httpCaptureBodyLimit"`
+		SMTPCaptureEnabled          *bool    `yaml:"smtpCaptureEnabled"`
+		SMTPCaptureAddr             string   `yaml:"smtpCaptureAddr"`
+		SMTPCaptureHostname         string   `yaml:"smtpCaptureHostname"`
+		SMTPCaptureMaxBytes         int      `yaml:"smtpCaptureMaxBytes"`
+		LDAPCaptureEnabled          *bool    `yaml:"ldapCaptureEnabled"`
+		LDAPCaptureAddr             string   `yaml:"ldapCaptureAddr"`
+		LDAPCaptureReferral         string   `yaml:"ldapCaptureReferral"`
+		RMICaptureEnabled           *bool    `yaml:"rmiCaptureEnabled"`
+		RMICaptureAddr              string   `yaml:"rmiCaptureAddr"`
+		RawCaptureListeners         []string `yaml:"rawCaptureListeners"`
+		RawCaptureReadBytes         int      `yaml:"rawCaptureReadBytes"`
+		RawCaptureIdleSeconds       int      `yaml:"rawCaptureIdleSeconds"`
+		RawCaptureCorrelateSeconds  *int     `yaml:"rawCaptureCorrelateSeconds"`
+		RawCaptureRateLimit         *int     `yaml:"rawCaptureRateLimit"`
 		PageSize                    int      `yaml:"pageSize"`
 		MaxPageSize                 int      `yaml:"maxPageSize"`
 		TokenTTLSeconds             int      `yaml:"tokenTTLSeconds"`
+		TokenWaitMaxSeconds         int      `yaml:"tokenWaitMaxSeconds"`
 		APIKeyRequired              *bool    `yaml:"apiKeyRequired"`
+		APIKeyRotationGraceSeconds  int      `yaml:"apiKeyRotationGraceSeconds"`
+		APIKeyTouchIntervalSeconds  *int     `yaml:"apiKeyTouchIntervalSeconds"`
+		SessionTTLSeconds           int      `yaml:"sessionTTLSeconds"`
+		SessionCookieSecure         *bool    `yaml:"sessionCookieSecure"`
+		OIDCEnabled                 *bool    `yaml:"oidcEnabled"`
+		OIDCIssuer                  string   `yaml:"oidcIssuer"`
+		OIDCClientID                string   `yaml:"oidcClientID"`
+		OIDCClientSecret            string   `yaml:"oidcClientSecret"`
+		OIDCRedirectURL             string   `yaml:"oidcRedirectURL"`
+		OIDCScopes                  []string `yaml:"oidcScopes"`
+		OIDCGroupsClaim             string   `yaml:"oidcGroupsClaim"`
+		OIDCGroupRoles              []string `yaml:"oidcGroupRoles"`
+		OIDCDefaultRole             string   `yaml:"oidcDefaultRole"`
+		OIDCTenant                  string   `yaml:"oidcTenant"`
+		OIDCPostLoginURL            string   `yaml:"oidcPostLoginURL"`
 		BootstrapEnabled            *bool    `yaml:"bootstrapEnabled"`
 		BootstrapToken              string   `yaml:"bootstrapToken"`
 		RateLimitEnabled            *bool    `yaml:"rateLimitEnabled"`
 		RateLimitWindowSeconds      int      `yaml:"rateLimitWindowSeconds"`
 		RateLimitMaxRequests        int      `yaml:"rateLimitMaxRequests"`
+		RateLimitGroupQuotas        []string `yaml:"rateLimitGroupQuotas"`
+		RateLimitKeyQuotas          []string `yaml:"rateLimitKeyQuotas"`
 		DNSRateLimitEnabled         *bool    `yaml:"dnsRateLimitEnabled"`
 		DNSRateLimitWindowSeconds   int      `yaml:"dnsRateLimitWindowSeconds"`
 		DNSRateLimitMaxRequests     int      `yaml:"dnsRateLimitMaxRequests"`
+		BlacklistSyncSeconds        int      `yaml:"blacklistSyncSeconds"`
+		AutoBanEnabled              *bool    `yaml:"autoBanEnabled"`
+		AutoBanViolations           int      `yaml:"autoBanViolations"`
+		AutoBanWindowSeconds        int      `yaml:"autoBanWindowSeconds"`
+		AutoBanBaseSeconds          int      `yaml:"autoBanBaseSeconds"`
+		AutoBanMaxSeconds           int      `yaml:"autoBanMaxSeconds"`
+		AutoBanResetSeconds         int      `yaml:"autoBanResetSeconds"`
 		RedisAddr                   string   `yaml:"redisAddr"`
 		RedisPassword               string   `yaml:"redisPassword"`
 		RedisDB                     int      `yaml:"redisDB"`
+		QueueBackend                string   `yaml:"queueBackend"`
+		QueueMemorySize             int      `yaml:"queueMemorySize"`
 		AuditEnabled                *bool    `yaml:"auditEnabled"`
 		PublicConfig                *bool    `yaml:"publicConfig"`
 		WebhookEnabled              *bool    `yaml:"webhookEnabled"`
 		WebhookMaxRetries           int      `yaml:"webhookMaxRetries"`
 		WebhookRetryIntervalSeconds int      `yaml:"webhookRetryIntervalSeconds"`
 		WebhookSecretKey            string   `yaml:"webhookSecretKey"`
+		WebhookSecretOverlapSeconds int      `yaml:"webhookSecretOverlapSeconds"`
+		WebhookLegacySignature      *bool    `yaml:"webhookLegacySignature"`
+		WebhookAllowedSchemes       []string `yaml:"webhookAllowedSchemes"`
+		WebhookDenyHosts            []string `yaml:"webhookDenyHosts"`
+		WebhookDenyCIDRs            []string `yaml:"webhookDenyCIDRs"`
+		WebhookWorkers              int      `yaml:"webhookWorkers"`
+		WebhookEndpointConcurrency  int      `yaml:"webhookEndpointConcurrency"`
+		WebhookRetryScheduleSeconds []int    `yaml:"webhookRetryScheduleSeconds"`
+		WebhookRetryJitterPercent   *int     `yaml:"webhookRetryJitterPercent"`
+		WebhookRetryAfterMaxSeconds int      `yaml:"webhookRetryAfterMaxSeconds"`
+		WebhookBreakerFailures      int      `yaml:"webhookBreakerFailures"`
+		WebhookBreakerOpenSeconds   int      `yaml:"webhookBreakerOpenSeconds"`
+		WebhookBreakerProbes        int      `yaml:"webhookBreakerProbes"`
+		AdminEventWebhookURL        string   `yaml:"adminEventWebhookURL"`
+		AdminEventWebhookSecret     string   `yaml:"adminEventWebhookSecret"`
 		MetricsEnabled              *bool    `yaml:"metricsEnabled"`
 		MetricsPublic               *bool    `yaml:"metricsPublic"`
 		RetentionEnabled            *bool    `yaml:"retentionEnabled"`
 		RecordRetentionDays         int      `yaml:"recordRetentionDays"`
+		AuditRetentionDays          *int     `yaml:"auditRetentionDays"`
 		RetentionIntervalSeconds    int      `yaml:"retentionIntervalSeconds"`
 		RetentionBatchSize          int      `yaml:"retentionBatchSize"`
 	}
@@ -265,6 +499,117 @@ func loadFromFile(cfg *Config) error {
 	if fc.MySQLDSN != "" {
 		cfg.MySQLDSN = fc.MySQLDSN
 	}
+	if fc.TLSEnabled != nil {
+		cfg.TLSEnabled = *fc.TLSEnabled
+	}
+	if fc.TLSCertFile != "" {
+		cfg.TLSCertFile = fc.TLSCertFile
+	}
+	if fc.TLSKeyFile != "" {
+		cfg.TLSKeyFile = fc.TLSKeyFile
+	}
+	if fc.TLSMinVersion != "" {
+		cfg.TLSMinVersion = fc.TLSMinVersion
+	}
+	if fc.TLSReloadSeconds != nil {
+		cfg.TLSReloadSeconds = *fc.TLSReloadSeconds
+	}
+	if fc.HTTPRedirectAddr != "" {
+		cfg.HTTPRedirectAddr = fc.HTTPRedirectAddr
+	}
+	if fc.TLSClientCAFile != "" {
+		cfg.TLSClientCAFile = fc.TLSClientCAFile
+	}
+	if fc.TLSClientAuth != "" {
+		cfg.TLSClientAuth = fc.TLSClientAuth
+	}
+	if len(fc.TLSClientCertKeys) > 0 {
+		cfg.TLSClientCertKeys = fc.TLSClientCertKeys
+	}
+	if fc.ACMEEnabled != nil {
+		cfg.ACMEEnabled = *fc.ACMEEnabled
+	}
+	if fc.ACMEDirectoryURL != "" {
+		cfg.ACMEDirectoryURL = fc.ACMEDirectoryURL
+	}
+	if fc.ACMEEmail != "" {
+		cfg.ACMEEmail = fc.ACMEEmail
+	}
+	if fc.ACMECacheDir != "" {
+		cfg.ACMECacheDir = fc.ACMECacheDir
+	}
+	if fc.ACMECAFile != "" {
+		cfg.ACMECAFile = fc.ACMECAFile
+	}
+	if fc.ACMERenewDays > 0 {
+		cfg.ACMERenewDays = fc.ACMERenewDays
+	}
+	if len(fc.CORSAllowedOrigins) > 0 {
+		cfg.CORSAllowedOrigins = fc.CORSAllowedOrigins
+	}
+	if len(fc.CORSGroupOrigins) > 0 {
+		cfg.CORSGroupOrigins = fc.CORSGroupOrigins
+	}
+	if fc.CORSAllowCredentials != nil {
+		cfg.CORSAllowCredentials = *fc.CORSAllowCredentials
+	}
+	if fc.CORSMaxAgeSeconds > 0 {
+		cfg.CORSMaxAgeSeconds = fc.CORSMaxAgeSeconds
+	}
+	if fc.HTTPCaptureEnabled != nil {
+		cfg.HTTPCaptureEnabled = *fc.HTTPCaptureEnabled
+	}
+	if fc.HTTPCaptureAddr != "" {
+		cfg.HTTPCaptureAddr = fc.HTTPCaptureAddr
+	}
+	if fc.HTTPCaptureTLSAddr != "" {
+		cfg.HTTPCaptureTLSAddr = fc.HTTPCaptureTLSAddr
+	}
+	if fc.HTTPCaptureBodyLimit > 0 {
+		cfg.HTTPCaptureBodyLimit = fc.HTTPCaptureBodyLimit
+	}
+	if fc.SMTPCaptureEnabled != nil {
+		cfg.SMTPCaptureEnabled = *fc.SMTPCaptureEnabled
+	}
+	if fc.SMTPCaptureAddr != "" {
+		cfg.SMTPCaptureAddr = fc.SMTPCaptureAddr
+	}
+	if fc.SMTPCaptureHostname != "" {
+		cfg.SMTPCaptureHostname = fc.SMTPCaptureHostname
+	}
+	if fc.SMTPCaptureMaxBytes > 0 {
+		cfg.SMTPCaptureMaxBytes = fc.SMTPCaptureMaxBytes
+	}
+	if fc.LDAPCaptureEnabled != nil {
+		cfg.LDAPCaptureEnabled = *fc.LDAPCaptureEnabled
+	}
+	if fc.LDAPCaptureAddr != "" {
+		cfg.LDAPCaptureAddr = fc.LDAPCaptureAddr
+	}
+	if fc.LDAPCaptureReferral != "" {
+		cfg.LDAPCaptureReferral = fc.LDAPCaptureReferral
+	}
+	if fc.RMICaptureEnabled != nil {
+		cfg.RMICaptureEnabled = *fc.RMICaptureEnabled
+	}
+	if fc.RMICaptureAddr != "" {
+		cfg.RMICaptureAddr = fc.RMICaptureAddr
+	}
+	if len(fc.RawCaptureListeners) > 0 {
+		cfg.RawCaptureListeners = fc.RawCaptureListeners
+	}
+	if fc.RawCaptureReadBytes > 0 {
+		cfg.RawCaptureReadBytes = fc.RawCaptureReadBytes
+	}
+	if fc.RawCaptureIdleSeconds > 0 {
+		cfg.RawCaptureIdleSeconds = fc.RawCaptureIdleSeconds
+	}
+	if fc.RawCaptureCorrelateSeconds != nil {
+		cfg.RawCaptureCorrelateSeconds = *fc.RawCaptureCorrelateSeconds
+	}
+	if fc.RawCaptureRateLimit != nil {
+		cfg.RawCaptureRateLimit = *fc.RawCaptureRateLimit
+	}
 	if fc.PageSize > 0 {
 		cfg.DefaultPageSize = fc.PageSize
 	}
@@ -274,9 +619,57 @@ func loadFromFile(cfg *Config) error {
 	if fc.TokenTTLSeconds > 0 {
 		cfg.TokenTTLSeconds = fc.TokenTTLSeconds
 	}
+	if fc.TokenWaitMaxSeconds > 0 {
+		cfg.TokenWaitMaxSeconds = fc.TokenWaitMaxSeconds
+	}
 	if fc.APIKeyRequired != nil {
 		cfg.APIKeyRequired = *fc.APIKeyRequired
 	}
+	if fc.APIKeyRotationGraceSeconds > 0 {
+		cfg.APIKeyRotationGraceSeconds = fc.APIKeyRotationGraceSeconds
+	}
+	if fc.APIKeyTouchIntervalSeconds != nil {
+		cfg.APIKeyTouchIntervalSeconds = *fc.APIKeyTouchIntervalSeconds
+	}
+	if fc.SessionTTLSeconds > 0 {
+		cfg.SessionTTLSeconds = fc.SessionTTLSeconds
+	}
+	if fc.SessionCookieSecure != nil {
+		cfg.SessionCookieSecure = *fc.SessionCookieSecure
+	}
+	if fc.OIDCEnabled != nil {
+		cfg.OIDCEnabled = *fc.OIDCEnabled
+	}
+	if fc.OIDCIssuer != "" {
+		cfg.OIDCIssuer = fc.OIDCIssuer
+	}
+	if fc.OIDCClientID != "" {
+		cfg.OIDCClientID = fc.OIDCClientID
+	}
+	if fc.OIDCClientSecret != "" {
+		cfg.OIDCClientSecret = fc.OIDCClientSecret
+	}
+	if fc.OIDCRedirectURL != "" {
+		cfg.OIDCRedirectURL = fc.OIDCRedirectURL
+	}
+	if len(fc.OIDCScopes) > 0 {
+		cfg.OIDCScopes = fc.OIDCScopes
+	}
+	if fc.OIDCGroupsClaim != "" {
+		cfg.OIDCGroupsClaim = fc.OIDCGroupsClaim
+	}
+	if len(fc.OIDCGroupRoles) > 0 {
+		cfg.OIDCGroupRoles = fc.OIDCGroupRoles
+	}
+	if fc.OIDCDefaultRole != "" {
+		cfg.OIDCDefaultRole = fc.OIDCDefaultRole
+	}
+	if fc.OIDCTenant != "" {
+		cfg.OIDCTenant = fc.OIDCTenant
+	}
+	if fc.OIDCPostLoginURL != "" {
+		cfg.OIDCPostLoginURL = fc.OIDCPostLoginURL
+	}
 	if fc.BootstrapEnabled != nil {
 		cfg.BootstrapEnabled = *fc.BootstrapEnabled
 	}
@@ -292,6 +685,12 @@ func loadFromFile(cfg *Config) error {
 	if fc.RateLimitMaxRequests > 0 {
 		cfg.RateLimitMaxRequests = fc.RateLimitMaxRequests
 	}
+	if len(fc.RateLimitGroupQuotas) > 0 {
+		cfg.RateLimitGroupQuotas = fc.RateLimitGroupQuotas
+	}
+	if len(fc.RateLimitKeyQuotas) > 0 {
+		cfg.RateLimitKeyQuotas = fc.RateLimitKeyQuotas
+	}
 	if fc.DNSRateLimitEnabled != nil {
 		cfg.DNSRateLimitEnabled = *fc.DNSRateLimitEnabled
 	}
@@ -301,6 +700,27 @@ func loadFromFile(cfg *Config) error {
 	if fc.DNSRateLimitMaxRequests > 0 {
 		cfg.DNSRateLimitMaxRequests = fc.DNSRateLimitMaxRequests
 	}
+	if fc.BlacklistSyncSeconds > 0 {
+		cfg.BlacklistSyncSeconds = fc.BlacklistSyncSeconds
+	}
+	if fc.AutoBanEnabled != nil {
+		cfg.AutoBanEnabled = *fc.AutoBanEnabled
+	}
+	if fc.AutoBanViolations > 0 {
+		cfg.AutoBanViolations = fc.AutoBanViolations
+	}
+	if fc.AutoBanWindowSeconds > 0 {
+		cfg.AutoBanWindowSeconds = fc.AutoBanWindowSeconds
+	}
+	if fc.AutoBanBaseSeconds > 0 {
+		cfg.AutoBanBaseSeconds = fc.AutoBanBaseSeconds
+	}
+	if fc.AutoBanMaxSeconds > 0 {
+		cfg.AutoBanMaxSeconds = fc.AutoBanMaxSeconds
+	}
+	if fc.AutoBanResetSeconds > 0 {
+		cfg.AutoBanResetSeconds = fc.AutoBanResetSeconds
+	}
 	if fc.RedisAddr != "" {
 		cfg.RedisAddr = fc.RedisAddr
 	}
@@ -310,6 +730,12 @@ func loadFromFile(cfg *Config) error {
 	if fc.RedisDB > 0 {
 		cfg.RedisDB = fc.RedisDB
 	}
+	if fc.QueueBackend != "" {
+		cfg.QueueBackend = strings.ToLower(fc.QueueBackend)
+	}
+	if fc.QueueMemorySize > 0 {
+		cfg.QueueMemorySize = fc.QueueMemorySize
+	}
 	if fc.AuditEnabled != nil {
 		cfg.AuditEnabled = *fc.AuditEnabled
 	}
@@ -328,6 +754,51 @@ func loadFromFile(cfg *Config) error {
 	if fc.WebhookSecretKey != "" {
 		cfg.WebhookSecretKey = fc.WebhookSecretKey
 	}
+	if fc.WebhookSecretOverlapSeconds > 0 {
+		cfg.WebhookSecretOverlapSeconds = fc.WebhookSecretOverlapSeconds
+	}
+	if fc.WebhookLegacySignature != nil {
+		cfg.WebhookLegacySignature = *fc.WebhookLegacySignature
+	}
+	if len(fc.WebhookAllowedSchemes) > 0 {
+		cfg.WebhookAllowedSchemes = fc.WebhookAllowedSchemes
+	}
+	if len(fc.WebhookDenyHosts) > 0 {
+		cfg.WebhookDenyHosts = fc.WebhookDenyHosts
+	}
+	if len(fc.WebhookDenyCIDRs) > 0 {
+		cfg.WebhookDenyCIDRs = fc.WebhookDenyCIDRs
+	}
+	if fc.WebhookWorkers > 0 {
+		cfg.WebhookWorkers = fc.WebhookWorkers
+	}
+	if fc.WebhookEndpointConcurrency > 0 {
+		cfg.WebhookEndpointConcurrency = fc.WebhookEndpointConcurrency
+	}
+	if len(fc.WebhookRetryScheduleSeconds) > 0 {
+		cfg.WebhookRetryScheduleSeconds = fc.WebhookRetryScheduleSeconds
+	}
+	if fc.WebhookRetryJitterPercent != nil {
+		cfg.WebhookRetryJitterPercent = *fc.WebhookRetryJitterPercent
+	}
+	if fc.WebhookRetryAfterMaxSeconds > 0 {
+		cfg.WebhookRetryAfterMaxSeconds = fc.WebhookRetryAfterMaxSeconds
+	}
+	if fc.WebhookBreakerFailures > 0 {
+		cfg.WebhookBreakerFailures = fc.WebhookBreakerFailures
+	}
+	if fc.WebhookBreakerOpenSeconds > 0 {
+		cfg.WebhookBreakerOpenSeconds = fc.WebhookBreakerOpenSeconds
+	}
+	if fc.WebhookBreakerProbes > 0 {
+		cfg.WebhookBreakerProbes = fc.WebhookBreakerProbes
+	}
+	if fc.AdminEventWebhookURL != "" {
+		cfg.AdminEventWebhookURL = fc.AdminEventWebhookURL
+	}
+	if fc.AdminEventWebhookSecret != "" {
+		cfg.AdminEventWebhookSecret = fc.AdminEventWebhookSecret
+	}
 	if fc.MetricsEnabled != nil {
 		cfg.MetricsEnabled = *fc.MetricsEnabled
 	}
@@ -340,6 +811,9 @@ func loadFromFile(cfg *Config) error {
 	if fc.RecordRetentionDays > 0 {
 		cfg.RecordRetentionDays = fc.RecordRetentionDays
 	}
+	if fc.AuditRetentionDays != nil && *fc.AuditRetentionDays >= 0 {
+		cfg.AuditRetentionDays = *fc.AuditRetentionDays
+	}
 	if fc.RetentionIntervalSeconds > 0 {
 		cfg.RetentionIntervalSeconds = fc.RetentionIntervalSeconds
 	}
@@ -374,6 +848,117 @@ func applyEnvOverrides(cfg *Config) {
 	if v := getEnv("MYSQL_DSN", ""); v != "" {
 		cfg.MySQLDSN = v
 	}
+	if v := getEnv("TLS_ENABLED", ""); v != "" {
+		cfg.TLSEnabled = strings.ToLower(v) == "true"
+	}
+	if v := getEnv("TLS_CERT_FILE", ""); v != "" {
+		cfg.TLSCertFile = v
+	}
+	if v := getEnv("TLS_KEY_FILE", ""); v != "" {
+		cfg.TLSKeyFile = v
+	}
+	if v := getEnv("TLS_MIN_VERSION", ""); v != "" {
+		cfg.TLSMinVersion = v
+	}
+	if v := getEnv("TLS_RELOAD_SECONDS", ""); v != "" {
+		cfg.TLSReloadSeconds = mustInt(v, cfg.TLSReloadSeconds)
+	}
+	if v := getEnv("HTTP_REDIRECT_ADDR", ""); v != "" {
+		cfg.HTTPRedirectAddr = v
+	}
+	if v := getEnv("TLS_CLIENT_CA_FILE", ""); v != "" {
+		cfg.TLSClientCAFile = v
+	}
+	if v := getEnv("TLS_CLIENT_AUTH", ""); v != "" {
+		cfg.TLSClientAuth = v
+	}
+	if v := getEnv("TLS_CLIENT_CERT_KEYS", ""); v != "" {
+		cfg.TLSClientCertKeys = splitAndTrim(v)
+	}
+	if v := getEnv("ACME_ENABLED", ""); v != "" {
+		cfg.ACMEEnabled = strings.ToLower(v) == "true"
+	}
+	if v := getEnv("ACME_DIRECTORY_URL", ""); v != "" {
+		cfg.ACMEDirectoryURL = v
+	}
+	if v := getEnv("ACME_EMAIL", ""); v != "" {
+		cfg.ACMEEmail = v
+	}
+	if v := getEnv("ACME_CACHE_DIR", ""); v != "" {
+		cfg.ACMECacheDir = v
+	}
+	if v := getEnv("ACME_CA_FILE", ""); v != "" {
+		cfg.ACMECAFile = v
+	}
+	if v := getEnv("ACME_RENEW_DAYS", ""); v != "" {
+		cfg.ACMERenewDays = mustInt(v, cfg.ACMERenewDays)
+	}
+	if v := getEnv("CORS_ALLOWED_ORIGINS", ""); v != "" {
+		cfg.CORSAllowedOrigins = splitAndTrim(v)
+	}
+	if v := getEnv("CORS_GROUP_ORIGINS", ""); v != "" {
+		cfg.CORSGroupOrigins = splitAndTrim(v)
+	}
+	if v := getEnv("CORS_ALLOW_CREDENTIALS", ""); v != "" {
+		cfg.CORSAllowCredentials = strings.ToLower(v) == "true"
+	}
+	if v := getEnv("CORS_MAX_AGE_SECONDS", ""); v != "" {
+		cfg.CORSMaxAgeSeconds = mustInt(v, cfg.CORSMaxAgeSeconds)
+	}
+	if v := getEnv("HTTP_CAPTURE_ENABLED", ""); v != "" {
+		cfg.HTTPCaptureEnabled = strings.ToLower(v) == "true"
+	}
+	if v := getEnv("HTTP_CAPTURE_ADDR", ""); v != "" {
+		cfg.HTTPCaptureAddr = v
+	}
+	if v := getEnv("HTTP_CAPTURE_TLS_ADDR", ""); v != "" {
+		cfg.HTTPCaptureTLSAddr = v
+	}
+	if v := getEnv("HTTP_CAPTURE_BODY_LIMIT", ""); v != "" {
+		cfg.HTTPCaptureBodyLimit = mustInt(v, cfg.HTTPCaptureBodyLimit)
+	}
+	if v := getEnv("SMTP_CAPTURE_ENABLED", ""); v != "" {
+		cfg.SMTPCaptureEnabled = strings.ToLower(v) == "true"
+	}
+	if v := getEnv("SMTP_CAPTURE_ADDR", ""); v != "" {
+		cfg.SMTPCaptureAddr = v
+	}
+	if v := getEnv("SMTP_CAPTURE_HOSTNAME", ""); v != "" {
+		cfg.SMTPCaptureHostname = v
+	}
+	if v := getEnv("SMTP_CAPTURE_MAX_BYTES", ""); v != "" {
+		cfg.SMTPCaptureMaxBytes = mustInt(v, cfg.SMTPCaptureMaxBytes)
+	}
+	if v := getEnv("LDAP_CAPTURE_ENABLED", ""); v != "" {
+		cfg.LDAPCaptureEnabled = strings.ToLower(v) == "true"
+	}
+	if v := getEnv("LDAP_CAPTURE_ADDR", ""); v != "" {
+		cfg.LDAPCaptureAddr = v
+	}
+	if v := getEnv("LDAP_CAPTURE_REFERRAL", ""); v != "" {
+		cfg.LDAPCaptureReferral = v
+	}
+	if v := getEnv("RMI_CAPTURE_ENABLED", ""); v != "" {
+		cfg.RMICaptureEnabled = strings.ToLower(v) == "true"
+	}
+	if v := getEnv("RMI_CAPTURE_ADDR", ""); v != "" {
+		cfg.RMICaptureAddr = v
+	}
+	if v := getEnv("RAW_CAPTURE_LISTENERS", ""); v != "" {
+		cfg.RawCaptureListeners = splitAndTrim(v)
+	}
+	if v := getEnv("RAW_CAPTURE_READ_BYTES", ""); v != "" {
+		cfg.RawCaptureReadBytes = mustInt(v, cfg.RawCaptureReadBytes)
+	}
+	if v := getEnv("RAW_CAPTURE_IDLE_SECONDS", ""); v != "" {
+		cfg.RawCaptureIdleSeconds = mustInt(v, cfg.RawCaptureIdleSeconds)
+	}
+	if v := getEnv("RAW_CAPTURE_CORRELATE_SECONDS", ""); v != "" {
+		cfg.RawCaptureCorrelateSeconds = mustInt(v, cfg.RawCaptureCorrelateSeconds)
+	}
+	if v := getEnv("RAW_CAPTURE_RATE_LIMIT", ""); v != "" {
+		cfg.RawCaptureRateLimit = mustInt(v, cfg.RawCaptureRateLimit)
+	}
 	if v := getEnv("PAGE_SIZE", ""); v != "" {
 		cfg.DefaultPageSize = mustInt(v, cfg.DefaultPageSize)
 	}
@@ -383,9 +968,57 @@ func applyEnvOverrides(cfg *Config) {
 	if v := getEnv("TOKEN_TTL_SECONDS", ""); v != "" {
 		cfg.TokenTTLSeconds = mustInt(v, cfg.TokenTTLSeconds)
 	}
+	if v := getEnv("TOKEN_WAIT_MAX_SECONDS", ""); v != "" {
+		cfg.TokenWaitMaxSeconds = mustInt(v, cfg.TokenWaitMaxSeconds)
+	}
 	if v := getEnv("API_KEY_REQUIRED", ""); v != "" {
 		cfg.APIKeyRequired = strings.ToLower(v) == "true"
 	}
+	if v := getEnv("API_KEY_ROTATION_GRACE_SECONDS", ""); v != "" {
+		cfg.APIKeyRotationGraceSeconds = mustInt(v, cfg.APIKeyRotationGraceSeconds)
+	}
+	if v := getEnv("API_KEY_TOUCH_INTERVAL_SECONDS", ""); v != "" {
+		cfg.APIKeyTouchIntervalSeconds = mustInt(v, cfg.APIKeyTouchIntervalSeconds)
+	}
+	if v := getEnv("SESSION_TTL_SECONDS", ""); v != "" {
+		cfg.SessionTTLSeconds = mustInt(v, cfg.SessionTTLSeconds)
+	}
+	if v := getEnv("SESSION_COOKIE_SECURE", ""); v != "" {
+		cfg.SessionCookieSecure = strings.ToLower(v) == "true"
+	}
+	if v := getEnv("OIDC_ENABLED", ""); v != "" {
+		cfg.OIDCEnabled = strings.ToLower(v) == "true"
+	}
+	if v := getEnv("OIDC_ISSUER", ""); v != "" {
+		cfg.OIDCIssuer = v
+	}
+	if v := getEnv("OIDC_CLIENT_ID", ""); v != "" {
+		cfg.OIDCClientID = v
+	}
+	if v := getEnv("OIDC_CLIENT_SECRET", ""); v != "" {
+		cfg.OIDCClientSecret = v
+	}
+	if v := getEnv("OIDC_REDIRECT_URL", ""); v != "" {
+		cfg.OIDCRedirectURL = v
+	}
+	if v := getEnv("OIDC_SCOPES", ""); v != "" {
+		cfg.OIDCScopes = splitAndTrim(v)
+	}
+	if v := getEnv("OIDC_GROUPS_CLAIM", ""); v != "" {
+		cfg.OIDCGroupsClaim = v
+	}
+	if v := getEnv("OIDC_GROUP_ROLES", ""); v != "" {
+		cfg.OIDCGroupRoles = splitAndTrim(v)
+	}
+	if v := getEnv("OIDC_DEFAULT_ROLE", ""); v != "" {
+		cfg.OIDCDefaultRole = v
+	}
+	if v := getEnv("OIDC_TENANT", ""); v != "" {
+		cfg.OIDCTenant = v
+	}
+	if v := getEnv("OIDC_POST_LOGIN_URL", ""); v != "" {
+		cfg.OIDCPostLoginURL = v
+	}
 	if v := getEnv("BOOTSTRAP_ENABLED", ""); v != "" {
 		cfg.BootstrapEnabled = strings.ToLower(v) == "true"
 	}
@@ -401,6 +1034,12 @@ func applyEnvOverrides(cfg *Config) {
 	if v := getEnv("RATE_LIMIT_MAX_REQUESTS", ""); v != "" {
 		cfg.RateLimitMaxRequests = mustInt(v, cfg.RateLimitMaxRequests)
 	}
+	if v := getEnv("RATE_LIMIT_GROUP_QUOTAS", ""); v != "" {
+		cfg.RateLimitGroupQuotas = splitAndTrim(v)
+	}
+	if v := getEnv("RATE_LIMIT_KEY_QUOTAS", ""); v != "" {
+		cfg.RateLimitKeyQuotas = splitAndTrim(v)
+	}
 	if v := getEnv("DNS_RATE_LIMIT_ENABLED", ""); v != "" {
 		cfg.DNSRateLimitEnabled = strings.ToLower(v) == "true"
 	}
@@ -410,6 +1049,27 @@ func applyEnvOverrides(cfg *Config) {
 	if v := getEnv("DNS_RATE_LIMIT_MAX_REQUESTS", ""); v != "" {
 		cfg.DNSRateLimitMaxRequests = mustInt(v, cfg.DNSRateLimitMaxRequests)
 	}
+	if v := getEnv("BLACKLIST_SYNC_SECONDS", ""); v != "" {
+		cfg.BlacklistSyncSeconds = mustInt(v, cfg.BlacklistSyncSeconds)
+	}
+	if v := getEnv("AUTO_BAN_ENABLED", ""); v != "" {
+		cfg.AutoBanEnabled = strings.ToLower(v) == "true"
+	}
+	if v := getEnv("AUTO_BAN_VIOLATIONS", ""); v != "" {
+		cfg.AutoBanViolations = mustInt(v, cfg.AutoBanViolations)
+	}
+	if v := getEnv("AUTO_BAN_WINDOW_SECONDS", ""); v != "" {
+		cfg.AutoBanWindowSeconds = mustInt(v, cfg.AutoBanWindowSeconds)
+	}
+	if v := getEnv("AUTO_BAN_BASE_SECONDS", ""); v != "" {
+		cfg.AutoBanBaseSeconds = mustInt(v, cfg.AutoBanBaseSeconds)
+	}
+	if v := getEnv("AUTO_BAN_MAX_SECONDS", ""); v != "" {
+		cfg.AutoBanMaxSeconds = mustInt(v, cfg.AutoBanMaxSeconds)
+	}
+	if v := getEnv("AUTO_BAN_RESET_SECONDS", ""); v != "" {
+		cfg.AutoBanResetSeconds = mustInt(v, cfg.AutoBanResetSeconds)
+	}
 	if v := getEnv("REDIS_ADDR", ""); v != "" {
 		cfg.RedisAddr = v
 	}
@@ -419,6 +1079,12 @@ func applyEnvOverrides(cfg *Config) {
 	if v := getEnv("REDIS_DB", ""); v != "" {
 		cfg.RedisDB = mustInt(v, cfg.RedisDB)
 	}
+	if v := getEnv("QUEUE_BACKEND", ""); v != "" {
+		cfg.QueueBackend = strings.ToLower(v)
+	}
+	if v := getEnv("QUEUE_MEMORY_SIZE", ""); v != "" {
+		cfg.QueueMemorySize = mustInt(v, cfg.QueueMemorySize)
+	}
 	if v := getEnv("AUDIT_ENABLED", ""); v != "" {
 		cfg.AuditEnabled = strings.ToLower(v) == "true"
 	}
@@ -437,6 +1103,51 @@ func applyEnvOverrides(cfg *Config) {
 	if v := getEnv("WEBHOOK_SECRET_KEY", ""); v != "" {
 		cfg.WebhookSecretKey = v
 	}
+	if v := getEnv("WEBHOOK_SECRET_OVERLAP_SECONDS", ""); v != "" {
+		cfg.WebhookSecretOverlapSeconds = mustInt(v, cfg.WebhookSecretOverlapSeconds)
+	}
+	if v := getEnv("WEBHOOK_LEGACY_SIGNATURE", ""); v != "" {
+		cfg.WebhookLegacySignature = strings.ToLower(v) == "true"
+	}
+	if v := getEnv("WEBHOOK_ALLOWED_SCHEMES", ""); v != "" {
+		cfg.WebhookAllowedSchemes = splitAndTrim(v)
+	}
+	if v := getEnv("WEBHOOK_DENY_HOSTS", ""); v != "" {
+		cfg.WebhookDenyHosts = splitAndTrim(v)
+	}
+	if v := getEnv("WEBHOOK_DENY_CIDRS", ""); v != "" {
+		cfg.WebhookDenyCIDRs = splitAndTrim(v)
+	}
+	if v := getEnv("WEBHOOK_WORKERS", ""); v != "" {
+		cfg.WebhookWorkers = mustInt(v, cfg.WebhookWorkers)
+	}
+	if v := getEnv("WEBHOOK_ENDPOINT_CONCURRENCY", ""); v != "" {
+		cfg.WebhookEndpointConcurrency = mustInt(v, cfg.WebhookEndpointConcurrency)
+	}
+	if v := getEnv("WEBHOOK_RETRY_SCHEDULE_SECONDS", ""); v != "" {
+		cfg.WebhookRetryScheduleSeconds = splitInts(v, cfg.WebhookRetryScheduleSeconds)
+	}
+	if v := getEnv("WEBHOOK_RETRY_JITTER_PERCENT", ""); v != "" {
+		cfg.WebhookRetryJitterPercent = mustInt(v, cfg.WebhookRetryJitterPercent)
+	}
+	if v := getEnv("WEBHOOK_RETRY_AFTER_MAX_SECONDS", ""); v != "" {
+		cfg.WebhookRetryAfterMaxSeconds = mustInt(v, cfg.WebhookRetryAfterMaxSeconds)
+	}
+	if v := getEnv("WEBHOOK_BREAKER_FAILURES", ""); v != "" {
+		cfg.WebhookBreakerFailures = mustInt(v, cfg.WebhookBreakerFailures)
+	}
+	if v := getEnv("WEBHOOK_BREAKER_OPEN_SECONDS", ""); v != "" {
+		cfg.WebhookBreakerOpenSeconds = mustInt(v, cfg.WebhookBreakerOpenSeconds)
+	}
+	if v := getEnv("WEBHOOK_BREAKER_PROBES", ""); v != "" {
+		cfg.WebhookBreakerProbes = mustInt(v, cfg.WebhookBreakerProbes)
+	}
+	if v := getEnv("ADMIN_EVENT_WEBHOOK_URL", ""); v != "" {
+		cfg.AdminEventWebhookURL = v
+	}
+	if v := getEnv("ADMIN_EVENT_WEBHOOK_SECRET", ""); v != "" {
+		cfg.AdminEventWebhookSecret = v
+	}
 	if v := getEnv("METRICS_ENABLED", ""); v != "" {
 		cfg.MetricsEnabled = strings.ToLower(v) == "true"
 	}
@@ -449,6 +1160,9 @@ func applyEnvOverrides(cfg *Config) {
 	if v := getEnv("RECORD_RETENTION_DAYS", ""); v != "" {
 		cfg.RecordRetentionDays = mustInt(v, cfg.RecordRetentionDays)
 	}
+	if v := getEnv("AUDIT_RETENTION_DAYS", ""); v != "" {
+		cfg.AuditRetentionDays = mustInt(v, cfg.AuditRetentionDays)
+	}
 	if v := getEnv("RETENTION_INTERVAL_SECONDS", ""); v != "" {
 		cfg.RetentionIntervalSeconds = mustInt(v, cfg.RetentionIntervalSeconds)
 	}
@@ -468,6 +1182,29 @@ func (c *Config) Validate() error {
 	return nil
 }
 
+// defaultWebhookDenyCIDRs 默认禁止 webhook 访问的内网、回环、链路本地与保留网段
+func defaultWebhookDenyCIDRs() []string {
+	return []string{
+		"0.0.0.0/8",
+		"10.0.0.0/8",
+		"100.64.0.0/10",
+		"127.0.0.0/8",
+		"169.254.0.0/16",
+		"172.16.0.0/12",
+		"192.0.0.0/24",
+		"192.168.0.0/16",
+		"198.18.0.0/15",
+		"224.0.0.0/4",
+		"240.0.0.0/4",
+		"::/128",
+		"::1/128",
+		"64:ff9b::/96",
+		"fc00::/7",
+		"fe80::/10",
+		"ff00::/8",
+	}
+}
+
 // mergeRootDomains 合并单值 RootDomain 与 RootDomains 切片，去重
 func mergeRootDomains(single string, list []string) []string {
 	all := make([]string, 0)
@@ -0,0 +1,6 @@
+ALTER TABLE token_webhooks
+  ADD COLUMN prev_secret VARCHAR(128) DEFAULT '' AFTER secret,
+  ADD COLUMN prev_secret_expires_at BIGINT NOT NULL DEFAULT 0 AFTER prev_secret;
+
+ALTER TABLE webhook_jobs
+  ADD COLUMN prev_secret VARCHAR(128) DEFAULT '' AFTER secret;
@@ -0,0 +1,4 @@
+ALTER TABLE token_webhooks
+  ADD COLUMN headers TEXT NULL AFTER prev_secret_expires_at,
+  ADD COLUMN auth_type VARCHAR(16) NOT NULL DEFAULT 'none' AFTER headers,
+  ADD COLUMN auth_credentials TEXT NULL AFTER auth_type;
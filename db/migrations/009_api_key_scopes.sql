@@ -0,0 +1,3 @@
+-- 已有 key 保持全部权限（*），新建 key 按需分配 scope
+ALTER TABLE api_keys
+  ADD COLUMN scopes VARCHAR(512) NOT NULL DEFAULT '*' AFTER comment;
@@ -0,0 +1,5 @@
+-- expires_at = 0 表示永不过期；rotated_from 记录轮换来源 key
+ALTER TABLE api_keys
+  ADD COLUMN expires_at BIGINT NOT NULL DEFAULT 0 AFTER scopes,
+  ADD COLUMN rotated_from BIGINT NOT NULL DEFAULT 0 AFTER expires_at,
+  ADD INDEX idx_expires_at (expires_at);
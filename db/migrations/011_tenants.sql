@@ -0,0 +1,8 @@
+-- 已有 key 归入 default 租户；已有 token 无归属，仅 admin:tenants 可见
+ALTER TABLE api_keys
+  ADD COLUMN tenant VARCHAR(64) NOT NULL DEFAULT 'default' AFTER rotated_from;
+
+ALTER TABLE dns_tokens
+  ADD COLUMN owner_tenant VARCHAR(64) NOT NULL DEFAULT '' AFTER expires_at,
+  ADD COLUMN owner_key_id BIGINT NOT NULL DEFAULT 0 AFTER owner_tenant,
+  ADD INDEX idx_owner_created (owner_tenant, created_at);
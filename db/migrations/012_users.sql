@@ -0,0 +1,27 @@
+-- 控制台用户与登录会话；会话只存 cookie 的 SHA-256，TOTP 密钥使用 WEBHOOK_SECRET_KEY 加密
+CREATE TABLE IF NOT EXISTS users (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    username VARCHAR(64) NOT NULL UNIQUE,
+    password_hash VARCHAR(128) NOT NULL,
+    roles VARCHAR(255) NOT NULL DEFAULT '',
+    tenant VARCHAR(64) NOT NULL DEFAULT 'default',
+    totp_secret VARCHAR(255) NOT NULL DEFAULT '',
+    totp_enabled TINYINT NOT NULL DEFAULT 0,
+    totp_last_step BIGINT NOT NULL DEFAULT 0,
+    enabled TINYINT NOT NULL DEFAULT 1,
+    created_at BIGINT NOT NULL,
+    last_login_at BIGINT NOT NULL DEFAULT 0
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS user_sessions (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    session_hash VARCHAR(128) NOT NULL UNIQUE,
+    user_id BIGINT NOT NULL,
+    csrf_token VARCHAR(64) NOT NULL,
+    client_ip VARCHAR(64) NOT NULL DEFAULT '',
+    user_agent VARCHAR(255) NOT NULL DEFAULT '',
+    created_at BIGINT NOT NULL,
+    expires_at BIGINT NOT NULL,
+    INDEX idx_user (user_id),
+    INDEX idx_expires_at (expires_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,4 @@
+-- SSO 用户按 IdP 的 sub 关联；本地用户为 NULL
+ALTER TABLE users
+  ADD COLUMN oidc_subject VARCHAR(255) NULL AFTER last_login_at,
+  ADD UNIQUE KEY uk_oidc_subject (oidc_subject);
@@ -0,0 +1,5 @@
+-- ip 列可存单个 IP 或 CIDR；scope 区分 HTTP API 与 DNS；expires_at 为 0 表示永久
+ALTER TABLE ip_blacklist
+  ADD COLUMN scope VARCHAR(16) NOT NULL DEFAULT 'all' AFTER reason,
+  ADD COLUMN expires_at BIGINT NOT NULL DEFAULT 0 AFTER enabled,
+  ADD INDEX idx_expires_at (expires_at);
@@ -0,0 +1,4 @@
+-- GET /audit 按 API key 与 token 过滤
+ALTER TABLE audit_logs
+  ADD INDEX idx_api_key (api_key_id),
+  ADD INDEX idx_token (token);
@@ -0,0 +1,19 @@
+-- 管理操作变更历史：操作者、变更前后值、原因与 trace ID
+CREATE TABLE IF NOT EXISTS admin_events (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    action VARCHAR(64) NOT NULL,
+    target VARCHAR(128) NOT NULL DEFAULT '',
+    actor_key_id BIGINT DEFAULT NULL,
+    actor_user_id BIGINT DEFAULT NULL,
+    actor_name VARCHAR(64) NOT NULL DEFAULT '',
+    tenant VARCHAR(64) NOT NULL DEFAULT '',
+    before_value TEXT,
+    after_value TEXT,
+    reason VARCHAR(255) NOT NULL DEFAULT '',
+    trace_id VARCHAR(64) NOT NULL DEFAULT '',
+    client_ip VARCHAR(64) NOT NULL DEFAULT '',
+    created_at BIGINT NOT NULL,
+    INDEX idx_created (created_at),
+    INDEX idx_action (action),
+    INDEX idx_actor_key (actor_key_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
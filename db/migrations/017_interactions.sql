@@ -0,0 +1,15 @@
+-- 非 DNS 的交互记录（HTTP 回连等），按 token 关联 dns_tokens
+CREATE TABLE IF NOT EXISTS interactions (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    token VARCHAR(128) NOT NULL,
+    protocol VARCHAR(16) NOT NULL,
+    client_ip VARCHAR(64) NOT NULL DEFAULT '',
+    listener VARCHAR(64) NOT NULL DEFAULT '',
+    summary VARCHAR(512) NOT NULL DEFAULT '',
+    details TEXT,
+    data BLOB,
+    data_size BIGINT NOT NULL DEFAULT 0,
+    created_at BIGINT NOT NULL,
+    INDEX idx_token_created (token, created_at),
+    INDEX idx_created (created_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
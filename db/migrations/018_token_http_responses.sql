@@ -0,0 +1,10 @@
+-- token 的 HTTP 回连自定义响应（托管 DTD、跳转等）
+CREATE TABLE IF NOT EXISTS token_http_responses (
+    token VARCHAR(128) NOT NULL PRIMARY KEY,
+    status_code INT NOT NULL DEFAULT 200,
+    headers TEXT,
+    body MEDIUMBLOB,
+    redirect_url VARCHAR(2048) NOT NULL DEFAULT '',
+    created_at BIGINT NOT NULL,
+    updated_at BIGINT NOT NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
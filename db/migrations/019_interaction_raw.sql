@@ -0,0 +1,7 @@
+-- 交互的完整原始内容（如 SMTP 的 .eml），列表接口只返回截断后的 data
+CREATE TABLE IF NOT EXISTS interaction_raw (
+    interaction_id BIGINT NOT NULL PRIMARY KEY,
+    content MEDIUMBLOB NOT NULL,
+    created_at BIGINT NOT NULL,
+    INDEX idx_created (created_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
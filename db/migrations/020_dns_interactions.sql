@@ -0,0 +1,7 @@
+-- DNS 记录回填进度；服务启动后在后台按 id 分批将已有的带 token 的 DNS 记录复制到交互时间线（protocol = dns）
+CREATE TABLE IF NOT EXISTS interaction_backfill (
+    name VARCHAR(32) NOT NULL PRIMARY KEY,
+    last_id BIGINT NOT NULL DEFAULT 0,
+    max_id BIGINT NOT NULL DEFAULT 0,
+    updated_at BIGINT NOT NULL DEFAULT 0
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
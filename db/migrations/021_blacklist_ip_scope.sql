@@ -0,0 +1,4 @@
+-- 同一 IP/CIDR 的不同 scope 各自一条（如 http 与 dns 分别封禁），唯一键由 ip 改为 (ip, scope)
+ALTER TABLE ip_blacklist
+  DROP INDEX ip,
+  ADD UNIQUE KEY uniq_ip_scope (ip, scope);
@@ -0,0 +1,4 @@
+-- 区分 token 命中通知与管理事件投递；管理事件不读取任何 token 的 webhook 自定义头与认证
+ALTER TABLE webhook_jobs
+  ADD COLUMN kind VARCHAR(16) NOT NULL DEFAULT 'token' AFTER id;
+UPDATE webhook_jobs SET kind = 'admin_event' WHERE token = '_admin_events';
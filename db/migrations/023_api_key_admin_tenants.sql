@@ -0,0 +1,4 @@
+-- "*" 不再隐含 admin:tenants：引入租户前的 key（仅 "*"，归属 default 租户）显式补上，保留跨租户与无归属 token 的可见性
+UPDATE api_keys
+SET scopes = '*,admin:tenants'
+WHERE scopes = '*' AND tenant = 'default';
@@ -0,0 +1,3 @@
+-- 同一封邮件发往多个 token 时原始报文只保存一份，其余交互通过 raw_id 引用
+ALTER TABLE interactions
+  ADD COLUMN raw_id BIGINT NOT NULL DEFAULT 0 AFTER data_size;
@@ -0,0 +1,157 @@
+package dnslog
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/genwilliam/dnslog_for_go/internal/infra"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	acmeChallengePrefix = "_acme-challenge."
+	acmeChallengeTTL    = 10 * time.Minute // Redis 中挑战值的保留时间，防止异常退出后残留
+	// acmeChallengeActiveKey 任一实例有进行中的订单时存在，TTL 与挑战值相同
+	acmeChallengeActiveKey = "acme:challenge:active"
+	acmePendingCheckEvery  = time.Second
+)
+
+// acmePendingCache 缓存 Redis 中是否有进行中的挑战，每个周期最多查询一次；
+// 没有订单时 _acme-challenge 查询不访问 Redis，避免未鉴权、未限流的查询放大到 Redis
+type acmePendingCache struct {
+	mu         sync.Mutex
+	checkedAt  time.Time
+	pending    bool
+	refreshing bool
+}
+
+var acmeRemotePending = &acmePendingCache{}
+
+// get 返回缓存结果；到期时由一个调用方执行 check 刷新，其他调用方不等待，直接使用旧值
+func (p *acmePendingCache) get(now time.Time, check func() bool) bool {
+	p.mu.Lock()
+	if p.refreshing || now.Sub(p.checkedAt) < acmePendingCheckEvery {
+		pending := p.pending
+		p.mu.Unlock()
+		return pending
+	}
+	p.refreshing = true
+	p.mu.Unlock()
+
+	pending := check()
+
+	p.mu.Lock()
+	p.pending, p.checkedAt, p.refreshing = pending, now, false
+	p.mu.Unlock()
+	return pending
+}
+
+// acmeChallenges 本实例发布的 DNS-01 TXT 值；配置了 Redis 时同时写入，使其他实例也能应答
+var acmeChallenges = struct {
+	sync.RWMutex
+	values map[string][]string
+}{values: make(map[string][]string)}
+
+// ACMEChallengeSolver 由本服务的 DNS 直接应答 _acme-challenge TXT 查询
+type ACMEChallengeSolver struct{}
+
+func (ACMEChallengeSolver) Present(ctx context.Context, fqdn, value string) error {
+	name := acmeChallengeKey(fqdn)
+	acmeChallenges.Lock()
+	acmeChallenges.values[name] = append(acmeChallenges.values[name], value)
+	acmeChallenges.Unlock()
+
+	if client := infra.GetRedis(); client != nil {
+		key := "acme:challenge:" + name
+		if err := client.SAdd(ctx, key, value).Err(); err != nil {
+			return err
+		}
+		if err := client.Expire(ctx, key, acmeChallengeTTL).Err(); err != nil {
+			return err
+		}
+		return client.Set(ctx, acmeChallengeActiveKey, 1, acmeChallengeTTL).Err()
+	}
+	return nil
+}
+
+func (ACMEChallengeSolver) CleanUp(ctx context.Context, fqdn, value string) error {
+	name := acmeChallengeKey(fqdn)
+	acmeChallenges.Lock()
+	kept := acmeChallenges.values[name][:0]
+	for _, v := range acmeChallenges.values[name] {
+		if v != value {
+			kept = append(kept, v)
+		}
+	}
+	if len(kept) == 0 {
+		delete(acmeChallenges.values, name)
+	} else {
+		acmeChallenges.values[name] = kept
+	}
+	acmeChallenges.Unlock()
+
+	if client := infra.GetRedis(); client != nil {
+		return client.SRem(ctx, "acme:challenge:"+name, value).Err()
+	}
+	return nil
+}
+
+func acmeChallengeKey(fqdn string) string {
+	return strings.ToLower(strings.TrimSuffix(fqdn, "."))
+}
+
+// acmeChallengeValues 返回 name 当前发布的 TXT 值，优先本地；只有其他实例有进行中的订单时才查 Redis
+func acmeChallengeValues(ctx context.Context, name string) []string {
+	acmeChallenges.RLock()
+	values := append([]string(nil), acmeChallenges.values[name]...)
+	acmeChallenges.RUnlock()
+	if len(values) > 0 {
+		return values
+	}
+	client := infra.GetRedis()
+	if client == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	defer cancel()
+	pending := acmeRemotePending.get(time.Now(), func() bool {
+		n, err := client.Exists(ctx, acmeChallengeActiveKey).Result()
+		return err == nil && n > 0
+	})
+	if !pending {
+		return nil
+	}
+	values, _ = client.SMembers(ctx, "acme:challenge:"+name).Result()
+	return values
+}
+
+// answerACMEChallenge 对根域下存在挑战值的 _acme-challenge 名称给出权威应答；
+// 返回 false 时按普通查询处理
+func answerACMEChallenge(w dns.ResponseWriter, r *dns.Msg) bool {
+	q := r.Question[0]
+	name := acmeChallengeKey(q.Name)
+	if !strings.HasPrefix(name, acmeChallengePrefix) || matchRootDomain(name) == "" {
+		return false
+	}
+	values := acmeChallengeValues(context.Background(), name)
+	if len(values) == 0 {
+		return false
+	}
+
+	m := new(dns.Msg)
+	m.SetReply(r)
+	m.Authoritative = true
+	if q.Qtype == dns.TypeTXT || q.Qtype == dns.TypeANY {
+		for _, v := range values {
+			m.Answer = append(m.Answer, &dns.TXT{
+				Hdr: dns.RR_Header{Name: q.Name, Rrtype: dns.TypeTXT, Class: dns.ClassINET, Ttl: 0},
+				Txt: []string{v},
+			})
+		}
+	}
+	_ = w.WriteMsg(m)
+	return true
+}
@@ -0,0 +1,79 @@
+package dnslog
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type captureWriter struct {
+	dns.ResponseWriter
+	msg *dns.Msg
+}
+
+func (w *captureWriter) WriteMsg(m *dns.Msg) error {
+	w.msg = m
+	return nil
+}
+
+func (w *captureWriter) RemoteAddr() net.Addr {
+	return &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 5353}
+}
+
+func TestAnswerACMEChallenge(t *testing.T) {
+	oldRoot := rootDomain
+	rootDomain = "demo.com"
+	t.Cleanup(func() { rootDomain = oldRoot })
+
+	ctx := context.Background()
+	var solver ACMEChallengeSolver
+	require.NoError(t, solver.Present(ctx, "_acme-challenge.Demo.com.", "v1"))
+	require.NoError(t, solver.Present(ctx, "_acme-challenge.demo.com", "v2"))
+
+	query := func(name string, qtype uint16) (bool, *dns.Msg) {
+		r := new(dns.Msg)
+		r.SetQuestion(name, qtype)
+		w := &captureWriter{}
+		return answerACMEChallenge(w, r), w.msg
+	}
+
+	ok, m := query("_acme-challenge.demo.com.", dns.TypeTXT)
+	require.True(t, ok)
+	assert.True(t, m.Authoritative)
+	require.Len(t, m.Answer, 2)
+	assert.Equal(t, []string{"v1"}, m.Answer[0].(*dns.TXT).Txt)
+
+	ok, m = query("_acme-challenge.demo.com.", dns.TypeA)
+	assert.True(t, ok)
+	assert.Empty(t, m.Answer)
+
+	ok, _ = query("_acme-challenge.other.com.", dns.TypeTXT)
+	assert.False(t, ok, "只应答配置的根域")
+
+	require.NoError(t, solver.CleanUp(ctx, "_acme-challenge.demo.com", "v1"))
+	require.NoError(t, solver.CleanUp(ctx, "_acme-challenge.demo.com", "v2"))
+	ok, _ = query("_acme-challenge.demo.com.", dns.TypeTXT)
+	assert.False(t, ok)
+}
+
+func TestACMEPendingCache(t *testing.T) {
+	var p acmePendingCache
+	checks := 0
+	check := func() bool {
+		checks++
+		return true
+	}
+	now := time.Now()
+	assert.True(t, p.get(now, check))
+	assert.True(t, p.get(now.Add(acmePendingCheckEvery/2), check))
+	assert.Equal(t, 1, checks, "周期内不重复查询 Redis")
+
+	assert.False(t, p.get(now.Add(acmePendingCheckEvery), func() bool { return false }))
+	assert.False(t, p.get(now.Add(acmePendingCheckEvery+time.Millisecond), check))
+	assert.Equal(t, 1, checks)
+}
@@ -0,0 +1,160 @@
+package dnslog
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/genwilliam/dnslog_for_go/config"
+	"github.com/genwilliam/dnslog_for_go/pkg/log"
+	"github.com/genwilliam/dnslog_for_go/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// 管理操作类型
+const (
+	AdminActionUpstreamChange   = "upstream.change"
+	AdminActionProtocolChange   = "protocol.change"
+	AdminActionSystemPause      = "system.pause"
+	AdminActionSystemStart      = "system.start"
+	AdminActionAPIKeyCreate     = "api_key.create"
+	AdminActionAPIKeyRotate     = "api_key.rotate"
+	AdminActionAPIKeyDisable    = "api_key.disable"
+	AdminActionBlacklistAdd     = "blacklist.add"
+	AdminActionBlacklistDisable = "blacklist.disable"
+)
+
+// AdminReasonHeader 调用方说明变更原因的请求头
+const AdminReasonHeader = "X-Change-Reason"
+
+// adminEventWebhookToken 管理事件投递任务在 webhook_jobs 中的占位 token，仅用于日志；
+// 任务以 kind=admin_event 区分，不读取任何 token 的 webhook 配置
+const adminEventWebhookToken = "_admin_events"
+
+// RecordAdminEvent 记录一次已生效的管理操作；before/after 为任意可 JSON 序列化的值，nil 表示不存在。
+// 写入失败只记日志，不影响已完成的操作
+func RecordAdminEvent(c *gin.Context, action, target string, before, after interface{}) {
+	e := newAdminEvent(c, action, target, before, after)
+	id, err := AddAdminEventWithContext(c.Request.Context(), e)
+	if err != nil {
+		log.Error("record admin event failed", zap.String("action", action), zap.String("target", target), zap.Error(err))
+		return
+	}
+	e.ID = id
+	enqueueAdminEventWebhook(e)
+}
+
+// newAdminEvent 从请求上下文提取操作者、原因与 trace ID
+func newAdminEvent(c *gin.Context, action, target string, before, after interface{}) AdminEvent {
+	e := AdminEvent{
+		Action:    action,
+		Target:    truncate(target, 128),
+		Before:    marshalAdminValue(before),
+		After:     marshalAdminValue(after),
+		Reason:    truncate(strings.TrimSpace(c.GetHeader(AdminReasonHeader)), 255),
+		TraceID:   c.GetString("trace_id"),
+		ClientIP:  c.ClientIP(),
+		CreatedAt: time.Now().UnixMilli(),
+	}
+	if v, ok := c.Get("api_key_id"); ok {
+		e.ActorKeyID, _ = v.(int64)
+	}
+	if id, ok := requestUserID(c); ok {
+		e.ActorUserID = id
+		e.ActorName = c.GetString("username")
+	}
+	e.Tenant = c.GetString("api_key_tenant")
+	return e
+}
+
+func marshalAdminValue(v interface{}) json.RawMessage {
+	if v == nil {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// enqueueAdminEventWebhook 配置了 adminEventWebhookURL 时，经由 webhook 任务队列推送事件（复用重试与熔断）
+func enqueueAdminEventWebhook(e AdminEvent) {
+	cfg := config.Get()
+	if cfg == nil || !cfg.WebhookEnabled || cfg.AdminEventWebhookURL == "" {
+		return
+	}
+	payload, err := json.Marshal(map[string]interface{}{
+		"type":  "admin_event",
+		"event": e,
+	})
+	if err != nil {
+		return
+	}
+	encSecret, err := EncryptWebhookSecret(cfg.AdminEventWebhookSecret)
+	if err != nil {
+		log.Error("admin event webhook secret unavailable", zap.Error(err))
+		return
+	}
+	nowMs := time.Now().UnixMilli()
+	jobID, err := CreateWebhookJobWithContext(context.Background(), WebhookJob{
+		Kind:        WebhookJobKindAdminEvent,
+		Token:       adminEventWebhookToken,
+		URL:         cfg.AdminEventWebhookURL,
+		Payload:     string(payload),
+		Secret:      encSecret,
+		NextRetryAt: nowMs,
+		CreatedAt:   nowMs,
+		UpdatedAt:   nowMs,
+	})
+	if err != nil {
+		log.Error("create admin event webhook job failed", zap.Error(err))
+		return
+	}
+	_ = EnqueueWebhookJob(jobID)
+}
+
+// ListAdminEventsHandler 查询管理操作变更记录（游标分页，按 id 倒序）
+func ListAdminEventsHandler(c *gin.Context) {
+	filter := AdminEventFilter{
+		Action: c.Query("action"),
+		Target: c.Query("target"),
+		Tenant: RequestListTenant(c),
+	}
+	for name, dst := range map[string]*int64{
+		"actor_key_id": &filter.ActorKeyID,
+		"start":        &filter.Start,
+		"end":          &filter.End,
+		"cursor":       &filter.Cursor,
+	} {
+		if v := c.Query(name); v != "" {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || n < 0 {
+				response.Error(c, http.StatusBadRequest, response.CodeBadRequest)
+				return
+			}
+			*dst = n
+		}
+	}
+	cfg := config.Get()
+	filter.Limit = queryPositiveInt(c, "pageSize", cfg.DefaultPageSize)
+	if filter.Limit > cfg.MaxPageSize {
+		filter.Limit = cfg.MaxPageSize
+	}
+
+	items, next, err := ListAdminEventsWithContext(c.Request.Context(), filter)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError)
+		return
+	}
+	response.Success(c, gin.H{
+		"items":       items,
+		"size":        filter.Limit,
+		"next_cursor": next,
+	})
+}
@@ -0,0 +1,160 @@
+package dnslog
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// AdminEvent 管理操作的变更记录；Before/After 为 JSON，新建时 Before 为空
+type AdminEvent struct {
+	ID          int64           `json:"id"`
+	Action      string          `json:"action"`
+	Target      string          `json:"target"`
+	ActorKeyID  int64           `json:"actor_key_id"`
+	ActorUserID int64           `json:"actor_user_id"`
+	ActorName   string          `json:"actor_name"`
+	Tenant      string          `json:"tenant"`
+	Before      json.RawMessage `json:"before"`
+	After       json.RawMessage `json:"after"`
+	Reason      string          `json:"reason"`
+	TraceID     string          `json:"trace_id"`
+	ClientIP    string          `json:"client_ip"`
+	CreatedAt   int64           `json:"created_at"`
+}
+
+// AdminEventFilter 变更记录查询条件；Cursor 为上一页最后一条的 id
+type AdminEventFilter struct {
+	Action     string
+	Target     string
+	ActorKeyID int64
+	Start      int64
+	End        int64
+	Cursor     int64
+	Limit      int
+
+	Tenant string
+}
+
+func AddAdminEventWithContext(ctx context.Context, e AdminEvent) (int64, error) {
+	if db == nil {
+		return 0, errors.New("store not initialized")
+	}
+	ctx = ensureContext(ctx)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	res, err := db.ExecContext(ctx, `
+INSERT INTO admin_events (action, target, actor_key_id, actor_user_id, actor_name, tenant, before_value, after_value, reason, trace_id, client_ip, created_at)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+`, e.Action, e.Target, nullID(e.ActorKeyID), nullID(e.ActorUserID), e.ActorName, e.Tenant,
+		nullJSON(e.Before), nullJSON(e.After), e.Reason, e.TraceID, e.ClientIP, e.CreatedAt)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// ListAdminEventsWithContext 按 id 倒序查询；返回的 nextCursor 为 0 表示没有更多数据
+func ListAdminEventsWithContext(ctx context.Context, filter AdminEventFilter) ([]AdminEvent, int64, error) {
+	if db == nil {
+		return nil, 0, errors.New("store not initialized")
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = 20
+	}
+	ctx = ensureContext(ctx)
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	where := []string{}
+	args := []interface{}{}
+	if filter.Action != "" {
+		where = append(where, "action = ?")
+		args = append(args, filter.Action)
+	}
+	if filter.Target != "" {
+		where = append(where, "target = ?")
+		args = append(args, filter.Target)
+	}
+	if filter.ActorKeyID > 0 {
+		where = append(where, "actor_key_id = ?")
+		args = append(args, filter.ActorKeyID)
+	}
+	if filter.Tenant != "" {
+		where = append(where, "tenant = ?")
+		args = append(args, filter.Tenant)
+	}
+	if filter.Start > 0 {
+		where = append(where, "created_at >= ?")
+		args = append(args, filter.Start)
+	}
+	if filter.End > 0 {
+		where = append(where, "created_at <= ?")
+		args = append(args, filter.End)
+	}
+	if filter.Cursor > 0 {
+		where = append(where, "id < ?")
+		args = append(args, filter.Cursor)
+	}
+	whereSQL := ""
+	if len(where) > 0 {
+		whereSQL = "WHERE " + strings.Join(where, " AND ")
+	}
+
+	rows, err := db.QueryContext(ctx, `
+SELECT id, action, target, actor_key_id, actor_user_id, actor_name, tenant,
+       COALESCE(before_value, ''), COALESCE(after_value, ''), reason, trace_id, client_ip, created_at
+FROM admin_events
+`+whereSQL+`
+ORDER BY id DESC
+LIMIT ?`, append(args, filter.Limit+1)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("query admin events: %w", err)
+	}
+	defer rows.Close()
+
+	items := make([]AdminEvent, 0, filter.Limit)
+	for rows.Next() {
+		var e AdminEvent
+		var keyID, userID sql.NullInt64
+		var before, after string
+		if err := rows.Scan(&e.ID, &e.Action, &e.Target, &keyID, &userID, &e.ActorName, &e.Tenant,
+			&before, &after, &e.Reason, &e.TraceID, &e.ClientIP, &e.CreatedAt); err != nil {
+			return nil, 0, fmt.Errorf("scan admin event: %w", err)
+		}
+		e.ActorKeyID = keyID.Int64
+		e.ActorUserID = userID.Int64
+		if before != "" {
+			e.Before = json.RawMessage(before)
+		}
+		if after != "" {
+			e.After = json.RawMessage(after)
+		}
+		items = append(items, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	var next int64
+	if len(items) > filter.Limit {
+		items = items[:filter.Limit]
+		next = items[len(items)-1].ID
+	}
+	return items, next, nil
+}
+
+func ListAdminEvents(filter AdminEventFilter) ([]AdminEvent, int64, error) {
+	return ListAdminEventsWithContext(context.Background(), filter)
+}
+
+func nullID(id int64) sql.NullInt64 {
+	return sql.NullInt64{Int64: id, Valid: id > 0}
+}
+
+func nullJSON(v json.RawMessage) sql.NullString {
+	return sql.NullString{String: string(v), Valid: len(v) > 0}
+}
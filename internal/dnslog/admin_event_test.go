@@ -0,0 +1,51 @@
+package dnslog
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewAdminEvent(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("POST", "/api/change-pact", nil)
+	c.Request.Header.Set(AdminReasonHeader, "  switch to tcp for testing ")
+	c.Set("trace_id", "trace-1")
+	c.Set("api_key_id", int64(7))
+	c.Set("api_key_tenant", "team-a")
+	c.Set("user_id", int64(3))
+	c.Set("username", "alice")
+
+	e := newAdminEvent(c, AdminActionProtocolChange, "protocol", gin.H{"protocol": "udp"}, gin.H{"protocol": "tcp"})
+	assert.Equal(t, AdminActionProtocolChange, e.Action)
+	assert.Equal(t, "protocol", e.Target)
+	assert.Equal(t, int64(7), e.ActorKeyID)
+	assert.Equal(t, int64(3), e.ActorUserID)
+	assert.Equal(t, "alice", e.ActorName)
+	assert.Equal(t, "team-a", e.Tenant)
+	assert.Equal(t, "switch to tcp for testing", e.Reason)
+	assert.Equal(t, "trace-1", e.TraceID)
+	assert.JSONEq(t, `{"protocol":"udp"}`, string(e.Before))
+	assert.JSONEq(t, `{"protocol":"tcp"}`, string(e.After))
+	assert.NotZero(t, e.CreatedAt)
+
+	e = newAdminEvent(c, AdminActionAPIKeyCreate, "8", nil, gin.H{"id": 8})
+	assert.Nil(t, e.Before, "nil before means the object did not exist")
+}
+
+func TestAdminEventJobSkipsTokenWebhook(t *testing.T) {
+	assert.True(t, WebhookJob{Kind: WebhookJobKindToken, Token: "abc123"}.usesTokenWebhook())
+	assert.False(t, WebhookJob{Kind: WebhookJobKindAdminEvent, Token: adminEventWebhookToken}.usesTokenWebhook())
+}
+
+func TestSetTokenWebhookRejectsReservedToken(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("POST", "/api/tokens/_admin_events/webhook", nil)
+	c.Params = gin.Params{{Key: "token", Value: adminEventWebhookToken}}
+	SetTokenWebhookHandler(c)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
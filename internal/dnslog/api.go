@@ -38,6 +38,7 @@ func ListRecordsHandler(c *gin.Context) {
 		QType:    c.Query("qtype"),
 		Token:    c.Query("token"),
 		Order:    order,
+		Tenant:   RequestListTenant(c),
 	}
 	if cursorStr != "" {
 		if v, err := strconv.ParseInt(cursorStr, 10, 64); err == nil {
@@ -0,0 +1,178 @@
+package dnslog
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/genwilliam/dnslog_for_go/config"
+	"github.com/genwilliam/dnslog_for_go/pkg/log"
+	"github.com/genwilliam/dnslog_for_go/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const (
+	auditExportBatch   = 500
+	auditExportMaxRows = 100000 // 单次导出上限，超出时返回 export_too_large，需按时间范围分段导出
+)
+
+// ListAuditLogsHandler 按条件查询审计日志（游标分页，按 id 倒序）
+func ListAuditLogsHandler(c *gin.Context) {
+	filter, ok := auditFilterFromQuery(c)
+	if !ok {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest)
+		return
+	}
+	cfg := config.Get()
+	filter.Limit = queryPositiveInt(c, "pageSize", cfg.DefaultPageSize)
+	if filter.Limit > cfg.MaxPageSize {
+		filter.Limit = cfg.MaxPageSize
+	}
+
+	items, next, err := ListAuditLogsWithContext(c.Request.Context(), filter)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError)
+		return
+	}
+	response.Success(c, gin.H{
+		"items":       items,
+		"size":        filter.Limit,
+		"next_cursor": next,
+	})
+}
+
+// ExportAuditLogsHandler 以 NDJSON（默认）或 CSV 流式导出审计日志
+func ExportAuditLogsHandler(c *gin.Context) {
+	filter, ok := auditFilterFromQuery(c)
+	if !ok {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest)
+		return
+	}
+	format := strings.ToLower(c.DefaultQuery("format", "ndjson"))
+	if format != "ndjson" && format != "csv" {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest)
+		return
+	}
+	filter.Limit = auditExportBatch
+
+	// 超过上限时直接拒绝，避免导出被截断而调用方无从得知
+	tooLarge, err := AuditLogsExceedWithContext(c.Request.Context(), filter, auditExportMaxRows)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError)
+		return
+	}
+	if tooLarge {
+		response.Error(c, http.StatusBadRequest, response.CodeExportTooLarge)
+		return
+	}
+
+	// 先查第一批，出错时仍可返回 JSON 错误
+	items, next, err := ListAuditLogsWithContext(c.Request.Context(), filter)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError)
+		return
+	}
+
+	filename := fmt.Sprintf("audit-%d.%s", time.Now().Unix(), format)
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	if format == "csv" {
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+	} else {
+		c.Header("Content-Type", "application/x-ndjson")
+	}
+	c.Status(http.StatusOK)
+
+	var write func(AuditEntry) error
+	var csvWriter *csv.Writer
+	if format == "csv" {
+		csvWriter = csv.NewWriter(c.Writer)
+		_ = csvWriter.Write([]string{"id", "trace_id", "api_key_id", "path", "method", "client_ip", "status_code", "latency_ms", "token", "created_at"})
+		write = func(e AuditEntry) error {
+			return csvWriter.Write([]string{
+				strconv.FormatInt(e.ID, 10),
+				csvSafe(e.TraceID),
+				strconv.FormatInt(e.APIKeyID, 10),
+				csvSafe(e.Path),
+				csvSafe(e.Method),
+				csvSafe(e.ClientIP),
+				strconv.Itoa(e.StatusCode),
+				strconv.FormatInt(e.LatencyMs, 10),
+				csvSafe(e.Token),
+				strconv.FormatInt(e.CreatedAt, 10),
+			})
+		}
+	} else {
+		enc := json.NewEncoder(c.Writer)
+		write = func(e AuditEntry) error { return enc.Encode(e) }
+	}
+
+	written := 0
+	for {
+		for _, e := range items {
+			if err := write(e); err != nil {
+				return
+			}
+		}
+		written += len(items)
+		if csvWriter != nil {
+			csvWriter.Flush()
+		}
+		c.Writer.Flush()
+		if next == 0 || written >= auditExportMaxRows || c.Request.Context().Err() != nil {
+			return
+		}
+		filter.Cursor = next
+		items, next, err = ListAuditLogsWithContext(c.Request.Context(), filter)
+		if err != nil {
+			log.Error("audit export aborted", zap.Int("written", written), zap.Error(err))
+			return
+		}
+	}
+}
+
+// auditFilterFromQuery 解析查询参数；数值参数格式错误时返回 false
+func auditFilterFromQuery(c *gin.Context) (AuditFilter, bool) {
+	filter := AuditFilter{
+		Path:     c.Query("path"),
+		Method:   strings.ToUpper(c.Query("method")),
+		ClientIP: c.Query("client_ip"),
+		Token:    c.Query("token"),
+		Tenant:   RequestListTenant(c),
+	}
+	for name, dst := range map[string]*int64{
+		"start":      &filter.Start,
+		"end":        &filter.End,
+		"api_key_id": &filter.APIKeyID,
+		"cursor":     &filter.Cursor,
+	} {
+		if v := c.Query(name); v != "" {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || n < 0 {
+				return AuditFilter{}, false
+			}
+			*dst = n
+		}
+	}
+	if v := c.Query("status"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 100 || n > 599 {
+			return AuditFilter{}, false
+		}
+		filter.StatusCode = n
+	}
+	return filter, true
+}
+
+// csvSafe 防止以公式字符开头的字段在表格软件中被当作公式执行
+func csvSafe(val string) string {
+	if val != "" && strings.ContainsRune("=+-@\t\r", rune(val[0])) {
+		return "'" + val
+	}
+	return val
+}
@@ -5,16 +5,16 @@ import (
 	"encoding/json"
 	"time"
 
-	"github.com/genwilliam/dnslog_for_go/internal/infra"
 	"github.com/genwilliam/dnslog_for_go/pkg/log"
 	"go.uber.org/zap"
 )
 
 const auditQueueKey = "audit:queue"
 
+// EnqueueAuditLog 审计日志入队；队列不可用或已满时同步写库，不丢审计
 func EnqueueAuditLog(logEntry AuditLog) error {
-	client := infra.GetRedis()
-	if client == nil {
+	q, err := getQueue(auditQueueKey)
+	if err != nil {
 		return AddAuditLog(logEntry)
 	}
 	data, err := json.Marshal(logEntry)
@@ -23,24 +23,31 @@ func EnqueueAuditLog(logEntry AuditLog) error {
 	}
 	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
 	defer cancel()
-	return client.LPush(ctx, auditQueueKey, data).Err()
+	if err := q.Push(ctx, string(data)); err != nil {
+		return AddAuditLog(logEntry)
+	}
+	return nil
 }
 
 func StartAuditWorker() {
-	client := infra.GetRedis()
-	if client == nil {
+	q, err := getQueue(auditQueueKey)
+	if err != nil {
+		log.Error("audit queue unavailable", zap.Error(err))
 		return
 	}
+	log.Info("audit worker started", zap.String("queue", q.Backend()))
 	go func() {
 		for {
-			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-			res, err := client.BRPop(ctx, 3*time.Second, auditQueueKey).Result()
-			cancel()
-			if err != nil || len(res) < 2 {
+			val, ok, err := q.Pop(context.Background(), 3*time.Second)
+			if err != nil {
+				time.Sleep(time.Second)
+				continue
+			}
+			if !ok {
 				continue
 			}
 			var entry AuditLog
-			if err := json.Unmarshal([]byte(res[1]), &entry); err != nil {
+			if err := json.Unmarshal([]byte(val), &entry); err != nil {
 				log.Error("decode audit log failed", zap.Error(err))
 				continue
 			}
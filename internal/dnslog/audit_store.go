@@ -0,0 +1,148 @@
+package dnslog
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// AuditEntry 查询返回的审计日志（api_key_id 为 0 表示未使用 key）
+type AuditEntry struct {
+	ID         int64  `json:"id"`
+	TraceID    string `json:"trace_id"`
+	APIKeyID   int64  `json:"api_key_id"`
+	Path       string `json:"path"`
+	Method     string `json:"method"`
+	ClientIP   string `json:"client_ip"`
+	StatusCode int    `json:"status_code"`
+	LatencyMs  int64  `json:"latency_ms"`
+	Token      string `json:"token"`
+	CreatedAt  int64  `json:"created_at"`
+}
+
+// AuditFilter 审计日志查询条件；字符串字段精确匹配，Cursor 为上一页最后一条的 id
+type AuditFilter struct {
+	Start      int64
+	End        int64
+	APIKeyID   int64
+	Path       string
+	Method     string
+	ClientIP   string
+	StatusCode int
+	Token      string
+	Cursor     int64
+	Limit      int
+
+	Tenant string // 非空时只返回该租户 key 产生的日志
+}
+
+// ListAuditLogsWithContext 按 id 倒序查询；返回的 nextCursor 为 0 表示没有更多数据
+func ListAuditLogsWithContext(ctx context.Context, filter AuditFilter) ([]AuditEntry, int64, error) {
+	if db == nil {
+		return nil, 0, errors.New("store not initialized")
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = 20
+	}
+	ctx = ensureContext(ctx)
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	whereSQL, args := auditWhere(filter)
+
+	// 多取一条用于判断是否还有下一页
+	rows, err := db.QueryContext(ctx, `
+SELECT id, trace_id, api_key_id, path, method, client_ip, status_code, latency_ms, COALESCE(token, ''), created_at
+FROM audit_logs
+`+whereSQL+`
+ORDER BY id DESC
+LIMIT ?`, append(args, filter.Limit+1)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("query audit logs: %w", err)
+	}
+	defer rows.Close()
+
+	items := make([]AuditEntry, 0, filter.Limit)
+	for rows.Next() {
+		var e AuditEntry
+		var keyID sql.NullInt64
+		if err := rows.Scan(&e.ID, &e.TraceID, &keyID, &e.Path, &e.Method, &e.ClientIP, &e.StatusCode, &e.LatencyMs, &e.Token, &e.CreatedAt); err != nil {
+			return nil, 0, fmt.Errorf("scan audit log: %w", err)
+		}
+		e.APIKeyID = keyID.Int64
+		items = append(items, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	var next int64
+	if len(items) > filter.Limit {
+		items = items[:filter.Limit]
+		next = items[len(items)-1].ID
+	}
+	return items, next, nil
+}
+
+func ListAuditLogs(filter AuditFilter) ([]AuditEntry, int64, error) {
+	return ListAuditLogsWithContext(context.Background(), filter)
+}
+
+// auditWhere 按过滤条件生成 WHERE 子句与参数
+func auditWhere(filter AuditFilter) (string, []interface{}) {
+	where := []string{}
+	args := []interface{}{}
+	addEq := func(col string, val interface{}, set bool) {
+		if set {
+			where = append(where, col+" = ?")
+			args = append(args, val)
+		}
+	}
+	addEq("api_key_id", filter.APIKeyID, filter.APIKeyID > 0)
+	addEq("path", filter.Path, filter.Path != "")
+	addEq("method", filter.Method, filter.Method != "")
+	addEq("client_ip", filter.ClientIP, filter.ClientIP != "")
+	addEq("status_code", filter.StatusCode, filter.StatusCode > 0)
+	addEq("token", filter.Token, filter.Token != "")
+	if filter.Tenant != "" {
+		where = append(where, "api_key_id IN (SELECT id FROM api_keys WHERE tenant = ?)")
+		args = append(args, filter.Tenant)
+	}
+	if filter.Start > 0 {
+		where = append(where, "created_at >= ?")
+		args = append(args, filter.Start)
+	}
+	if filter.End > 0 {
+		where = append(where, "created_at <= ?")
+		args = append(args, filter.End)
+	}
+	if filter.Cursor > 0 {
+		where = append(where, "id < ?")
+		args = append(args, filter.Cursor)
+	}
+	whereSQL := ""
+	if len(where) > 0 {
+		whereSQL = "WHERE " + strings.Join(where, " AND ")
+	}
+	return whereSQL, args
+}
+
+// AuditLogsExceedWithContext 判断匹配的日志是否超过 max 条；只扫描到第 max+1 条
+func AuditLogsExceedWithContext(ctx context.Context, filter AuditFilter, max int) (bool, error) {
+	if db == nil {
+		return false, errors.New("store not initialized")
+	}
+	ctx = ensureContext(ctx)
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	whereSQL, args := auditWhere(filter)
+	var n int
+	if err := db.QueryRowContext(ctx, `
+SELECT COUNT(*) FROM (SELECT id FROM audit_logs `+whereSQL+` LIMIT ?) AS t`, append(args, max+1)...).Scan(&n); err != nil {
+		return false, fmt.Errorf("count audit logs: %w", err)
+	}
+	return n > max, nil
+}
@@ -0,0 +1,52 @@
+package dnslog
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditFilterFromQuery(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/audit?start=10&end=20&api_key_id=3&path=/api/keys&method=post&client_ip=1.2.3.4&status=403&token=abc&cursor=99", nil)
+	filter, ok := auditFilterFromQuery(c)
+	assert.True(t, ok)
+	assert.Equal(t, AuditFilter{
+		Start: 10, End: 20, APIKeyID: 3, Path: "/api/keys", Method: "POST",
+		ClientIP: "1.2.3.4", StatusCode: 403, Token: "abc", Cursor: 99,
+	}, filter)
+
+	for _, q := range []string{"start=x", "cursor=-1", "status=42", "api_key_id=1.5"} {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("GET", "/audit?"+q, nil)
+		_, ok := auditFilterFromQuery(c)
+		assert.False(t, ok, q)
+	}
+
+	c, _ = gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/audit", nil)
+	c.Set("api_key_scopes", []string{ScopeAuditRead})
+	c.Set("api_key_tenant", "team-a")
+	filter, ok = auditFilterFromQuery(c)
+	assert.True(t, ok)
+	assert.Equal(t, "team-a", filter.Tenant, "non-admin callers only see their tenant")
+}
+
+func TestCSVSafe(t *testing.T) {
+	assert.Equal(t, "'=cmd()", csvSafe("=cmd()"))
+	assert.Equal(t, "'@x", csvSafe("@x"))
+	assert.Equal(t, "/api/audit", csvSafe("/api/audit"))
+	assert.Equal(t, "", csvSafe(""))
+}
+
+func TestAuditWhere(t *testing.T) {
+	whereSQL, args := auditWhere(AuditFilter{})
+	assert.Empty(t, whereSQL)
+	assert.Empty(t, args)
+
+	whereSQL, args = auditWhere(AuditFilter{Method: "POST", Tenant: "team-a", Start: 10, End: 20})
+	assert.Equal(t, "WHERE method = ? AND api_key_id IN (SELECT id FROM api_keys WHERE tenant = ?) AND created_at >= ? AND created_at <= ?", whereSQL)
+	assert.Equal(t, []interface{}{"POST", "team-a", int64(10), int64(20)}, args)
+}
@@ -0,0 +1,201 @@
+package dnslog
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/genwilliam/dnslog_for_go/config"
+	"github.com/genwilliam/dnslog_for_go/internal/infra"
+	"github.com/genwilliam/dnslog_for_go/internal/metrics"
+	"github.com/genwilliam/dnslog_for_go/pkg/log"
+	"github.com/genwilliam/dnslog_for_go/pkg/utils"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// 自动封禁写入审计日志时使用的 method/path
+const (
+	autoBanAuditMethod = "BAN"
+	autoBanAuditPath   = "auto_ban:"
+)
+
+// autoBanCounter 违规与封禁次数计数；incrWindow 只在创建时设置过期（固定窗口），incrSliding 每次续期
+type autoBanCounter interface {
+	incrWindow(ctx context.Context, key string, ttl time.Duration) (int64, error)
+	incrSliding(ctx context.Context, key string, ttl time.Duration) (int64, error)
+	del(ctx context.Context, key string)
+}
+
+// autoBanFunc 写入封禁记录，签名同 AutoBanIPWithContext
+type autoBanFunc func(ctx context.Context, ip, scope string, expiresAt, now int64) (bool, error)
+
+type redisAutoBanCounter struct {
+	client *redis.Client
+}
+
+func (r redisAutoBanCounter) incrWindow(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	count, err := r.client.Incr(ctx, key).Result()
+	if err == nil && count == 1 {
+		_ = r.client.Expire(ctx, key, ttl).Err()
+	}
+	return count, err
+}
+
+func (r redisAutoBanCounter) incrSliding(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	count, err := r.client.Incr(ctx, key).Result()
+	if err == nil {
+		_ = r.client.Expire(ctx, key, ttl).Err()
+	}
+	return count, err
+}
+
+func (r redisAutoBanCounter) del(ctx context.Context, key string) {
+	_ = r.client.Del(ctx, key).Err()
+}
+
+// memoryAutoBanCounter Redis 不可用时的进程内计数，多实例时各自计数
+type memoryAutoBanCounter struct {
+	mu      sync.Mutex
+	entries map[string]memoryAutoBanEntry
+}
+
+type memoryAutoBanEntry struct {
+	count     int64
+	expiresAt time.Time
+}
+
+// memoryAutoBanMaxKeys 超过后先清理过期计数，避免大量来源 IP 撑大内存
+const memoryAutoBanMaxKeys = 100000
+
+var localAutoBanCounter = &memoryAutoBanCounter{entries: make(map[string]memoryAutoBanEntry)}
+
+func (m *memoryAutoBanCounter) incr(key string, ttl time.Duration, sliding bool) int64 {
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.entries) >= memoryAutoBanMaxKeys {
+		for k, e := range m.entries {
+			if now.After(e.expiresAt) {
+				delete(m.entries, k)
+			}
+		}
+	}
+	e, ok := m.entries[key]
+	if !ok || now.After(e.expiresAt) {
+		e = memoryAutoBanEntry{expiresAt: now.Add(ttl)}
+	}
+	e.count++
+	if sliding {
+		e.expiresAt = now.Add(ttl)
+	}
+	m.entries[key] = e
+	return e.count
+}
+
+func (m *memoryAutoBanCounter) incrWindow(_ context.Context, key string, ttl time.Duration) (int64, error) {
+	return m.incr(key, ttl, false), nil
+}
+
+func (m *memoryAutoBanCounter) incrSliding(_ context.Context, key string, ttl time.Duration) (int64, error) {
+	return m.incr(key, ttl, true), nil
+}
+
+func (m *memoryAutoBanCounter) del(_ context.Context, key string) {
+	m.mu.Lock()
+	delete(m.entries, key)
+	m.mu.Unlock()
+}
+
+// RecordRateLimitViolation 记录一次限流违规；窗口内违规次数达到阈值时临时封禁该 IP，
+// 返回是否触发了封禁。计数优先使用 Redis（多实例共享），不可用时在进程内计数
+func RecordRateLimitViolation(ctx context.Context, cfg *config.Config, ip, scope, traceID string) bool {
+	if cfg == nil || !cfg.AutoBanEnabled || ip == "" {
+		return false
+	}
+	var counter autoBanCounter = localAutoBanCounter
+	if client := infra.GetRedis(); client != nil {
+		counter = redisAutoBanCounter{client: client}
+	}
+	return recordViolation(ctx, cfg, counter, AutoBanIPWithContext, ip, scope, traceID)
+}
+
+func recordViolation(ctx context.Context, cfg *config.Config, counter autoBanCounter, ban autoBanFunc, ip, scope, traceID string) bool {
+	ctx = ensureContext(ctx)
+	rctx, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	defer cancel()
+
+	key := fmt.Sprintf("autoban:v:%s:%s", scope, ip)
+	count, err := counter.incrWindow(rctx, key, time.Duration(positiveOr(cfg.AutoBanWindowSeconds, 300))*time.Second)
+	if err != nil {
+		return false
+	}
+	// 只有恰好达到阈值的那次请求负责封禁，并发的后续违规不会重复写库
+	if count != int64(positiveOr(cfg.AutoBanViolations, 10)) {
+		return false
+	}
+	counter.del(rctx, key)
+
+	offense, err := counter.incrSliding(rctx, "autoban:offenses:"+ip, time.Duration(positiveOr(cfg.AutoBanResetSeconds, 604800))*time.Second)
+	if err != nil {
+		offense = 1
+	}
+
+	now := time.Now()
+	duration := autoBanDuration(cfg, int(offense))
+	expiresAt := now.Add(duration).UnixMilli()
+	banned, err := ban(ctx, ip, scope, expiresAt, now.UnixMilli())
+	if err != nil {
+		log.Error("auto ban failed", zap.String("ip", ip), zap.Error(err))
+		return false
+	}
+	if !banned {
+		return false
+	}
+
+	log.Warn("client auto banned",
+		zap.String("ip", ip),
+		zap.String("scope", scope),
+		zap.Int64("offense", offense),
+		zap.Duration("duration", duration),
+	)
+	metrics.AutoBansTotal.WithLabelValues(scope).Inc()
+	if cfg.AuditEnabled {
+		if traceID == "" {
+			traceID = utils.GenerateTraceID()
+		}
+		_ = EnqueueAuditLog(AuditLog{
+			TraceID:    traceID,
+			Path:       autoBanAuditPath + scope,
+			Method:     autoBanAuditMethod,
+			ClientIP:   ip,
+			StatusCode: http.StatusTooManyRequests,
+			CreatedAt:  now.UnixMilli(),
+		})
+	}
+	return true
+}
+
+// autoBanDuration 第 offense 次封禁的时长：基础时长按次数翻倍，不超过上限
+func autoBanDuration(cfg *config.Config, offense int) time.Duration {
+	base := time.Duration(positiveOr(cfg.AutoBanBaseSeconds, 600)) * time.Second
+	max := time.Duration(positiveOr(cfg.AutoBanMaxSeconds, 86400)) * time.Second
+	d := base
+	for i := 1; i < offense && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	return d
+}
+
+func positiveOr(v, def int) int {
+	if v <= 0 {
+		return def
+	}
+	return v
+}
@@ -0,0 +1,64 @@
+package dnslog
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/genwilliam/dnslog_for_go/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAutoBanDuration(t *testing.T) {
+	cfg := &config.Config{AutoBanBaseSeconds: 60, AutoBanMaxSeconds: 300}
+	assert.Equal(t, time.Minute, autoBanDuration(cfg, 1))
+	assert.Equal(t, 2*time.Minute, autoBanDuration(cfg, 2))
+	assert.Equal(t, 4*time.Minute, autoBanDuration(cfg, 3))
+	assert.Equal(t, 5*time.Minute, autoBanDuration(cfg, 4), "capped at max")
+	assert.Equal(t, 5*time.Minute, autoBanDuration(cfg, 1000))
+
+	assert.Equal(t, 10*time.Minute, autoBanDuration(&config.Config{}, 1), "defaults")
+}
+
+func TestRecordRateLimitViolationDisabled(t *testing.T) {
+	assert.False(t, RecordRateLimitViolation(context.Background(), nil, "1.2.3.4", BlacklistScopeHTTP, ""))
+	assert.False(t, RecordRateLimitViolation(context.Background(), &config.Config{}, "1.2.3.4", BlacklistScopeHTTP, ""))
+}
+
+func TestRecordViolationBansAtThreshold(t *testing.T) {
+	cfg := &config.Config{AutoBanEnabled: true, AutoBanViolations: 3, AutoBanBaseSeconds: 60, AutoBanMaxSeconds: 600}
+	counter := &memoryAutoBanCounter{entries: make(map[string]memoryAutoBanEntry)}
+	type banCall struct {
+		ip, scope string
+		duration  time.Duration
+	}
+	var bans []banCall
+	ban := func(_ context.Context, ip, scope string, expiresAt, now int64) (bool, error) {
+		bans = append(bans, banCall{ip, scope, time.Duration(expiresAt-now) * time.Millisecond})
+		return true, nil
+	}
+
+	ctx := context.Background()
+	assert.False(t, recordViolation(ctx, cfg, counter, ban, "1.2.3.4", BlacklistScopeHTTP, ""))
+	assert.False(t, recordViolation(ctx, cfg, counter, ban, "1.2.3.4", BlacklistScopeHTTP, ""))
+	assert.False(t, recordViolation(ctx, cfg, counter, ban, "1.2.3.4", BlacklistScopeDNS, ""), "按 scope 分别计数")
+	assert.True(t, recordViolation(ctx, cfg, counter, ban, "1.2.3.4", BlacklistScopeHTTP, ""))
+	assert.Equal(t, []banCall{{"1.2.3.4", BlacklistScopeHTTP, time.Minute}}, bans)
+
+	// 达到阈值后计数清零，再次达到阈值时封禁时长翻倍
+	for i := 0; i < 3; i++ {
+		recordViolation(ctx, cfg, counter, ban, "1.2.3.4", BlacklistScopeHTTP, "")
+	}
+	assert.Len(t, bans, 2)
+	assert.Equal(t, 2*time.Minute, bans[1].duration)
+}
+
+func TestMemoryAutoBanCounterWindow(t *testing.T) {
+	counter := &memoryAutoBanCounter{entries: make(map[string]memoryAutoBanEntry)}
+	ctx := context.Background()
+	n, _ := counter.incrWindow(ctx, "k", time.Millisecond)
+	assert.Equal(t, int64(1), n)
+	time.Sleep(2 * time.Millisecond)
+	n, _ = counter.incrWindow(ctx, "k", time.Millisecond)
+	assert.Equal(t, int64(1), n, "窗口过期后重新计数")
+}
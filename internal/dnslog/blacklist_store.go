@@ -2,82 +2,269 @@ package dnslog
 
 import (
 	"context"
+	"database/sql"
 	"errors"
+	"net/netip"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/genwilliam/dnslog_for_go/config"
 	"github.com/genwilliam/dnslog_for_go/internal/infra"
+	"github.com/genwilliam/dnslog_for_go/pkg/log"
+	"go.uber.org/zap"
 )
 
 type BlacklistEntry struct {
 	ID        int64  `json:"id"`
-	IP        string `json:"ip"`
+	IP        string `json:"ip"` // 单个 IP 或 CIDR
 	Reason    string `json:"reason"`
+	Scope     string `json:"scope"` // all | http | dns
 	Enabled   bool   `json:"enabled"`
+	ExpiresAt int64  `json:"expires_at"` // 0 表示永久
 	CreatedAt int64  `json:"created_at"`
 }
 
+// matches 条目是否对 scope 生效且未过期
+func (e BlacklistEntry) matches(scope string, nowMs int64) bool {
+	if e.ExpiresAt > 0 && nowMs >= e.ExpiresAt {
+		return false
+	}
+	return e.Scope == BlacklistScopeAll || e.Scope == scope
+}
+
 var ErrBlacklistNotFound = errors.New("blacklist_not_found")
 
-const blacklistRedisKey = "blacklist:ip"
+// blacklistChangedChannel 多实例间通知黑名单变更，收到后从 MySQL 重新加载
+const blacklistChangedChannel = "blacklist:changed"
 
-func IsIPBlacklistedWithContext(ctx context.Context, ip string) (bool, error) {
-	if db == nil {
-		return false, errors.New("store not initialized")
-	}
+var (
+	blacklistTrie     atomic.Pointer[ipTrie]
+	blacklistReloadMu sync.Mutex
+)
+
+// IsIPBlacklistedWithContext 在内存前缀树中匹配 ip；首次调用时从 MySQL 加载，
+// 加载失败时先使用空树放行，由定时同步重试，避免数据库故障时每个请求都重新加载
+func IsIPBlacklistedWithContext(ctx context.Context, ip, scope string) (bool, error) {
 	if ip == "" {
 		return false, nil
 	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false, nil
+	}
+	trie := blacklistTrie.Load()
+	if trie == nil {
+		if err := ReloadBlacklistWithContext(ctx); err != nil {
+			blacklistTrie.CompareAndSwap(nil, newIPTrie())
+			return false, err
+		}
+		trie = blacklistTrie.Load()
+	}
+	_, hit := trie.lookup(addr.WithZone(""), scope, time.Now().UnixMilli())
+	return hit, nil
+}
+
+func IsIPBlacklisted(ip, scope string) (bool, error) {
+	return IsIPBlacklistedWithContext(context.Background(), ip, scope)
+}
+
+// ReloadBlacklistWithContext 从 ip_blacklist 重建前缀树（仅启用且未过期的条目）
+func ReloadBlacklistWithContext(ctx context.Context) error {
+	if db == nil {
+		return errors.New("store not initialized")
+	}
+	blacklistReloadMu.Lock()
+	defer blacklistReloadMu.Unlock()
+
+	ctx = ensureContext(ctx)
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	rows, err := db.QueryContext(ctx, `
+SELECT id, ip, COALESCE(reason, ''), scope, enabled, expires_at, created_at
+FROM ip_blacklist
+WHERE enabled = 1 AND (expires_at = 0 OR expires_at > ?)
+`, time.Now().UnixMilli())
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
 
+	trie := newIPTrie()
+	for rows.Next() {
+		e, err := scanBlacklistEntry(rows)
+		if err != nil {
+			return err
+		}
+		prefix, err := ParseBlacklistTarget(e.IP)
+		if err != nil {
+			log.Warn("skip invalid blacklist entry", zap.Int64("id", e.ID), zap.String("ip", e.IP))
+			continue
+		}
+		trie.insert(prefix, e)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	blacklistTrie.Store(trie)
+	return nil
+}
+
+// StartBlacklistSync 定时从 MySQL 同步黑名单；Redis 可用时同时订阅其他实例的变更通知
+func StartBlacklistSync(cfg *config.Config) {
+	if err := ReloadBlacklistWithContext(context.Background()); err != nil {
+		blacklistTrie.CompareAndSwap(nil, newIPTrie())
+		log.Error("load blacklist failed", zap.Error(err))
+	}
+	interval := 10 * time.Second
+	if cfg != nil && cfg.BlacklistSyncSeconds > 0 {
+		interval = time.Duration(cfg.BlacklistSyncSeconds) * time.Second
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := ReloadBlacklistWithContext(context.Background()); err != nil {
+				log.Error("sync blacklist failed", zap.Error(err))
+			}
+		}
+	}()
+	if client := infra.GetRedis(); client != nil {
+		go func() {
+			sub := client.Subscribe(context.Background(), blacklistChangedChannel)
+			for range sub.Channel() {
+				if err := ReloadBlacklistWithContext(context.Background()); err != nil {
+					log.Error("sync blacklist failed", zap.Error(err))
+				}
+			}
+		}()
+	}
+}
+
+// blacklistChanged 本地立即重建，并通知其他实例
+func blacklistChanged(ctx context.Context) {
+	if err := ReloadBlacklistWithContext(ctx); err != nil {
+		log.Error("reload blacklist failed", zap.Error(err))
+	}
 	if client := infra.GetRedis(); client != nil {
 		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
 		defer cancel()
-		ok, err := client.SIsMember(ctx, blacklistRedisKey, ip).Result()
-		if err == nil {
-			return ok, nil
-		}
+		_ = client.Publish(ctx, blacklistChangedChannel, "1").Err()
 	}
+}
 
+// AddBlacklistIPWithContext 添加或更新 (target, scope) 条目；target 为单个 IP 或 CIDR，expiresAt 为 0 表示永久。
+// 同一目标的不同 scope 各自一条，互不覆盖
+func AddBlacklistIPWithContext(ctx context.Context, target, reason, scope string, expiresAt, nowMs int64) (string, error) {
+	if db == nil {
+		return "", errors.New("store not initialized")
+	}
+	prefix, err := ParseBlacklistTarget(target)
+	if err != nil {
+		return "", err
+	}
+	scope, ok := NormalizeBlacklistScope(scope)
+	if !ok {
+		return "", ErrInvalidBlacklistScope
+	}
+	ip := FormatBlacklistTarget(prefix)
 	ctx = ensureContext(ctx)
 	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
-	var enabled int
-	err := db.QueryRowContext(ctx, `
-SELECT enabled FROM ip_blacklist WHERE ip = ?
-`, ip).Scan(&enabled)
+	_, err = db.ExecContext(ctx, `
+INSERT INTO ip_blacklist (ip, reason, scope, enabled, expires_at, created_at)
+VALUES (?, ?, ?, 1, ?, ?)
+ON DUPLICATE KEY UPDATE enabled = 1, reason = VALUES(reason), expires_at = VALUES(expires_at)
+`, ip, reason, scope, expiresAt, nowMs)
 	if err != nil {
-		return false, nil
-	}
-	if enabled == 1 {
-		_ = addIPToRedis(ip)
-		return true, nil
+		return "", err
 	}
-	return false, nil
+	blacklistChanged(ctx)
+	return ip, nil
 }
 
-func IsIPBlacklisted(ip string) (bool, error) {
-	return IsIPBlacklistedWithContext(context.Background(), ip)
+func AddBlacklistIP(target, reason, scope string, expiresAt, nowMs int64) (string, error) {
+	return AddBlacklistIPWithContext(context.Background(), target, reason, scope, expiresAt, nowMs)
 }
 
-func AddBlacklistIPWithContext(ctx context.Context, ip, reason string, nowMs int64) error {
+// BlacklistReasonAuto 自动封禁条目的 reason，用于区分人工添加的条目
+const BlacklistReasonAuto = "auto"
+
+// AutoBanIPWithContext 写入或延长 (ip, scope) 的自动封禁；ip 已有覆盖该 scope（同 scope 或 all）的
+// 生效中人工条目时不做修改并返回 false。已有生效中的自动条目时过期时间取较晚者
+func AutoBanIPWithContext(ctx context.Context, ip, scope string, expiresAt, nowMs int64) (bool, error) {
 	if db == nil {
-		return errors.New("store not initialized")
+		return false, errors.New("store not initialized")
 	}
+	prefix, err := ParseBlacklistTarget(ip)
+	if err != nil {
+		return false, err
+	}
+	ip = FormatBlacklistTarget(prefix)
 	ctx = ensureContext(ctx)
 	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
-	_, err := db.ExecContext(ctx, `
-INSERT INTO ip_blacklist (ip, reason, enabled, created_at)
-VALUES (?, ?, 1, ?)
-ON DUPLICATE KEY UPDATE enabled = 1, reason = VALUES(reason)
-`, ip, reason, nowMs)
-	if err == nil {
-		_ = addIPToRedis(ip)
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	rows, err := tx.QueryContext(ctx, `
+SELECT id, COALESCE(reason, ''), scope, enabled, expires_at
+FROM ip_blacklist
+WHERE ip = ? AND scope IN (?, ?)
+FOR UPDATE
+`, ip, scope, BlacklistScopeAll)
+	if err != nil {
+		return false, err
+	}
+	var old *BlacklistEntry
+	for rows.Next() {
+		var e BlacklistEntry
+		var enabled int
+		if err := rows.Scan(&e.ID, &e.Reason, &e.Scope, &enabled, &e.ExpiresAt); err != nil {
+			rows.Close()
+			return false, err
+		}
+		e.Enabled = enabled == 1 && (e.ExpiresAt == 0 || e.ExpiresAt > nowMs)
+		if e.Enabled && e.Reason != BlacklistReasonAuto {
+			rows.Close()
+			return false, nil
+		}
+		if e.Scope == scope {
+			old = &e
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return false, err
 	}
-	return err
-}
 
-func AddBlacklistIP(ip, reason string, nowMs int64) error {
-	return AddBlacklistIPWithContext(context.Background(), ip, reason, nowMs)
+	if old == nil {
+		_, err = tx.ExecContext(ctx, `
+INSERT INTO ip_blacklist (ip, reason, scope, enabled, expires_at, created_at)
+VALUES (?, ?, ?, 1, ?, ?)
+`, ip, BlacklistReasonAuto, scope, expiresAt, nowMs)
+	} else {
+		if old.Enabled && old.ExpiresAt > expiresAt {
+			expiresAt = old.ExpiresAt
+		}
+		_, err = tx.ExecContext(ctx, `
+UPDATE ip_blacklist SET reason = ?, enabled = 1, expires_at = ? WHERE id = ?
+`, BlacklistReasonAuto, expiresAt, old.ID)
+	}
+	if err != nil {
+		return false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	blacklistChanged(ctx)
+	return true, nil
 }
 
 func DisableBlacklistIPWithContext(ctx context.Context, id int64) error {
@@ -96,7 +283,7 @@ UPDATE ip_blacklist SET enabled = 0 WHERE id = ?
 `, id); err != nil {
 		return err
 	}
-	_ = removeIPFromRedis(ip)
+	blacklistChanged(ctx)
 	return nil
 }
 
@@ -104,6 +291,31 @@ func DisableBlacklistIP(id int64) error {
 	return DisableBlacklistIPWithContext(context.Background(), id)
 }
 
+// GetBlacklistEntryWithContext 按 id 或 (ip, scope) 读取条目（id 为 0 时按 ip 与 scope）
+func GetBlacklistEntryWithContext(ctx context.Context, id int64, ip, scope string) (BlacklistEntry, error) {
+	if db == nil {
+		return BlacklistEntry{}, errors.New("store not initialized")
+	}
+	ctx = ensureContext(ctx)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	query := `
+SELECT id, ip, COALESCE(reason, ''), scope, enabled, expires_at, created_at
+FROM ip_blacklist
+WHERE `
+	var row *sql.Row
+	if id > 0 {
+		row = db.QueryRowContext(ctx, query+"id = ?", id)
+	} else {
+		row = db.QueryRowContext(ctx, query+"ip = ? AND scope = ?", ip, scope)
+	}
+	e, err := scanBlacklistEntry(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return BlacklistEntry{}, ErrBlacklistNotFound
+	}
+	return e, err
+}
+
 func ListBlacklistWithContext(ctx context.Context, page, pageSize int) ([]BlacklistEntry, int, error) {
 	if db == nil {
 		return nil, 0, errors.New("store not initialized")
@@ -126,7 +338,7 @@ func ListBlacklistWithContext(ctx context.Context, page, pageSize int) ([]Blackl
 
 	offset := (page - 1) * pageSize
 	rows, err := db.QueryContext(ctx, `
-SELECT id, ip, reason, enabled, created_at
+SELECT id, ip, COALESCE(reason, ''), scope, enabled, expires_at, created_at
 FROM ip_blacklist
 ORDER BY id DESC
 LIMIT ? OFFSET ?
@@ -138,12 +350,10 @@ LIMIT ? OFFSET ?
 
 	var items []BlacklistEntry
 	for rows.Next() {
-		var e BlacklistEntry
-		var enabled int
-		if err := rows.Scan(&e.ID, &e.IP, &e.Reason, &enabled, &e.CreatedAt); err != nil {
+		e, err := scanBlacklistEntry(rows)
+		if err != nil {
 			return nil, 0, err
 		}
-		e.Enabled = enabled == 1
 		items = append(items, e)
 	}
 	return items, total, nil
@@ -153,22 +363,12 @@ func ListBlacklist(page, pageSize int) ([]BlacklistEntry, int, error) {
 	return ListBlacklistWithContext(context.Background(), page, pageSize)
 }
 
-func addIPToRedis(ip string) error {
-	client := infra.GetRedis()
-	if client == nil {
-		return nil
-	}
-	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
-	defer cancel()
-	return client.SAdd(ctx, blacklistRedisKey, ip).Err()
-}
-
-func removeIPFromRedis(ip string) error {
-	client := infra.GetRedis()
-	if client == nil {
-		return nil
+func scanBlacklistEntry(row rowScanner) (BlacklistEntry, error) {
+	var e BlacklistEntry
+	var enabled int
+	if err := row.Scan(&e.ID, &e.IP, &e.Reason, &e.Scope, &enabled, &e.ExpiresAt, &e.CreatedAt); err != nil {
+		return BlacklistEntry{}, err
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
-	defer cancel()
-	return client.SRem(ctx, blacklistRedisKey, ip).Err()
+	e.Enabled = enabled == 1
+	return e, nil
 }
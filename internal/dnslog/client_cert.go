@@ -0,0 +1,81 @@
+package dnslog
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// clientCertKeys mTLS 客户端证书到 API Key 的映射
+type clientCertKeys struct {
+	fingerprints map[string]int64 // 叶子证书 DER 的 SHA-256（小写 hex）
+	commonNames  map[string]int64 // 已验证证书的 Subject CN
+}
+
+var clientCertMapping atomic.Pointer[clientCertKeys]
+
+// InitClientCertKeys 解析映射，格式为 sha256:<hex>=<key id> 或 cn:<CommonName>=<key id>
+func InitClientCertKeys(entries []string) error {
+	m, err := parseClientCertKeys(entries)
+	if err != nil {
+		return err
+	}
+	clientCertMapping.Store(m)
+	return nil
+}
+
+func parseClientCertKeys(entries []string) (*clientCertKeys, error) {
+	m := &clientCertKeys{
+		fingerprints: make(map[string]int64),
+		commonNames:  make(map[string]int64),
+	}
+	for _, e := range entries {
+		idx := strings.LastIndex(e, "=")
+		if idx <= 0 {
+			return nil, fmt.Errorf("invalid client cert mapping %q", e)
+		}
+		id, err := strconv.ParseInt(strings.TrimSpace(e[idx+1:]), 10, 64)
+		if err != nil || id <= 0 {
+			return nil, fmt.Errorf("invalid client cert mapping %q: bad key id", e)
+		}
+		selector := strings.TrimSpace(e[:idx])
+		switch {
+		case strings.HasPrefix(strings.ToLower(selector), "sha256:"):
+			fp := strings.ToLower(strings.ReplaceAll(selector[len("sha256:"):], ":", ""))
+			if b, err := hex.DecodeString(fp); err != nil || len(b) != sha256.Size {
+				return nil, fmt.Errorf("invalid client cert mapping %q: bad fingerprint", e)
+			}
+			m.fingerprints[fp] = id
+		case strings.HasPrefix(strings.ToLower(selector), "cn:") && len(selector) > len("cn:"):
+			m.commonNames[selector[len("cn:"):]] = id
+		default:
+			return nil, fmt.Errorf("invalid client cert mapping %q", e)
+		}
+	}
+	return m, nil
+}
+
+// ClientCertKeyID 返回已通过 CA 校验的客户端证书对应的 key id；指纹优先于 CN
+func ClientCertKeyID(state *tls.ConnectionState) (int64, bool) {
+	m := clientCertMapping.Load()
+	if m == nil || state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return 0, false
+	}
+	return m.lookup(state.VerifiedChains[0][0].Raw, state.VerifiedChains[0][0].Subject.CommonName)
+}
+
+func (m *clientCertKeys) lookup(der []byte, cn string) (int64, bool) {
+	sum := sha256.Sum256(der)
+	if id, ok := m.fingerprints[hex.EncodeToString(sum[:])]; ok {
+		return id, true
+	}
+	if cn == "" {
+		return 0, false
+	}
+	id, ok := m.commonNames[cn]
+	return id, ok
+}
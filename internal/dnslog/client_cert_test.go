@@ -0,0 +1,51 @@
+package dnslog
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseClientCertKeys(t *testing.T) {
+	der := []byte("leaf-certificate")
+	sum := sha256.Sum256(der)
+	fp := hex.EncodeToString(sum[:])
+
+	m, err := parseClientCertKeys([]string{"sha256:" + fp + "=7", "cn:ci-runner=9"})
+	require.NoError(t, err)
+
+	id, ok := m.lookup(der, "ci-runner")
+	assert.True(t, ok)
+	assert.Equal(t, int64(7), id, "指纹优先于 CN")
+
+	id, ok = m.lookup([]byte("other"), "ci-runner")
+	assert.True(t, ok)
+	assert.Equal(t, int64(9), id)
+
+	_, ok = m.lookup([]byte("other"), "")
+	assert.False(t, ok)
+
+	for _, bad := range []string{"cn:x", "cn:=1", "sha256:abcd=1", "sha256:" + fp + "=0", "email:a@b=1"} {
+		_, err := parseClientCertKeys([]string{bad})
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestClientCertKeyIDRequiresVerifiedChain(t *testing.T) {
+	require.NoError(t, InitClientCertKeys([]string{"cn:ops=3"}))
+	t.Cleanup(func() { clientCertMapping.Store(nil) })
+
+	leaf := &x509.Certificate{Raw: []byte("leaf"), Subject: pkix.Name{CommonName: "ops"}}
+	_, ok := ClientCertKeyID(&tls.ConnectionState{PeerCertificates: []*x509.Certificate{leaf}})
+	assert.False(t, ok, "未经 CA 校验的证书不能映射")
+
+	id, ok := ClientCertKeyID(&tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{leaf}}})
+	assert.True(t, ok)
+	assert.Equal(t, int64(3), id)
+}
@@ -35,5 +35,9 @@ func AllowDNSQuery(clientIP string) bool {
 	if count == 1 {
 		_ = client.Expire(ctx, key, window).Err()
 	}
-	return int(count) <= limit
+	if int(count) > limit {
+		RecordRateLimitViolation(context.Background(), activeConfig, clientIP, BlacklistScopeDNS, "")
+		return false
+	}
+	return true
 }
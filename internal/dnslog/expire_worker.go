@@ -22,6 +22,9 @@ func StartExpireWorker() {
 			if affected > 0 {
 				log.Info("expired tokens updated", zap.Int64("count", affected))
 			}
+			if _, err := DeleteExpiredSessions(nowMs, 500); err != nil {
+				log.Error("expired sessions cleanup failed", zap.Error(err))
+			}
 		}
 	}()
 }
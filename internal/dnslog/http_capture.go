@@ -0,0 +1,209 @@
+package dnslog
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/genwilliam/dnslog_for_go/config"
+	"github.com/genwilliam/dnslog_for_go/pkg/log"
+
+	"go.uber.org/zap"
+)
+
+// httpCaptureDrainLimit 超出 body 截断长度后最多继续读取（仅计数）的字节数
+const httpCaptureDrainLimit = 10 << 20
+
+var tokenPathPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,127}$`)
+
+var (
+	httpCaptureMu      sync.Mutex
+	httpCaptureServers []*http.Server
+)
+
+// httpInteractionDetails HTTP 回连的结构化信息
+type httpInteractionDetails struct {
+	Method     string              `json:"method"`
+	Host       string              `json:"host"`
+	Path       string              `json:"path"`
+	Proto      string              `json:"proto"`
+	Headers    map[string][]string `json:"headers"`
+	ServerName string              `json:"server_name,omitempty"` // HTTPS 的 SNI
+	Status     int                 `json:"status"`                // 返回给回连方的状态码
+}
+
+// StartHTTPCapture 启动 HTTP 回连捕获监听；tlsCfg 非空且配置了 httpCaptureTLSAddr 时同时启动 HTTPS
+func StartHTTPCapture(cfg *config.Config, tlsCfg *tls.Config) {
+	if cfg == nil || !cfg.HTTPCaptureEnabled {
+		return
+	}
+	if cfg.HTTPCaptureAddr != "" {
+		startCaptureServer(&http.Server{
+			Addr:    cfg.HTTPCaptureAddr,
+			Handler: httpCaptureHandler(cfg.HTTPCaptureAddr, InteractionHTTP, cfg.HTTPCaptureBodyLimit),
+		}, false)
+	}
+	if cfg.HTTPCaptureTLSAddr != "" {
+		if tlsCfg == nil {
+			log.Warn("httpCaptureTLSAddr requires tlsEnabled, https capture disabled")
+			return
+		}
+		// 回连方不会提供客户端证书，不沿用 API 的 mTLS 设置
+		captureTLS := tlsCfg.Clone()
+		captureTLS.ClientAuth = tls.NoClientCert
+		captureTLS.ClientCAs = nil
+		startCaptureServer(&http.Server{
+			Addr:      cfg.HTTPCaptureTLSAddr,
+			Handler:   httpCaptureHandler(cfg.HTTPCaptureTLSAddr, InteractionHTTPS, cfg.HTTPCaptureBodyLimit),
+			TLSConfig: captureTLS,
+		}, true)
+	}
+}
+
+func startCaptureServer(srv *http.Server, useTLS bool) {
+	srv.ReadHeaderTimeout = 10 * time.Second
+	srv.ReadTimeout = 30 * time.Second
+	srv.WriteTimeout = 30 * time.Second
+	srv.IdleTimeout = 60 * time.Second
+	srv.MaxHeaderBytes = 64 << 10
+	httpCaptureMu.Lock()
+	httpCaptureServers = append(httpCaptureServers, srv)
+	httpCaptureMu.Unlock()
+
+	go func() {
+		log.Info("HTTP capture listening", zap.String("addr", srv.Addr), zap.Bool("tls", useTLS))
+		var err error
+		if useTLS {
+			err = srv.ListenAndServeTLS("", "")
+		} else {
+			err = srv.ListenAndServe()
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error("HTTP capture failed", zap.String("addr", srv.Addr), zap.Error(err))
+		}
+	}()
+}
+
+// ShutdownHTTPCapture 关闭回连捕获监听
+func ShutdownHTTPCapture(ctx context.Context) {
+	httpCaptureMu.Lock()
+	servers := httpCaptureServers
+	httpCaptureServers = nil
+	httpCaptureMu.Unlock()
+	for _, srv := range servers {
+		if err := srv.Shutdown(ctx); err != nil {
+			log.Error("HTTP capture shutdown failed", zap.String("addr", srv.Addr), zap.Error(err))
+		}
+	}
+}
+
+func httpCaptureHandler(listener, protocol string, bodyLimit int) http.Handler {
+	if bodyLimit <= 0 {
+		bodyLimit = 8192
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clientIP := r.RemoteAddr
+		if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+			clientIP = host
+		}
+		if blocked, _ := IsIPBlacklistedWithContext(r.Context(), clientIP, BlacklistScopeHTTP); blocked {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		// 调用方断开后仍需完成记录
+		ctx := context.WithoutCancel(r.Context())
+		token, domain, ok := tokenFromHost(r.Host)
+		if !ok {
+			token, domain, ok = tokenFromPath(ctx, r.URL.Path)
+		}
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		body, _ := io.ReadAll(io.LimitReader(r.Body, int64(bodyLimit)))
+		rest, _ := io.Copy(io.Discard, io.LimitReader(r.Body, httpCaptureDrainLimit))
+
+		// token 配置了自定义响应（DTD、跳转等）时按配置返回，否则返回 200 空响应
+		custom, err := GetTokenHTTPResponseWithContext(ctx, token)
+		hasCustom := err == nil
+		if err != nil && err != ErrTokenResponseNotFound {
+			log.Error("load token http response failed", zap.String("token", token), zap.Error(err))
+		}
+
+		details := httpInteractionDetails{
+			Method:  r.Method,
+			Host:    r.Host,
+			Path:    r.URL.RequestURI(),
+			Proto:   r.Proto,
+			Headers: r.Header,
+			Status:  http.StatusOK,
+		}
+		if hasCustom {
+			details.Status = custom.StatusCode
+		}
+		if r.TLS != nil {
+			details.ServerName = r.TLS.ServerName
+		}
+		detailsJSON, _ := json.Marshal(details)
+
+		RecordInteraction(ctx, Interaction{
+			Token:    token,
+			Protocol: protocol,
+			ClientIP: clientIP,
+			Listener: listener,
+			Summary:  r.Method + " " + r.Host + r.URL.RequestURI(),
+			Details:  detailsJSON,
+			Data:     string(body),
+			DataSize: int64(len(body)) + rest,
+		}, domain)
+
+		if hasCustom {
+			writeTokenHTTPResponse(w, custom)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// tokenFromHost 从 <token>.<root>[:port] 形式的 Host 中提取 token，规则与 DNS 记录一致
+func tokenFromHost(hostport string) (string, string, bool) {
+	host := hostport
+	if h, _, err := net.SplitHostPort(hostport); err == nil {
+		host = h
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	root := matchRootDomain(host)
+	if root == "" {
+		return "", "", false
+	}
+	token, _, _ := strings.Cut(strings.TrimSuffix(host, "."+root), ".")
+	if !tokenPathPattern.MatchString(token) {
+		return "", "", false
+	}
+	return token, host, true
+}
+
+// tokenFromPath 从路径首段提取 token（如 /abc123/xxe.dtd）；仅接受已存在的 token，避免扫描流量产生垃圾记录
+func tokenFromPath(ctx context.Context, path string) (string, string, bool) {
+	seg, _, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	seg = strings.ToLower(seg)
+	if !tokenPathPattern.MatchString(seg) {
+		return "", "", false
+	}
+	ts, err := GetTokenStatusWithContext(ctx, seg)
+	if err != nil {
+		return "", "", false
+	}
+	return ts.Token, ts.Domain, true
+}
@@ -0,0 +1,54 @@
+package dnslog
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenFromHost(t *testing.T) {
+	oldRoot, oldRoots := rootDomain, rootDomains
+	rootDomain = "demo.com"
+	rootDomains = []string{"example.org"}
+	t.Cleanup(func() { rootDomain, rootDomains = oldRoot, oldRoots })
+
+	cases := []struct {
+		host   string
+		token  string
+		domain string
+		ok     bool
+	}{
+		{"abc123.demo.com", "abc123", "abc123.demo.com", true},
+		{"ABC123.Demo.com:8081", "abc123", "abc123.demo.com", true},
+		{"x.abc123.demo.com", "x", "x.abc123.demo.com", true},
+		{"abc123.example.org.", "abc123", "abc123.example.org", true},
+		{"demo.com", "", "", false},
+		{"abc123.other.com", "", "", false},
+		{"_acme-challenge.demo.com", "", "", false},
+		{"[::1]:8081", "", "", false},
+	}
+	for _, tc := range cases {
+		token, domain, ok := tokenFromHost(tc.host)
+		assert.Equal(t, tc.ok, ok, tc.host)
+		assert.Equal(t, tc.token, token, tc.host)
+		assert.Equal(t, tc.domain, domain, tc.host)
+	}
+}
+
+func TestDNSInteraction(t *testing.T) {
+	it := dnsInteraction(Record{
+		Domain:    "abc123.demo.com",
+		ClientIP:  "1.2.3.4",
+		Protocol:  "udp",
+		QType:     "A",
+		Timestamp: 1700000000000,
+		Server:    ":53",
+		Token:     "abc123",
+	})
+	assert.Equal(t, InteractionDNS, it.Protocol)
+	assert.Equal(t, "abc123", it.Token)
+	assert.Equal(t, "1.2.3.4", it.ClientIP)
+	assert.Equal(t, "A abc123.demo.com", it.Summary)
+	assert.Equal(t, int64(1700000000000), it.CreatedAt)
+	assert.JSONEq(t, `{"domain":"abc123.demo.com","qtype":"A","transport":"udp"}`, string(it.Details))
+}
@@ -0,0 +1,153 @@
+package dnslog
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/genwilliam/dnslog_for_go/config"
+	"github.com/genwilliam/dnslog_for_go/internal/metrics"
+	"github.com/genwilliam/dnslog_for_go/pkg/log"
+	"github.com/genwilliam/dnslog_for_go/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// recordTokenHit 更新 token 命中状态并按需触发 webhook；DNS 与其他协议的命中共用同一套计数
+func recordTokenHit(token, domain, protocol string) {
+	ttlMs := int64(3600 * 1000)
+	if cfg := config.Get(); cfg != nil && cfg.TokenTTLSeconds > 0 {
+		ttlMs = int64(cfg.TokenTTLSeconds) * 1000
+	}
+	isFirst, err := UpsertTokenHit(token, domain, nowMillis(), ttlMs)
+	if err != nil {
+		log.Error("更新 token 状态失败", zap.Error(err))
+		return
+	}
+	metrics.TokenHitsTotal.Inc()
+	publishTokenHit(token)
+	if err := MaybeEnqueueWebhook(token, isFirst, domain, protocol); err != nil {
+		log.Error("触发 webhook 失败", zap.Error(err))
+	}
+}
+
+// RecordInteraction 保存一次回连并计入 token 命中；domain 用于首次命中时创建 token。返回交互 id，保存失败时为 0
+func RecordInteraction(ctx context.Context, it Interaction, domain string) int64 {
+	if it.CreatedAt == 0 {
+		it.CreatedAt = nowMillis()
+	}
+	it.Summary = strings.ToValidUTF8(truncate(it.Summary, 512), "")
+	id, err := AddInteractionWithContext(ctx, it)
+	if err != nil {
+		log.Error("保存交互记录失败", zap.String("protocol", it.Protocol), zap.Error(err))
+	}
+	metrics.InteractionsTotal.WithLabelValues(it.Protocol).Inc()
+	recordTokenHit(it.Token, domain, it.Protocol)
+
+	log.Info("Captured interaction",
+		zap.String("token", it.Token),
+		zap.String("protocol", it.Protocol),
+		zap.String("summary", it.Summary),
+		zap.String("client_ip", it.ClientIP),
+	)
+	return id
+}
+
+// dnsInteraction 将 DNS 记录转换为交互记录，Protocol 为 dns，传输层协议放在 Details
+func dnsInteraction(rec Record) Interaction {
+	details, _ := json.Marshal(DNSInteractionDetails{
+		Domain:    rec.Domain,
+		QType:     rec.QType,
+		Transport: rec.Protocol,
+	})
+	return Interaction{
+		Token:     rec.Token,
+		Protocol:  InteractionDNS,
+		ClientIP:  rec.ClientIP,
+		Listener:  rec.Server,
+		Summary:   rec.QType + " " + rec.Domain,
+		Details:   details,
+		CreatedAt: rec.Timestamp,
+	}
+}
+
+// maxNameTokenCandidates 从自由文本提取 token 时最多查库的候选数
+const maxNameTokenCandidates = 8
+
+// tokenFromName 从 LDAP DN、RMI 名称等文本中提取 token：优先匹配 <token>.<root>，
+// 其次取各分段中已存在的 token；查库的候选数有上限，避免扫描流量放大查询
+func tokenFromName(ctx context.Context, name string) (string, string, bool) {
+	parts := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' || r == '.')
+	})
+	for _, part := range parts {
+		if token, domain, ok := tokenFromHost(part); ok {
+			return token, domain, true
+		}
+	}
+	seen := make(map[string]bool)
+	for _, part := range parts {
+		for _, label := range strings.Split(part, ".") {
+			if seen[label] || !tokenPathPattern.MatchString(label) {
+				continue
+			}
+			if len(seen) >= maxNameTokenCandidates {
+				return "", "", false
+			}
+			seen[label] = true
+			if ts, err := GetTokenStatusWithContext(ctx, label); err == nil {
+				return ts.Token, ts.Domain, true
+			}
+		}
+	}
+	return "", "", false
+}
+
+// GetTokenInteractionsHandler 查询 token 的交互时间线（DNS 与其他协议合并，游标分页，按时间倒序）
+func GetTokenInteractionsHandler(c *gin.Context) {
+	token := c.Param("token")
+	if token == "" {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest)
+		return
+	}
+	if !authorizeToken(c, token) {
+		return
+	}
+	filter := InteractionFilter{
+		Token:    token,
+		Protocol: strings.ToLower(c.Query("protocol")),
+	}
+	for name, dst := range map[string]*int64{
+		"start":  &filter.Start,
+		"end":    &filter.End,
+		"cursor": &filter.Cursor,
+	} {
+		if v := c.Query(name); v != "" {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || n < 0 {
+				response.Error(c, http.StatusBadRequest, response.CodeBadRequest)
+				return
+			}
+			*dst = n
+		}
+	}
+	cfg := config.Get()
+	filter.Limit = queryPositiveInt(c, "pageSize", cfg.DefaultPageSize)
+	if filter.Limit > cfg.MaxPageSize {
+		filter.Limit = cfg.MaxPageSize
+	}
+
+	items, next, err := ListInteractionsWithContext(c.Request.Context(), filter)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError)
+		return
+	}
+	response.Success(c, gin.H{
+		"items":       items,
+		"size":        filter.Limit,
+		"next_cursor": next,
+	})
+}
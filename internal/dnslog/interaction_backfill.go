@@ -0,0 +1,116 @@
+package dnslog
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/genwilliam/dnslog_for_go/pkg/log"
+	"go.uber.org/zap"
+)
+
+const (
+	dnsBackfillName  = "dns"
+	dnsBackfillBatch = 5000
+	dnsBackfillPause = 200 * time.Millisecond
+)
+
+// prepareDNSBackfill 首次启动时记录回填终点（当前最大 DNS 记录 id），之后的记录由命中路径直接写入时间线；
+// 旧版本已复制过（存在 dns 交互）时直接记为完成。已有进度时只读一行
+func prepareDNSBackfill(conn *sql.DB) error {
+	var lastID, maxID int64
+	err := conn.QueryRow(`SELECT last_id, max_id FROM interaction_backfill WHERE name = ?`, dnsBackfillName).Scan(&lastID, &maxID)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("load dns backfill state: %w", err)
+	}
+	if err := conn.QueryRow(`SELECT COALESCE(MAX(id), 0) FROM dns_records`).Scan(&maxID); err != nil {
+		return fmt.Errorf("load dns backfill state: %w", err)
+	}
+	var migrated int
+	if err := conn.QueryRow(`SELECT COUNT(*) FROM (SELECT id FROM interactions WHERE protocol = 'dns' LIMIT 1) AS t`).Scan(&migrated); err != nil {
+		return fmt.Errorf("load dns backfill state: %w", err)
+	}
+	if migrated > 0 {
+		lastID = maxID
+	}
+	if _, err := conn.Exec(`
+INSERT IGNORE INTO interaction_backfill (name, last_id, max_id, updated_at)
+VALUES (?, ?, ?, ?)`, dnsBackfillName, lastID, maxID, nowMillis()); err != nil {
+		return fmt.Errorf("init dns backfill state: %w", err)
+	}
+	return nil
+}
+
+// nextBackfillRange 下一批的 id 区间 (from, to]；已到终点时返回 false
+func nextBackfillRange(lastID, maxID, batch int64) (int64, int64, bool) {
+	if lastID >= maxID || batch <= 0 {
+		return 0, 0, false
+	}
+	return lastID, min(lastID+batch, maxID), true
+}
+
+// backfillDNSBatch 复制下一批带 token 的 DNS 记录并推进进度；进度行加锁，多实例同时运行时按批串行。返回是否已完成
+func backfillDNSBatch(ctx context.Context, batch int64) (bool, error) {
+	if db == nil {
+		return false, errors.New("store not initialized")
+	}
+	ctx = ensureContext(ctx)
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	var lastID, maxID int64
+	if err := tx.QueryRowContext(ctx, `SELECT last_id, max_id FROM interaction_backfill WHERE name = ? FOR UPDATE`, dnsBackfillName).Scan(&lastID, &maxID); err != nil {
+		return false, err
+	}
+	from, to, ok := nextBackfillRange(lastID, maxID, batch)
+	if !ok {
+		return true, nil
+	}
+	if _, err := tx.ExecContext(ctx, `
+INSERT INTO interactions (token, protocol, client_ip, listener, summary, details, data_size, created_at)
+SELECT r.token, 'dns', COALESCE(r.client_ip, ''), COALESCE(r.server, ''),
+       LEFT(CONCAT(COALESCE(r.qtype, ''), ' ', r.domain), 512),
+       JSON_OBJECT('domain', r.domain, 'qtype', COALESCE(r.qtype, ''), 'transport', COALESCE(r.protocol, '')), 0, r.timestamp
+FROM dns_records r
+WHERE r.id > ? AND r.id <= ? AND r.token NOT IN ('', '(none)')
+ORDER BY r.id`, from, to); err != nil {
+		return false, err
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE interaction_backfill SET last_id = ?, updated_at = ? WHERE name = ?`, to, nowMillis(), dnsBackfillName); err != nil {
+		return false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	return to >= maxID, nil
+}
+
+// StartDNSBackfillWorker 后台按 id 分批把已有 DNS 记录复制到交互时间线；出错时停止，下次启动从已提交的进度继续
+func StartDNSBackfillWorker() {
+	go func() {
+		for {
+			done, err := backfillDNSBatch(context.Background(), dnsBackfillBatch)
+			if err != nil {
+				log.Error("dns interaction backfill failed", zap.Error(err))
+				return
+			}
+			if done {
+				return
+			}
+			time.Sleep(dnsBackfillPause)
+		}
+	}()
+}
@@ -0,0 +1,24 @@
+package dnslog
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextBackfillRange(t *testing.T) {
+	from, to, ok := nextBackfillRange(0, 12000, 5000)
+	assert.True(t, ok)
+	assert.Equal(t, int64(0), from)
+	assert.Equal(t, int64(5000), to)
+
+	from, to, ok = nextBackfillRange(10000, 12000, 5000)
+	assert.True(t, ok)
+	assert.Equal(t, int64(10000), from)
+	assert.Equal(t, int64(12000), to, "最后一批不超过终点")
+
+	_, _, ok = nextBackfillRange(12000, 12000, 5000)
+	assert.False(t, ok)
+	_, _, ok = nextBackfillRange(0, 0, 5000)
+	assert.False(t, ok, "空表无需回填")
+}
@@ -0,0 +1,205 @@
+package dnslog
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var ErrInteractionRawNotFound = errors.New("interaction_raw_not_found")
+
+// 交互协议
+const (
+	InteractionDNS   = "dns"
+	InteractionHTTP  = "http"
+	InteractionHTTPS = "https"
+	InteractionSMTP  = "smtp"
+	InteractionLDAP  = "ldap"
+	InteractionRMI   = "rmi"
+	InteractionTCP   = "tcp"
+	InteractionUDP   = "udp"
+)
+
+// Interaction 一次回连记录（DNS、HTTP、SMTP 等共用）；ClientIP 为来源，Details 为协议相关的结构化信息，
+// Data 为截断后的原始内容
+type Interaction struct {
+	ID        int64           `json:"id"`
+	Token     string          `json:"token"`
+	Protocol  string          `json:"protocol"`
+	ClientIP  string          `json:"client_ip"`
+	Listener  string          `json:"listener"`
+	Summary   string          `json:"summary"`
+	Details   json.RawMessage `json:"details"`
+	Data      string          `json:"data"`
+	DataSize  int64           `json:"data_size"` // 原始内容长度，大于 len(Data) 表示已截断
+	CreatedAt int64           `json:"created_at"`
+	Raw       []byte          `json:"-"` // 完整原始内容（如 .eml），单独存放，按需下载
+	RawID     int64           `json:"-"` // 与另一条交互共用其原始内容（同一封邮件的多个 token），非 0 时不再保存 Raw
+}
+
+// DNSInteractionDetails DNS 查询的结构化信息
+type DNSInteractionDetails struct {
+	Domain    string `json:"domain"`
+	QType     string `json:"qtype"`
+	Transport string `json:"transport"` // udp / tcp
+}
+
+// InteractionFilter 交互记录查询条件；Cursor 为上一页最后一条的 id
+type InteractionFilter struct {
+	Token    string
+	Protocol string
+	Start    int64
+	End      int64
+	Cursor   int64
+	Limit    int
+}
+
+func AddInteractionWithContext(ctx context.Context, it Interaction) (int64, error) {
+	if db == nil {
+		return 0, errors.New("store not initialized")
+	}
+	ctx = ensureContext(ctx)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	res, err := db.ExecContext(ctx, `
+INSERT INTO interactions (token, protocol, client_ip, listener, summary, details, data, data_size, raw_id, created_at)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+`, it.Token, it.Protocol, it.ClientIP, it.Listener, truncate(it.Summary, 512), nullJSON(it.Details), []byte(it.Data), it.DataSize, it.RawID, it.CreatedAt)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil || len(it.Raw) == 0 || it.RawID != 0 {
+		return id, err
+	}
+	if _, err := db.ExecContext(ctx, `
+INSERT INTO interaction_raw (interaction_id, content, created_at) VALUES (?, ?, ?)
+`, id, it.Raw, it.CreatedAt); err != nil {
+		return id, fmt.Errorf("insert interaction raw: %w", err)
+	}
+	return id, nil
+}
+
+// ListInteractionsWithContext 按时间倒序（同一时间按 id）查询；返回的 nextCursor 为 0 表示没有更多数据
+func ListInteractionsWithContext(ctx context.Context, filter InteractionFilter) ([]Interaction, int64, error) {
+	if db == nil {
+		return nil, 0, errors.New("store not initialized")
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = 20
+	}
+	ctx = ensureContext(ctx)
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	where := []string{}
+	args := []interface{}{}
+	if filter.Token != "" {
+		where = append(where, "token = ?")
+		args = append(args, filter.Token)
+	}
+	if filter.Protocol != "" {
+		where = append(where, "protocol = ?")
+		args = append(args, filter.Protocol)
+	}
+	if filter.Start > 0 {
+		where = append(where, "created_at >= ?")
+		args = append(args, filter.Start)
+	}
+	if filter.End > 0 {
+		where = append(where, "created_at <= ?")
+		args = append(args, filter.End)
+	}
+	if filter.Cursor > 0 {
+		// 回填的历史 DNS 记录 id 较大但时间较早，游标按 (created_at, id) 比较
+		var cursorAt int64
+		err := db.QueryRowContext(ctx, "SELECT created_at FROM interactions WHERE id = ?", filter.Cursor).Scan(&cursorAt)
+		switch {
+		case err == nil:
+			where = append(where, "(created_at < ? OR (created_at = ? AND id < ?))")
+			args = append(args, cursorAt, cursorAt, filter.Cursor)
+		case errors.Is(err, sql.ErrNoRows):
+			where = append(where, "id < ?")
+			args = append(args, filter.Cursor)
+		default:
+			return nil, 0, fmt.Errorf("query interaction cursor: %w", err)
+		}
+	}
+	whereSQL := ""
+	if len(where) > 0 {
+		whereSQL = "WHERE " + strings.Join(where, " AND ")
+	}
+
+	rows, err := db.QueryContext(ctx, `
+SELECT id, token, protocol, client_ip, listener, summary, COALESCE(details, ''), COALESCE(data, ''), data_size, created_at
+FROM interactions
+`+whereSQL+`
+ORDER BY created_at DESC, id DESC
+LIMIT ?`, append(args, filter.Limit+1)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("query interactions: %w", err)
+	}
+	defer rows.Close()
+
+	items := make([]Interaction, 0, filter.Limit)
+	for rows.Next() {
+		var it Interaction
+		var details string
+		var data []byte
+		if err := rows.Scan(&it.ID, &it.Token, &it.Protocol, &it.ClientIP, &it.Listener, &it.Summary,
+			&details, &data, &it.DataSize, &it.CreatedAt); err != nil {
+			return nil, 0, fmt.Errorf("scan interaction: %w", err)
+		}
+		if details != "" {
+			it.Details = json.RawMessage(details)
+		}
+		it.Data = string(data)
+		items = append(items, it)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	var next int64
+	if len(items) > filter.Limit {
+		items = items[:filter.Limit]
+		next = items[len(items)-1].ID
+	}
+	return items, next, nil
+}
+
+func ListInteractions(filter InteractionFilter) ([]Interaction, int64, error) {
+	return ListInteractionsWithContext(context.Background(), filter)
+}
+
+// GetInteractionRawWithContext 返回交互所属 token 与完整原始内容（含共用的原始内容）
+func GetInteractionRawWithContext(ctx context.Context, id int64) (string, []byte, error) {
+	if db == nil {
+		return "", nil, errors.New("store not initialized")
+	}
+	ctx = ensureContext(ctx)
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	var token string
+	var content []byte
+	err := db.QueryRowContext(ctx, `
+SELECT i.token, r.content
+FROM interactions i
+JOIN interaction_raw r ON r.interaction_id = IF(i.raw_id > 0, i.raw_id, i.id)
+WHERE i.id = ?
+`, id).Scan(&token, &content)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil, ErrInteractionRawNotFound
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	return token, content, nil
+}
+
+func GetInteractionRaw(id int64) (string, []byte, error) {
+	return GetInteractionRawWithContext(context.Background(), id)
+}
@@ -0,0 +1,127 @@
+package dnslog
+
+import (
+	"errors"
+	"net/netip"
+	"strings"
+)
+
+// 黑名单生效范围
+const (
+	BlacklistScopeAll  = "all"
+	BlacklistScopeHTTP = "http"
+	BlacklistScopeDNS  = "dns"
+)
+
+// 过宽的网段会把管理员自己也挡在外面，限制最短前缀
+const (
+	minBlacklistPrefixV4 = 8
+	minBlacklistPrefixV6 = 16
+)
+
+var (
+	ErrInvalidBlacklistIP    = errors.New("invalid_blacklist_ip")
+	ErrInvalidBlacklistScope = errors.New("invalid_blacklist_scope")
+)
+
+// ParseBlacklistTarget 解析单个 IP 或 CIDR，返回规范化前缀（主机位清零，IPv4 映射地址还原为 IPv4）
+func ParseBlacklistTarget(val string) (netip.Prefix, error) {
+	val = strings.TrimSpace(val)
+	if !strings.Contains(val, "/") {
+		addr, err := netip.ParseAddr(val)
+		if err != nil || addr.Zone() != "" {
+			return netip.Prefix{}, ErrInvalidBlacklistIP
+		}
+		addr = addr.Unmap()
+		return netip.PrefixFrom(addr, addr.BitLen()), nil
+	}
+	prefix, err := netip.ParsePrefix(val)
+	if err != nil {
+		return netip.Prefix{}, ErrInvalidBlacklistIP
+	}
+	addr, bits := prefix.Addr(), prefix.Bits()
+	if addr.Is4In6() {
+		if bits < 96 {
+			return netip.Prefix{}, ErrInvalidBlacklistIP
+		}
+		addr, bits = addr.Unmap(), bits-96
+	}
+	if (addr.Is4() && bits < minBlacklistPrefixV4) || (addr.Is6() && bits < minBlacklistPrefixV6) {
+		return netip.Prefix{}, ErrInvalidBlacklistIP
+	}
+	return netip.PrefixFrom(addr, bits).Masked(), nil
+}
+
+// FormatBlacklistTarget 落库格式：单个地址不带前缀长度，兼容旧数据
+func FormatBlacklistTarget(p netip.Prefix) string {
+	if p.IsSingleIP() {
+		return p.Addr().String()
+	}
+	return p.String()
+}
+
+// NormalizeBlacklistScope 空值视为 all
+func NormalizeBlacklistScope(scope string) (string, bool) {
+	switch s := strings.ToLower(strings.TrimSpace(scope)); s {
+	case "", BlacklistScopeAll:
+		return BlacklistScopeAll, true
+	case BlacklistScopeHTTP, BlacklistScopeDNS:
+		return s, true
+	}
+	return "", false
+}
+
+// ipTrie 按位的前缀树，IPv4 与 IPv6 分开存放；构建后只读，可并发查询
+type ipTrie struct {
+	v4 *trieNode
+	v6 *trieNode
+}
+
+type trieNode struct {
+	child   [2]*trieNode
+	entries []BlacklistEntry // 恰好以该节点结尾的前缀（不同 scope 可能各有一条）
+}
+
+func newIPTrie() *ipTrie {
+	return &ipTrie{v4: &trieNode{}, v6: &trieNode{}}
+}
+
+func (t *ipTrie) insert(p netip.Prefix, e BlacklistEntry) {
+	node := t.v6
+	if p.Addr().Is4() {
+		node = t.v4
+	}
+	raw := p.Addr().AsSlice()
+	for i := 0; i < p.Bits(); i++ {
+		bit := (raw[i/8] >> (7 - uint(i%8))) & 1
+		if node.child[bit] == nil {
+			node.child[bit] = &trieNode{}
+		}
+		node = node.child[bit]
+	}
+	node.entries = append(node.entries, e)
+}
+
+// lookup 沿地址路径查找第一条覆盖 scope 且未过期的条目
+func (t *ipTrie) lookup(addr netip.Addr, scope string, nowMs int64) (BlacklistEntry, bool) {
+	addr = addr.Unmap()
+	node := t.v6
+	if addr.Is4() {
+		node = t.v4
+	}
+	raw := addr.AsSlice()
+	for i := 0; ; i++ {
+		for _, e := range node.entries {
+			if e.matches(scope, nowMs) {
+				return e, true
+			}
+		}
+		if i == len(raw)*8 {
+			return BlacklistEntry{}, false
+		}
+		bit := (raw[i/8] >> (7 - uint(i%8))) & 1
+		if node = node.child[bit]; node == nil {
+			return BlacklistEntry{}, false
+		}
+	}
+}
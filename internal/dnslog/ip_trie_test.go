@@ -0,0 +1,86 @@
+package dnslog
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBlacklistTarget(t *testing.T) {
+	cases := map[string]string{
+		"1.2.3.4":             "1.2.3.4",
+		" 10.1.2.3/16 ":       "10.1.0.0/16",
+		"::ffff:1.2.3.4":      "1.2.3.4",
+		"::ffff:10.0.0.0/104": "10.0.0.0/8",
+		"2001:db8::1/32":      "2001:db8::/32",
+	}
+	for in, want := range cases {
+		p, err := ParseBlacklistTarget(in)
+		require.NoError(t, err, in)
+		assert.Equal(t, want, FormatBlacklistTarget(p), in)
+	}
+
+	for _, in := range []string{"", "abc", "1.2.3.4/33", "0.0.0.0/0", "10.0.0.0/7", "::/8", "fe80::1%eth0"} {
+		_, err := ParseBlacklistTarget(in)
+		assert.ErrorIs(t, err, ErrInvalidBlacklistIP, in)
+	}
+}
+
+func TestIPTrieLookup(t *testing.T) {
+	const now = int64(1_000_000)
+	trie := newIPTrie()
+	add := func(target, scope string, expiresAt int64) {
+		p, err := ParseBlacklistTarget(target)
+		require.NoError(t, err)
+		trie.insert(p, BlacklistEntry{IP: FormatBlacklistTarget(p), Scope: scope, ExpiresAt: expiresAt})
+	}
+	add("10.0.0.0/8", BlacklistScopeDNS, 0)
+	add("10.1.0.0/16", BlacklistScopeHTTP, now+1)
+	add("192.168.1.7", BlacklistScopeAll, 0)
+	add("2001:db8::/32", BlacklistScopeAll, now-1)
+	add("2001:db8:1::/48", BlacklistScopeHTTP, 0)
+
+	hit := func(ip, scope string) bool {
+		_, ok := trie.lookup(netip.MustParseAddr(ip), scope, now)
+		return ok
+	}
+	assert.True(t, hit("10.9.9.9", BlacklistScopeDNS))
+	assert.False(t, hit("10.9.9.9", BlacklistScopeHTTP))
+	assert.True(t, hit("10.1.2.3", BlacklistScopeHTTP), "more specific prefix with another scope")
+	assert.True(t, hit("192.168.1.7", BlacklistScopeHTTP))
+	assert.True(t, hit("::ffff:192.168.1.7", BlacklistScopeDNS), "4in6 address matches IPv4 entry")
+	assert.False(t, hit("192.168.1.8", BlacklistScopeHTTP))
+	assert.False(t, hit("11.0.0.1", BlacklistScopeDNS))
+
+	assert.False(t, hit("2001:db8:2::1", BlacklistScopeDNS), "expired entry is ignored")
+	assert.True(t, hit("2001:db8:1::1", BlacklistScopeHTTP))
+	assert.False(t, hit("2001:db8:1::1", BlacklistScopeDNS))
+
+	_, ok := trie.lookup(netip.MustParseAddr("10.1.2.3"), BlacklistScopeHTTP, now+1)
+	assert.False(t, ok, "expires exactly at expires_at")
+}
+
+func TestNormalizeBlacklistScope(t *testing.T) {
+	s, ok := NormalizeBlacklistScope("")
+	assert.True(t, ok)
+	assert.Equal(t, BlacklistScopeAll, s)
+	s, ok = NormalizeBlacklistScope(" DNS ")
+	assert.True(t, ok)
+	assert.Equal(t, BlacklistScopeDNS, s)
+	_, ok = NormalizeBlacklistScope("smtp")
+	assert.False(t, ok)
+}
+
+func TestIsIPBlacklistedLoadFailureUsesEmptyTrie(t *testing.T) {
+	prev := blacklistTrie.Swap(nil)
+	defer blacklistTrie.Store(prev)
+
+	// 未初始化存储时加载失败，之后的查询直接使用空树而不是重复加载
+	_, err := IsIPBlacklisted("1.2.3.4", BlacklistScopeHTTP)
+	assert.Error(t, err)
+	blocked, err := IsIPBlacklisted("1.2.3.4", BlacklistScopeHTTP)
+	assert.NoError(t, err)
+	assert.False(t, blocked)
+}
@@ -0,0 +1,72 @@
+package dnslog
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/genwilliam/dnslog_for_go/config"
+	"github.com/genwilliam/dnslog_for_go/pkg/ldapd"
+)
+
+// ldapInteractionDetails LDAP 回连的结构化信息
+type ldapInteractionDetails struct {
+	Op         string   `json:"op"`
+	MessageID  int64    `json:"message_id"`
+	Version    int64    `json:"version,omitempty"`
+	DN         string   `json:"dn"`
+	Scope      int64    `json:"scope,omitempty"`
+	Filter     string   `json:"filter,omitempty"`
+	Attributes []string `json:"attributes,omitempty"`
+	Referral   string   `json:"referral,omitempty"`
+}
+
+// StartLDAPCapture 启动 LDAP 捕获监听（JNDI 注入回连），token 取自 bind/search 的 DN
+func StartLDAPCapture(cfg *config.Config) {
+	if cfg == nil || !cfg.LDAPCaptureEnabled || cfg.LDAPCaptureAddr == "" {
+		return
+	}
+	srv := &ldapd.Server{Handler: ldapCaptureHandler(cfg.LDAPCaptureAddr, cfg.LDAPCaptureReferral)}
+	startTCPCapture(InteractionLDAP, cfg.LDAPCaptureAddr, srv.ServeConn)
+}
+
+// ldapCaptureHandler 记录带 token 的请求；配置了 referral 时对其搜索返回该地址（{token} 替换为 token）
+func ldapCaptureHandler(listener, referral string) ldapd.Handler {
+	return func(req *ldapd.Request) []string {
+		ctx := context.Background()
+		token, domain, ok := tokenFromName(ctx, req.DN)
+		if !ok {
+			return nil
+		}
+		var referrals []string
+		if req.Op == ldapd.OpSearch && referral != "" {
+			referrals = []string{strings.ReplaceAll(referral, "{token}", token)}
+		}
+		details := ldapInteractionDetails{
+			Op:         req.Op,
+			MessageID:  req.MessageID,
+			Version:    req.Version,
+			DN:         req.DN,
+			Scope:      req.Scope,
+			Filter:     req.Filter,
+			Attributes: req.Attributes,
+		}
+		if len(referrals) > 0 {
+			details.Referral = referrals[0]
+		}
+		detailsJSON, _ := json.Marshal(details)
+		summary := strings.ToUpper(req.Op) + " " + req.DN
+		if req.Filter != "" {
+			summary += " " + req.Filter
+		}
+		RecordInteraction(ctx, Interaction{
+			Token:    token,
+			Protocol: InteractionLDAP,
+			ClientIP: parseClientIP(req.RemoteAddr),
+			Listener: listener,
+			Summary:  summary,
+			Details:  detailsJSON,
+		}, domain)
+		return referrals
+	}
+}
@@ -0,0 +1,233 @@
+package dnslog
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/genwilliam/dnslog_for_go/config"
+	"github.com/genwilliam/dnslog_for_go/pkg/log"
+	"github.com/genwilliam/dnslog_for_go/pkg/oidc"
+	"github.com/genwilliam/dnslog_for_go/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// oidcStateCookie 保存授权请求的 state、nonce 与 PKCE verifier；
+// 回调是 IdP 发起的跨站跳转，因此使用 SameSite=Lax
+const (
+	oidcStateCookie = "dnslog_oidc"
+	oidcStateTTL    = 10 * time.Minute
+)
+
+var (
+	oidcMu       sync.Mutex
+	oidcProvider *oidc.Provider
+	oidcRoleMap  map[string][]string
+)
+
+// InitOIDC 校验组映射并尝试 discovery；IdP 暂不可用时在首次登录时重试
+func InitOIDC(cfg *config.Config) error {
+	roleMap, err := ParseGroupRoles(cfg.OIDCGroupRoles)
+	if err != nil {
+		return err
+	}
+	if cfg.OIDCDefaultRole != "" {
+		if _, err := NormalizeRoles([]string{cfg.OIDCDefaultRole}); err != nil {
+			return fmt.Errorf("oidcDefaultRole: %w", err)
+		}
+	}
+	if cfg.OIDCTenant != "" && !ValidTenant(cfg.OIDCTenant) {
+		return ErrInvalidTenant
+	}
+	oidcMu.Lock()
+	oidcRoleMap = roleMap
+	oidcMu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := getOIDCProvider(ctx); err != nil {
+		log.Warn("oidc discovery failed, will retry on login", zap.Error(err))
+	}
+	return nil
+}
+
+// ParseGroupRoles 解析 group=role 映射；同一组可映射多个角色
+func ParseGroupRoles(entries []string) (map[string][]string, error) {
+	out := make(map[string][]string, len(entries))
+	for _, e := range entries {
+		idx := strings.LastIndex(e, "=")
+		if idx <= 0 || idx == len(e)-1 {
+			return nil, fmt.Errorf("invalid oidc group mapping %q", e)
+		}
+		group := strings.TrimSpace(e[:idx])
+		roles, err := NormalizeRoles([]string{e[idx+1:]})
+		if err != nil {
+			return nil, fmt.Errorf("invalid oidc group mapping %q: %w", e, err)
+		}
+		out[group] = append(out[group], roles...)
+	}
+	return out, nil
+}
+
+// MapGroupsToRoles 根据组声明计算角色；无匹配时使用 defaultRole（为空则返回空）
+func MapGroupsToRoles(groups []string, roleMap map[string][]string, defaultRole string) []string {
+	var roles []string
+	for _, g := range groups {
+		roles = append(roles, roleMap[g]...)
+	}
+	if len(roles) == 0 && defaultRole != "" {
+		roles = []string{defaultRole}
+	}
+	out, _ := NormalizeRoles(roles)
+	return out
+}
+
+func getOIDCProvider(ctx context.Context) (*oidc.Provider, error) {
+	oidcMu.Lock()
+	defer oidcMu.Unlock()
+	if oidcProvider != nil {
+		return oidcProvider, nil
+	}
+	cfg := config.Get()
+	if cfg == nil || !cfg.OIDCEnabled {
+		return nil, errors.New("oidc disabled")
+	}
+	p, err := oidc.NewProvider(ctx, oidc.Config{
+		Issuer:       cfg.OIDCIssuer,
+		ClientID:     cfg.OIDCClientID,
+		ClientSecret: cfg.OIDCClientSecret,
+		RedirectURL:  cfg.OIDCRedirectURL,
+		Scopes:       cfg.OIDCScopes,
+	})
+	if err != nil {
+		return nil, err
+	}
+	oidcProvider = p
+	return p, nil
+}
+
+// OIDCLoginHandler 跳转到 IdP 授权页（授权码 + PKCE）
+func OIDCLoginHandler(c *gin.Context) {
+	p, err := getOIDCProvider(c.Request.Context())
+	if err != nil {
+		log.Error("oidc provider unavailable", zap.Error(err))
+		response.Error(c, http.StatusServiceUnavailable, response.CodeOIDCUnavailable)
+		return
+	}
+	state, err1 := oidc.RandomString()
+	nonce, err2 := oidc.RandomString()
+	verifier, err3 := oidc.RandomString()
+	if err1 != nil || err2 != nil || err3 != nil {
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError)
+		return
+	}
+	setOIDCStateCookie(c, state+"."+nonce+"."+verifier, int(oidcStateTTL/time.Second))
+	c.Redirect(http.StatusFound, p.AuthCodeURL(state, nonce, verifier))
+}
+
+// OIDCCallbackHandler 校验 state、换取并验证 ID token，按组映射角色后建立会话
+func OIDCCallbackHandler(c *gin.Context) {
+	cookie, _ := c.Cookie(oidcStateCookie)
+	setOIDCStateCookie(c, "", -1)
+	parts := strings.Split(cookie, ".")
+	state := c.Query("state")
+	if len(parts) != 3 || state == "" || subtle.ConstantTimeCompare([]byte(parts[0]), []byte(state)) != 1 {
+		response.Error(c, http.StatusBadRequest, response.CodeOIDCLoginFailed)
+		return
+	}
+	if c.Query("error") != "" || c.Query("code") == "" {
+		response.Error(c, http.StatusUnauthorized, response.CodeOIDCLoginFailed)
+		return
+	}
+
+	p, err := getOIDCProvider(c.Request.Context())
+	if err != nil {
+		response.Error(c, http.StatusServiceUnavailable, response.CodeOIDCUnavailable)
+		return
+	}
+	rawIDToken, err := p.Exchange(c.Request.Context(), c.Query("code"), parts[2])
+	if err != nil {
+		log.Warn("oidc code exchange failed", zap.Error(err))
+		response.Error(c, http.StatusUnauthorized, response.CodeOIDCLoginFailed)
+		return
+	}
+	now := time.Now()
+	claims, err := p.Verify(c.Request.Context(), rawIDToken, parts[1], now)
+	if err != nil {
+		log.Warn("oidc id token rejected", zap.Error(err))
+		response.Error(c, http.StatusUnauthorized, response.CodeOIDCLoginFailed)
+		return
+	}
+
+	cfg := config.Get()
+	oidcMu.Lock()
+	roleMap := oidcRoleMap
+	oidcMu.Unlock()
+	roles := MapGroupsToRoles(claims.Groups(cfg.OIDCGroupsClaim), roleMap, cfg.OIDCDefaultRole)
+	if len(roles) == 0 {
+		response.Error(c, http.StatusForbidden, response.CodeOIDCNoRole)
+		return
+	}
+	tenant := cfg.OIDCTenant
+	if tenant == "" {
+		tenant = DefaultTenant
+	}
+	user, err := UpsertOIDCUserWithContext(c.Request.Context(), claims.Subject, oidcUsername(claims), roles, tenant, now.UnixMilli())
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError)
+		return
+	}
+	if !user.Enabled {
+		response.Error(c, http.StatusUnauthorized, response.CodeInvalidCredentials)
+		return
+	}
+	if _, ok := startSession(c, user, now); !ok {
+		return
+	}
+	target := cfg.OIDCPostLoginURL
+	if target == "" {
+		target = "/dnslog"
+	}
+	c.Redirect(http.StatusFound, target)
+}
+
+func setOIDCStateCookie(c *gin.Context, val string, maxAge int) {
+	secure := c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+	if cfg := config.Get(); cfg != nil && cfg.SessionCookieSecure {
+		secure = true
+	}
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    val,
+		Path:     "/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// oidcUsername 依次取 preferred_username、email、sub，替换不合法字符
+func oidcUsername(claims oidc.Claims) string {
+	name := claims.PreferredUsername
+	if name == "" {
+		name = claims.Email
+	}
+	if name == "" {
+		name = claims.Subject
+	}
+	b := []byte(truncate(name, 55))
+	for i, ch := range b {
+		if !validUsername(string(ch)) {
+			b[i] = '_'
+		}
+	}
+	return string(b)
+}
@@ -0,0 +1,98 @@
+package dnslog
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/genwilliam/dnslog_for_go/pkg/oidc"
+	"github.com/genwilliam/dnslog_for_go/pkg/oidc/oidctest"
+	"github.com/genwilliam/dnslog_for_go/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMapGroupsToRoles(t *testing.T) {
+	roleMap, err := ParseGroupRoles([]string{"dnslog-admins=admin", "oncall=operator", "oncall=viewer"})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{RoleAdmin}, MapGroupsToRoles([]string{"staff", "dnslog-admins"}, roleMap, ""))
+	assert.Equal(t, []string{RoleOperator, RoleViewer}, MapGroupsToRoles([]string{"oncall"}, roleMap, ""))
+	assert.Empty(t, MapGroupsToRoles([]string{"staff"}, roleMap, ""))
+	assert.Equal(t, []string{RoleViewer}, MapGroupsToRoles(nil, roleMap, RoleViewer))
+
+	_, err = ParseGroupRoles([]string{"ops=root"})
+	assert.Error(t, err)
+	_, err = ParseGroupRoles([]string{"ops"})
+	assert.Error(t, err)
+}
+
+func TestOIDCUsername(t *testing.T) {
+	assert.Equal(t, "alice", oidcUsername(oidc.Claims{Subject: "s", PreferredUsername: "alice"}))
+	assert.Equal(t, "bob@corp.example", oidcUsername(oidc.Claims{Subject: "s", Email: "bob@corp.example"}))
+	assert.Equal(t, "auth0_123", oidcUsername(oidc.Claims{Subject: "auth0|123"}))
+}
+
+func TestOIDCCallbackFlow(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	iss := oidctest.NewIssuer("dnslog")
+	defer iss.Close()
+	iss.SetClaims(map[string]interface{}{"sub": "u-1", "groups": []string{"unmapped"}})
+
+	p, err := oidc.NewProvider(context.Background(), oidc.Config{
+		Issuer:      iss.URL,
+		ClientID:    "dnslog",
+		RedirectURL: "http://dnslog.test/auth/oidc/callback",
+	})
+	require.NoError(t, err)
+	oidcMu.Lock()
+	oidcProvider, oidcRoleMap = p, map[string][]string{"dnslog-admins": {RoleAdmin}}
+	oidcMu.Unlock()
+	defer func() {
+		oidcMu.Lock()
+		oidcProvider, oidcRoleMap = nil, nil
+		oidcMu.Unlock()
+	}()
+
+	r := gin.New()
+	r.GET("/auth/oidc/login", OIDCLoginHandler)
+	r.GET("/auth/oidc/callback", OIDCCallbackHandler)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/auth/oidc/login", nil))
+	require.Equal(t, http.StatusFound, w.Code)
+	cookies := w.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.True(t, cookies[0].HttpOnly)
+
+	// IdP 直接批准并带 code 跳回
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(w.Header().Get("Location"))
+	require.NoError(t, err)
+	resp.Body.Close()
+	callback, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+
+	forged := httptest.NewRequest(http.MethodGet, "/auth/oidc/callback?code=x&state=forged", nil)
+	forged.AddCookie(cookies[0])
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, forged)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// 令牌校验通过，但用户组未映射到任何角色
+	req := httptest.NewRequest(http.MethodGet, "/auth/oidc/callback?"+callback.RawQuery, nil)
+	req.AddCookie(cookies[0])
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	var body struct {
+		Message string `json:"message"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, response.CodeOIDCNoRole, body.Message)
+}
@@ -0,0 +1,147 @@
+package dnslog
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/genwilliam/dnslog_for_go/config"
+	"github.com/genwilliam/dnslog_for_go/internal/infra"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	QueueBackendAuto   = "auto"
+	QueueBackendRedis  = "redis"
+	QueueBackendMemory = "memory"
+)
+
+var (
+	ErrQueueFull           = errors.New("queue_full")
+	ErrQueueNotInitialized = errors.New("queue not initialized")
+)
+
+// Queue 异步任务队列：Push 入队，Pop 阻塞至多 timeout，超时返回 ok=false
+type Queue interface {
+	Push(ctx context.Context, val string) error
+	Pop(ctx context.Context, timeout time.Duration) (string, bool, error)
+	Backend() string
+}
+
+// RedisQueue 基于 Redis list（LPUSH/BRPOP），多实例共享
+type RedisQueue struct {
+	client *redis.Client
+	key    string
+}
+
+func NewRedisQueue(client *redis.Client, key string) *RedisQueue {
+	return &RedisQueue{client: client, key: key}
+}
+
+func (q *RedisQueue) Push(ctx context.Context, val string) error {
+	return q.client.LPush(ctx, q.key, val).Err()
+}
+
+func (q *RedisQueue) Pop(ctx context.Context, timeout time.Duration) (string, bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout+2*time.Second)
+	defer cancel()
+	res, err := q.client.BRPop(ctx, timeout, q.key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	if len(res) < 2 {
+		return "", false, nil
+	}
+	return res[1], true, nil
+}
+
+func (q *RedisQueue) Backend() string {
+	return QueueBackendRedis
+}
+
+// MemoryQueue 进程内有界队列；进程重启会丢失未消费的元素，
+// 依赖 MySQL 的任务（如 webhook）由到期扫描补回
+type MemoryQueue struct {
+	ch chan string
+}
+
+func NewMemoryQueue(size int) *MemoryQueue {
+	if size <= 0 {
+		size = 10000
+	}
+	return &MemoryQueue{ch: make(chan string, size)}
+}
+
+// Push 队列满时不阻塞调用方，返回 ErrQueueFull
+func (q *MemoryQueue) Push(ctx context.Context, val string) error {
+	select {
+	case q.ch <- val:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+		return ErrQueueFull
+	}
+}
+
+func (q *MemoryQueue) Pop(ctx context.Context, timeout time.Duration) (string, bool, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case val := <-q.ch:
+		return val, true, nil
+	case <-timer.C:
+		return "", false, nil
+	case <-ctx.Done():
+		return "", false, ctx.Err()
+	}
+}
+
+func (q *MemoryQueue) Backend() string {
+	return QueueBackendMemory
+}
+
+var (
+	queueMu sync.Mutex
+	queues  = make(map[string]Queue)
+)
+
+// getQueue 按配置返回（并缓存）指定 key 的队列：
+// redis 强制使用 Redis；memory 仅用进程内队列；auto 在 Redis 可用时用 Redis，否则回退进程内队列
+func getQueue(key string) (Queue, error) {
+	queueMu.Lock()
+	defer queueMu.Unlock()
+	if q, ok := queues[key]; ok {
+		return q, nil
+	}
+	cfg := config.Get()
+	backend := QueueBackendAuto
+	size := 0
+	if cfg != nil {
+		backend = cfg.QueueBackend
+		size = cfg.QueueMemorySize
+	}
+	client := infra.GetRedis()
+	var q Queue
+	switch backend {
+	case QueueBackendMemory:
+		q = NewMemoryQueue(size)
+	case QueueBackendRedis:
+		if client == nil {
+			return nil, ErrQueueNotInitialized
+		}
+		q = NewRedisQueue(client, key)
+	default:
+		if client != nil {
+			q = NewRedisQueue(client, key)
+		} else {
+			q = NewMemoryQueue(size)
+		}
+	}
+	queues[key] = q
+	return q, nil
+}
@@ -0,0 +1,27 @@
+package dnslog
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryQueue(t *testing.T) {
+	q := NewMemoryQueue(2)
+	ctx := context.Background()
+	assert.NoError(t, q.Push(ctx, "1"))
+	assert.NoError(t, q.Push(ctx, "2"))
+	assert.ErrorIs(t, q.Push(ctx, "3"), ErrQueueFull)
+
+	val, ok, err := q.Pop(ctx, time.Second)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "1", val)
+	_, _, _ = q.Pop(ctx, time.Second)
+
+	_, ok, err = q.Pop(ctx, 10*time.Millisecond)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
@@ -0,0 +1,338 @@
+package dnslog
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/genwilliam/dnslog_for_go/config"
+	"github.com/genwilliam/dnslog_for_go/pkg/log"
+
+	"go.uber.org/zap"
+)
+
+const (
+	rawCaptureMaxDuration = time.Minute // 单个 TCP 连接最长保持时间
+	rawCaptureMaxBytes    = 1 << 20     // 单个 TCP 连接最多读取（计数）的字节数
+	rawCaptureQueueSize   = 1024        // 待落库的连接/数据报上限，队列满时丢弃
+	rawCaptureWorkers     = 4
+	rawCaptureMaxTracked  = 100000 // 限流窗口内最多跟踪的来源 IP 数，超出后新 IP 一律拒绝
+)
+
+// rawCaptureSpec 一个原始端口监听：<tcp|udp>/<addr>[=<banner>]
+type rawCaptureSpec struct {
+	network string
+	addr    string
+	banner  []byte
+}
+
+// rawCaptureOptions 原始端口监听的公共参数
+type rawCaptureOptions struct {
+	readBytes int
+	idle      time.Duration
+	correlate time.Duration
+}
+
+// rawInteractionDetails 原始端口回连的结构化信息
+type rawInteractionDetails struct {
+	SourcePort  string `json:"source_port"`
+	DurationMs  int64  `json:"duration_ms"`
+	Attribution string `json:"attribution"` // payload：载荷中包含 token；source_ip：按来源 IP 关联的 DNS 命中
+	BannerSent  bool   `json:"banner_sent"`
+	DataBase64  string `json:"data_base64,omitempty"` // 载荷不是合法 UTF-8 时提供
+}
+
+// rawCaptureJob 一次待归属、落库的连接或数据报
+type rawCaptureJob struct {
+	protocol   string
+	listener   string
+	remote     net.Addr
+	data       []byte
+	total      int64
+	dur        time.Duration
+	bannerSent bool
+	correlate  time.Duration
+}
+
+// ipWindowLimiter 按来源 IP 的固定窗口计数（与 DNS 限流同样的计数方式，但只在进程内，不为每个包访问 Redis）
+type ipWindowLimiter struct {
+	mu     sync.Mutex
+	limit  int
+	window time.Duration
+	start  time.Time
+	counts map[string]int
+}
+
+var (
+	udpCaptureMu    sync.Mutex
+	udpCaptureConns []net.PacketConn
+	udpCaptureDone  bool
+
+	rawCaptureQueue   chan rawCaptureJob
+	rawCaptureLimiter *ipWindowLimiter
+)
+
+func newIPWindowLimiter(limit int, window time.Duration) *ipWindowLimiter {
+	if limit <= 0 {
+		return nil
+	}
+	return &ipWindowLimiter{limit: limit, window: window, counts: make(map[string]int)}
+}
+
+// allow 计数并判断 ip 在当前窗口内是否仍在限额内；nil 表示不限制
+func (l *ipWindowLimiter) allow(ip string, now time.Time) bool {
+	if l == nil {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if now.Sub(l.start) >= l.window {
+		l.start = now
+		clear(l.counts)
+	}
+	n, ok := l.counts[ip]
+	if !ok && len(l.counts) >= rawCaptureMaxTracked {
+		return false
+	}
+	if n >= l.limit {
+		return false
+	}
+	l.counts[ip] = n + 1
+	return true
+}
+
+// enqueueRawCapture 交给后台 worker 归属与落库，不阻塞读取；队列满或未启动时丢弃
+func enqueueRawCapture(job rawCaptureJob) bool {
+	select {
+	case rawCaptureQueue <- job:
+		return true
+	default:
+		log.Debug("raw capture queue full, dropped", zap.String("protocol", job.protocol), zap.String("listener", job.listener))
+		return false
+	}
+}
+
+// parseRawCaptureSpec 解析监听配置；端口可省略冒号，banner 支持 \r\n 等转义
+func parseRawCaptureSpec(spec string) (rawCaptureSpec, error) {
+	head, banner, hasBanner := strings.Cut(spec, "=")
+	network, addr, ok := strings.Cut(strings.TrimSpace(head), "/")
+	network = strings.ToLower(network)
+	if !ok || (network != "tcp" && network != "udp") {
+		return rawCaptureSpec{}, fmt.Errorf("invalid raw capture listener %q: want tcp/<port> or udp/<port>", spec)
+	}
+	if !strings.Contains(addr, ":") {
+		addr = ":" + addr
+	}
+	_, port, err := net.SplitHostPort(addr)
+	if n, perr := strconv.Atoi(port); err != nil || perr != nil || n <= 0 || n > 65535 {
+		return rawCaptureSpec{}, fmt.Errorf("invalid raw capture listener %q: bad port", spec)
+	}
+	out := rawCaptureSpec{network: network, addr: addr}
+	if hasBanner {
+		if unquoted, err := strconv.Unquote(`"` + banner + `"`); err == nil {
+			banner = unquoted
+		}
+		out.banner = []byte(banner)
+	}
+	return out, nil
+}
+
+// StartRawCapture 按 rawCaptureListeners 启动原始 TCP/UDP 端口监听，配置有误的项跳过
+func StartRawCapture(cfg *config.Config) {
+	if cfg == nil || len(cfg.RawCaptureListeners) == 0 {
+		return
+	}
+	opts := rawCaptureOptions{
+		readBytes: cfg.RawCaptureReadBytes,
+		idle:      time.Duration(cfg.RawCaptureIdleSeconds) * time.Second,
+		correlate: time.Duration(cfg.RawCaptureCorrelateSeconds) * time.Second,
+	}
+	if opts.readBytes <= 0 {
+		opts.readBytes = 1024
+	}
+	if opts.idle <= 0 {
+		opts.idle = 10 * time.Second
+	}
+	rawCaptureLimiter = newIPWindowLimiter(cfg.RawCaptureRateLimit, time.Minute)
+	rawCaptureQueue = make(chan rawCaptureJob, rawCaptureQueueSize)
+	for i := 0; i < rawCaptureWorkers; i++ {
+		go func() {
+			for job := range rawCaptureQueue {
+				recordRawInteraction(context.Background(), job)
+			}
+		}()
+	}
+	for _, raw := range cfg.RawCaptureListeners {
+		spec, err := parseRawCaptureSpec(raw)
+		if err != nil {
+			log.Error("skip raw capture listener", zap.Error(err))
+			continue
+		}
+		if spec.network == "tcp" {
+			startTCPCapture(InteractionTCP, spec.addr, rawTCPHandler(spec, opts))
+		} else {
+			startUDPCapture(spec, opts)
+		}
+	}
+}
+
+// rawTCPHandler 连接建立后先发送 banner，再读取到对端关闭、空闲超时或达到上限为止
+func rawTCPHandler(spec rawCaptureSpec, opts rawCaptureOptions) func(conn net.Conn) {
+	return func(conn net.Conn) {
+		start := time.Now()
+		if !rawCaptureLimiter.allow(parseClientIP(conn.RemoteAddr()), start) {
+			return
+		}
+		if len(spec.banner) > 0 {
+			_ = conn.SetWriteDeadline(start.Add(opts.idle))
+			_, _ = conn.Write(spec.banner)
+		}
+		data := make([]byte, 0, opts.readBytes)
+		chunk := make([]byte, 4096)
+		var total int64
+		deadline := start.Add(rawCaptureMaxDuration)
+		for total < rawCaptureMaxBytes {
+			readDeadline := time.Now().Add(opts.idle)
+			if readDeadline.After(deadline) {
+				readDeadline = deadline
+			}
+			_ = conn.SetReadDeadline(readDeadline)
+			n, err := conn.Read(chunk)
+			total += int64(n)
+			if room := opts.readBytes - len(data); room > 0 {
+				data = append(data, chunk[:min(n, room)]...)
+			}
+			if err != nil {
+				break
+			}
+		}
+		enqueueRawCapture(rawCaptureJob{
+			protocol:   InteractionTCP,
+			listener:   spec.addr,
+			remote:     conn.RemoteAddr(),
+			data:       data,
+			total:      total,
+			dur:        time.Since(start),
+			bannerSent: len(spec.banner) > 0,
+			correlate:  opts.correlate,
+		})
+	}
+}
+
+// startUDPCapture 每个数据报记为一次交互；banner 回复不超过收到的长度，避免被用于反射放大。
+// 读循环只做黑名单与限流检查，归属与落库交给后台 worker
+func startUDPCapture(spec rawCaptureSpec, opts rawCaptureOptions) {
+	pc, err := net.ListenPacket("udp", spec.addr)
+	if err != nil {
+		log.Error("capture listen failed", zap.String("listener", InteractionUDP), zap.String("addr", spec.addr), zap.Error(err))
+		return
+	}
+	udpCaptureMu.Lock()
+	udpCaptureConns = append(udpCaptureConns, pc)
+	udpCaptureMu.Unlock()
+	log.Info("capture listening", zap.String("listener", InteractionUDP), zap.String("addr", pc.LocalAddr().String()))
+
+	go func() {
+		buf := make([]byte, 65535)
+		for {
+			n, from, err := pc.ReadFrom(buf)
+			if err != nil {
+				udpCaptureMu.Lock()
+				done := udpCaptureDone
+				udpCaptureMu.Unlock()
+				var ne net.Error
+				if !done && errors.As(err, &ne) && ne.Timeout() {
+					continue
+				}
+				if !done {
+					log.Error("capture read failed", zap.String("listener", InteractionUDP), zap.Error(err))
+				}
+				return
+			}
+			clientIP := parseClientIP(from)
+			if blocked, _ := IsIPBlacklisted(clientIP, BlacklistScopeAll); blocked {
+				continue
+			}
+			if !rawCaptureLimiter.allow(clientIP, time.Now()) {
+				continue
+			}
+			sent := false
+			if len(spec.banner) > 0 {
+				_, werr := pc.WriteTo(spec.banner[:min(len(spec.banner), n)], from)
+				sent = werr == nil
+			}
+			data := append([]byte(nil), buf[:min(n, opts.readBytes)]...)
+			// UDP 来源地址可伪造，不按来源 IP 关联 DNS 命中，避免向他人的 token 注入交互
+			enqueueRawCapture(rawCaptureJob{
+				protocol:   InteractionUDP,
+				listener:   spec.addr,
+				remote:     from,
+				data:       data,
+				total:      int64(n),
+				bannerSent: sent,
+			})
+		}
+	}()
+}
+
+// ShutdownUDPCapture 关闭原始 UDP 端口监听
+func ShutdownUDPCapture() {
+	udpCaptureMu.Lock()
+	conns := udpCaptureConns
+	udpCaptureConns = nil
+	udpCaptureDone = true
+	udpCaptureMu.Unlock()
+	for _, pc := range conns {
+		pc.Close()
+	}
+}
+
+// recordRawInteraction 按载荷中的 token 或来源 IP 最近的 DNS 命中归属 token，无法归属时不记录
+func recordRawInteraction(ctx context.Context, job rawCaptureJob) {
+	protocol, listener, remote, data := job.protocol, job.listener, job.remote, job.data
+	clientIP := parseClientIP(remote)
+	attribution := "payload"
+	token, domain, ok := tokenFromName(ctx, string(data))
+	if !ok && job.correlate > 0 {
+		rec, err := LatestTokenRecordByClientIPWithContext(ctx, clientIP, nowMillis()-job.correlate.Milliseconds())
+		if err == nil {
+			token, domain, ok = rec.Token, rec.Domain, true
+			attribution = "source_ip"
+		}
+	}
+	if !ok {
+		log.Debug("raw capture not attributed to any token", zap.String("protocol", protocol), zap.String("listener", listener), zap.String("client_ip", clientIP))
+		return
+	}
+
+	details := rawInteractionDetails{
+		DurationMs:  job.dur.Milliseconds(),
+		Attribution: attribution,
+		BannerSent:  job.bannerSent,
+	}
+	if _, port, err := net.SplitHostPort(remote.String()); err == nil {
+		details.SourcePort = port
+	}
+	if !utf8.Valid(data) {
+		details.DataBase64 = base64.StdEncoding.EncodeToString(data)
+	}
+	detailsJSON, _ := json.Marshal(details)
+	RecordInteraction(ctx, Interaction{
+		Token:    token,
+		Protocol: protocol,
+		ClientIP: clientIP,
+		Listener: listener,
+		Summary:  fmt.Sprintf("%s %s from %s, %d bytes", strings.ToUpper(protocol), listener, remote.String(), job.total),
+		Details:  detailsJSON,
+		Data:     string(data),
+		DataSize: job.total,
+	}, domain)
+}
@@ -0,0 +1,95 @@
+package dnslog
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRawCaptureSpec(t *testing.T) {
+	spec, err := parseRawCaptureSpec("tcp/4444")
+	require.NoError(t, err)
+	assert.Equal(t, rawCaptureSpec{network: "tcp", addr: ":4444"}, spec)
+
+	spec, err = parseRawCaptureSpec(`TCP/127.0.0.1:2222=SSH-2.0-OpenSSH_8.9\r\n`)
+	require.NoError(t, err)
+	assert.Equal(t, "tcp", spec.network)
+	assert.Equal(t, "127.0.0.1:2222", spec.addr)
+	assert.Equal(t, "SSH-2.0-OpenSSH_8.9\r\n", string(spec.banner))
+
+	spec, err = parseRawCaptureSpec("udp/:5000=a=b")
+	require.NoError(t, err)
+	assert.Equal(t, "a=b", string(spec.banner))
+
+	for _, bad := range []string{"4444", "sctp/4444", "tcp/", "tcp/0", "tcp/70000", "udp/abc"} {
+		_, err := parseRawCaptureSpec(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestRawTCPHandlerSendsBanner(t *testing.T) {
+	queue := make(chan rawCaptureJob, 1)
+	prev := rawCaptureQueue
+	rawCaptureQueue = queue
+	defer func() { rawCaptureQueue = prev }()
+
+	client, server := net.Pipe()
+	done := make(chan struct{})
+	go func() {
+		spec := rawCaptureSpec{network: "tcp", addr: ":4444", banner: []byte("hello\n")}
+		rawTCPHandler(spec, rawCaptureOptions{readBytes: 16, idle: 100 * time.Millisecond})(server)
+		server.Close()
+		close(done)
+	}()
+
+	banner := make([]byte, 6)
+	_, err := io.ReadFull(client, banner)
+	require.NoError(t, err)
+	assert.Equal(t, "hello\n", string(banner))
+	_, err = client.Write([]byte("id; uname -a"))
+	require.NoError(t, err)
+
+	// 空闲超时后由服务端结束连接
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("handler did not return after idle timeout")
+	}
+	client.Close()
+
+	// 记录交给后台 worker，处理函数本身不落库
+	job := <-queue
+	assert.Equal(t, InteractionTCP, job.protocol)
+	assert.Equal(t, "id; uname -a", string(job.data))
+	assert.True(t, job.bannerSent)
+}
+
+func TestEnqueueRawCaptureDropsWhenFull(t *testing.T) {
+	prev := rawCaptureQueue
+	defer func() { rawCaptureQueue = prev }()
+
+	rawCaptureQueue = nil
+	assert.False(t, enqueueRawCapture(rawCaptureJob{protocol: InteractionUDP}))
+
+	rawCaptureQueue = make(chan rawCaptureJob, 1)
+	assert.True(t, enqueueRawCapture(rawCaptureJob{protocol: InteractionUDP}))
+	assert.False(t, enqueueRawCapture(rawCaptureJob{protocol: InteractionUDP}))
+}
+
+func TestIPWindowLimiter(t *testing.T) {
+	assert.Nil(t, newIPWindowLimiter(0, time.Minute))
+	var unlimited *ipWindowLimiter
+	assert.True(t, unlimited.allow("1.2.3.4", time.Now()))
+
+	now := time.Now()
+	l := newIPWindowLimiter(2, time.Minute)
+	assert.True(t, l.allow("1.2.3.4", now))
+	assert.True(t, l.allow("1.2.3.4", now))
+	assert.False(t, l.allow("1.2.3.4", now))
+	assert.True(t, l.allow("5.6.7.8", now), "限额按来源 IP 独立计算")
+	assert.True(t, l.allow("1.2.3.4", now.Add(time.Minute)), "新窗口重新计数")
+}
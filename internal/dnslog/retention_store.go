@@ -26,3 +26,53 @@ LIMIT ?
 	affected, _ := res.RowsAffected()
 	return affected, nil
 }
+
+func DeleteOldAuditLogs(cutoffMs int64, limit int) (int64, error) {
+	if db == nil {
+		return 0, errors.New("store not initialized")
+	}
+	if limit <= 0 {
+		limit = 1000
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	res, err := db.ExecContext(ctx, `
+DELETE FROM audit_logs
+WHERE created_at < ?
+LIMIT ?
+`, cutoffMs, limit)
+	if err != nil {
+		return 0, err
+	}
+	affected, _ := res.RowsAffected()
+	return affected, nil
+}
+
+func DeleteOldInteractions(cutoffMs int64, limit int) (int64, error) {
+	if db == nil {
+		return 0, errors.New("store not initialized")
+	}
+	if limit <= 0 {
+		limit = 1000
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	// 原始内容与交互记录的 created_at 相同，按同一截止时间清理
+	if _, err := db.ExecContext(ctx, `
+DELETE FROM interaction_raw
+WHERE created_at < ?
+LIMIT ?
+`, cutoffMs, limit); err != nil {
+		return 0, err
+	}
+	res, err := db.ExecContext(ctx, `
+DELETE FROM interactions
+WHERE created_at < ?
+LIMIT ?
+`, cutoffMs, limit)
+	if err != nil {
+		return 0, err
+	}
+	affected, _ := res.RowsAffected()
+	return affected, nil
+}
@@ -12,7 +12,7 @@ func StartRetentionWorker(cfg *config.Config) {
 	if cfg == nil || !cfg.RetentionEnabled {
 		return
 	}
-	if cfg.RecordRetentionDays <= 0 {
+	if cfg.RecordRetentionDays <= 0 && cfg.AuditRetentionDays <= 0 {
 		return
 	}
 	interval := time.Duration(cfg.RetentionIntervalSeconds) * time.Second
@@ -23,14 +23,29 @@ func StartRetentionWorker(cfg *config.Config) {
 		ticker := time.NewTicker(interval)
 		defer ticker.Stop()
 		for range ticker.C {
-			cutoff := time.Now().Add(-time.Duration(cfg.RecordRetentionDays) * 24 * time.Hour).UnixMilli()
-			affected, err := DeleteOldRecords(cutoff, cfg.RetentionBatchSize)
-			if err != nil {
-				log.Error("retention cleanup failed", zap.Error(err))
-				continue
+			if cfg.RecordRetentionDays > 0 {
+				cutoff := time.Now().Add(-time.Duration(cfg.RecordRetentionDays) * 24 * time.Hour).UnixMilli()
+				affected, err := DeleteOldRecords(cutoff, cfg.RetentionBatchSize)
+				if err != nil {
+					log.Error("retention cleanup failed", zap.Error(err))
+				} else if affected > 0 {
+					log.Info("retention cleanup", zap.Int64("deleted", affected))
+				}
+				affected, err = DeleteOldInteractions(cutoff, cfg.RetentionBatchSize)
+				if err != nil {
+					log.Error("interaction retention cleanup failed", zap.Error(err))
+				} else if affected > 0 {
+					log.Info("interaction retention cleanup", zap.Int64("deleted", affected))
+				}
 			}
-			if affected > 0 {
-				log.Info("retention cleanup", zap.Int64("deleted", affected))
+			if cfg.AuditRetentionDays > 0 {
+				cutoff := time.Now().Add(-time.Duration(cfg.AuditRetentionDays) * 24 * time.Hour).UnixMilli()
+				affected, err := DeleteOldAuditLogs(cutoff, cfg.RetentionBatchSize)
+				if err != nil {
+					log.Error("audit retention cleanup failed", zap.Error(err))
+				} else if affected > 0 {
+					log.Info("audit retention cleanup", zap.Int64("deleted", affected))
+				}
 			}
 		}
 	}()
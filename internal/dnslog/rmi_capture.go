@@ -0,0 +1,135 @@
+package dnslog
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/genwilliam/dnslog_for_go/config"
+)
+
+// Java RMI 传输协议（JRMP）常量
+const (
+	rmiMagic             = "JRMI"
+	rmiStreamProtocol    = 0x4b
+	rmiSingleOpProtocol  = 0x4c
+	rmiProtocolAck       = 0x4e
+	rmiReadLimit         = 4096
+	rmiSerializationHead = "\xac\xed\x00\x05"
+)
+
+// rmiInteractionDetails RMI 回连的结构化信息
+type rmiInteractionDetails struct {
+	Protocol string   `json:"protocol"` // stream / singleop
+	Version  int      `json:"version"`
+	Names    []string `json:"names"` // 调用中出现的字符串，lookup 时为名称
+}
+
+// StartRMICapture 启动 RMI 注册中心捕获监听：完成 JRMP 握手、读取 lookup 名称后断开
+func StartRMICapture(cfg *config.Config) {
+	if cfg == nil || !cfg.RMICaptureEnabled || cfg.RMICaptureAddr == "" {
+		return
+	}
+	startTCPCapture(InteractionRMI, cfg.RMICaptureAddr, rmiCaptureHandler(cfg.RMICaptureAddr))
+}
+
+func rmiCaptureHandler(listener string) func(conn net.Conn) {
+	return func(conn net.Conn) {
+		_ = conn.SetDeadline(time.Now().Add(10 * time.Second))
+		hdr := make([]byte, 7)
+		if _, err := io.ReadFull(conn, hdr); err != nil || string(hdr[:4]) != rmiMagic {
+			return
+		}
+		details := rmiInteractionDetails{Version: int(binary.BigEndian.Uint16(hdr[4:6]))}
+		switch hdr[6] {
+		case rmiStreamProtocol:
+			// ProtocolAck 携带客户端地址，随后客户端回送自身端点与调用
+			details.Protocol = "stream"
+			host, portStr, _ := net.SplitHostPort(conn.RemoteAddr().String())
+			port, _ := strconv.Atoi(portStr)
+			ack := []byte{rmiProtocolAck}
+			ack = binary.BigEndian.AppendUint16(ack, uint16(len(host)))
+			ack = append(ack, host...)
+			ack = binary.BigEndian.AppendUint32(ack, uint32(port))
+			if _, err := conn.Write(ack); err != nil {
+				return
+			}
+		case rmiSingleOpProtocol:
+			details.Protocol = "singleop"
+		default:
+			return
+		}
+
+		// 读到包含字符串的调用即停止，客户端在等待应答，不必等到超时
+		_ = conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+		buf := make([]byte, rmiReadLimit)
+		n := 0
+		for n < len(buf) {
+			m, err := conn.Read(buf[n:])
+			n += m
+			if details.Names = rmiStrings(buf[:n]); len(details.Names) > 0 || err != nil {
+				break
+			}
+		}
+
+		ctx := context.Background()
+		for _, name := range details.Names {
+			token, domain, ok := tokenFromName(ctx, name)
+			if !ok {
+				continue
+			}
+			detailsJSON, _ := json.Marshal(details)
+			RecordInteraction(ctx, Interaction{
+				Token:    token,
+				Protocol: InteractionRMI,
+				ClientIP: parseClientIP(conn.RemoteAddr()),
+				Listener: listener,
+				Summary:  "LOOKUP " + strings.Join(details.Names, " "),
+				Details:  detailsJSON,
+				DataSize: int64(n),
+			}, domain)
+			return
+		}
+	}
+}
+
+// rmiStrings 提取 Java 序列化流中的 TC_STRING（可打印 ASCII）
+func rmiStrings(data []byte) []string {
+	start := bytes.Index(data, []byte(rmiSerializationHead))
+	if start < 0 {
+		return nil
+	}
+	var out []string
+	data = data[start+len(rmiSerializationHead):]
+	for i := 0; i+3 <= len(data); i++ {
+		if data[i] != 0x74 {
+			continue
+		}
+		l := int(binary.BigEndian.Uint16(data[i+1 : i+3]))
+		if l == 0 || i+3+l > len(data) {
+			continue
+		}
+		s := data[i+3 : i+3+l]
+		if !isPrintableASCII(s) {
+			continue
+		}
+		out = append(out, string(s))
+		i += 2 + l
+	}
+	return out
+}
+
+func isPrintableASCII(b []byte) bool {
+	for _, c := range b {
+		if c < 0x20 || c > 0x7e {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,70 @@
+package dnslog
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func rmiLookupCall(name string) []byte {
+	call := []byte{0x50}
+	call = append(call, rmiSerializationHead...)
+	call = append(call, 0x77, 0x22) // TC_BLOCKDATA：objid、操作号与接口 hash
+	call = append(call, make([]byte, 0x22)...)
+	call = append(call, 0x74)
+	call = binary.BigEndian.AppendUint16(call, uint16(len(name)))
+	return append(call, name...)
+}
+
+func TestRMIStrings(t *testing.T) {
+	assert.Equal(t, []string{"abc123.demo.com/Exploit"}, rmiStrings(rmiLookupCall("abc123.demo.com/Exploit")))
+	assert.Nil(t, rmiStrings([]byte("t\x00\x03abc")), "序列化头之前的数据不解析")
+}
+
+func TestRMICaptureHandshake(t *testing.T) {
+	client, server := net.Pipe()
+	done := make(chan struct{})
+	go func() {
+		rmiCaptureHandler(":1099")(server)
+		server.Close()
+		close(done)
+	}()
+
+	_, err := client.Write([]byte{'J', 'R', 'M', 'I', 0x00, 0x02, rmiStreamProtocol})
+	require.NoError(t, err)
+	ack := make([]byte, 3)
+	_, err = io.ReadFull(client, ack)
+	require.NoError(t, err)
+	assert.Equal(t, byte(rmiProtocolAck), ack[0])
+	host := make([]byte, binary.BigEndian.Uint16(ack[1:3])+4)
+	_, err = io.ReadFull(client, host)
+	require.NoError(t, err)
+
+	// 名称中没有 token，不会落库
+	_, err = client.Write(rmiLookupCall("Exploit"))
+	require.NoError(t, err)
+	<-done
+}
+
+func TestTokenFromName(t *testing.T) {
+	oldRoot := rootDomain
+	rootDomain = "demo.com"
+	t.Cleanup(func() { rootDomain = oldRoot })
+
+	for name, want := range map[string]string{
+		"abc123.demo.com/Exploit":     "abc123",
+		"cn=x,dc=abc123.demo.com":     "abc123",
+		"ldap://ABC123.demo.com:1389": "abc123",
+	} {
+		token, _, ok := tokenFromName(context.Background(), name)
+		assert.True(t, ok, name)
+		assert.Equal(t, want, token, name)
+	}
+	_, _, ok := tokenFromName(context.Background(), "Exploit")
+	assert.False(t, ok)
+}
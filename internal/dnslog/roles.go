@@ -0,0 +1,58 @@
+package dnslog
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+// 控制台用户的内置角色，角色展开为 scope 后沿用 API Key 的权限检查
+const (
+	RoleAdmin    = "admin"
+	RoleOperator = "operator"
+	RoleViewer   = "viewer"
+)
+
+var ErrInvalidRole = errors.New("invalid_role")
+
+// roleScopes admin 与首个 key 同权，含跨租户访问（"*" 不隐含 admin:tenants）
+var roleScopes = map[string][]string{
+	RoleAdmin:    bootstrapAPIKeyScopes,
+	RoleOperator: {ScopeTokensWrite, ScopeRecordsRead, ScopeConfigRead, ScopeMetricsRead},
+	RoleViewer:   {ScopeTokensRead, ScopeRecordsRead},
+}
+
+// NormalizeRoles 校验、去重并排序角色
+func NormalizeRoles(roles []string) ([]string, error) {
+	seen := make(map[string]struct{}, len(roles))
+	out := make([]string, 0, len(roles))
+	for _, r := range roles {
+		r = strings.ToLower(strings.TrimSpace(r))
+		if r == "" {
+			continue
+		}
+		if _, ok := roleScopes[r]; !ok {
+			return nil, ErrInvalidRole
+		}
+		if _, dup := seen[r]; dup {
+			continue
+		}
+		seen[r] = struct{}{}
+		out = append(out, r)
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// RoleScopes 合并角色对应的 scope
+func RoleScopes(roles []string) []string {
+	var scopes []string
+	for _, r := range roles {
+		scopes = append(scopes, roleScopes[r]...)
+	}
+	out, err := NormalizeScopes(scopes)
+	if err != nil {
+		return []string{}
+	}
+	return out
+}
@@ -0,0 +1,148 @@
+package dnslog
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+// API Key 权限范围
+const (
+	ScopeAll            = "*"
+	ScopeRecordsRead    = "records:read"
+	ScopeTokensRead     = "tokens:read"
+	ScopeTokensWrite    = "tokens:write"
+	ScopeConfigRead     = "config:read"
+	ScopeMetricsRead    = "metrics:read"
+	ScopeAdminKeys      = "admin:keys"
+	ScopeAdminConfig    = "admin:config"
+	ScopeAdminBlacklist = "admin:blacklist"
+	ScopeAdminTenants   = "admin:tenants" // 跨租户查看全部 token 与记录
+	ScopeAdminUsers     = "admin:users"
+	ScopeAuditRead      = "audit:read"
+)
+
+var ErrInvalidScope = errors.New("invalid_scope")
+
+// knownScopes 可授予的全部 scope（不含通配）
+var knownScopes = map[string]struct{}{
+	ScopeRecordsRead:    {},
+	ScopeTokensRead:     {},
+	ScopeTokensWrite:    {},
+	ScopeConfigRead:     {},
+	ScopeMetricsRead:    {},
+	ScopeAdminKeys:      {},
+	ScopeAdminConfig:    {},
+	ScopeAdminBlacklist: {},
+	ScopeAdminTenants:   {},
+	ScopeAdminUsers:     {},
+	ScopeAuditRead:      {},
+}
+
+// scopeAllExcluded 不被 "*" 隐含、必须显式授予的 scope；跨租户访问不能因旧 key 默认为 "*" 而默认开启
+var scopeAllExcluded = map[string]struct{}{
+	ScopeAdminTenants: {},
+}
+
+// defaultAPIKeyScopes 新建 key 未指定 scopes 时的默认权限
+var defaultAPIKeyScopes = []string{ScopeRecordsRead, ScopeTokensWrite}
+
+// bootstrapAPIKeyScopes 首个 key 与紧急恢复 key 的权限：全部权限并显式包含跨租户访问
+var bootstrapAPIKeyScopes = []string{ScopeAll, ScopeAdminTenants}
+
+// scopeImplies 高权限 scope 隐含的低权限 scope
+var scopeImplies = map[string][]string{
+	ScopeTokensWrite: {ScopeTokensRead},
+	ScopeAdminConfig: {ScopeConfigRead},
+}
+
+// NormalizeScopes 校验、去重并排序；包含 "*" 时只保留 "*" 与不被其隐含的 scope
+func NormalizeScopes(scopes []string) ([]string, error) {
+	seen := make(map[string]struct{}, len(scopes))
+	out := make([]string, 0, len(scopes))
+	for _, s := range scopes {
+		s = strings.ToLower(strings.TrimSpace(s))
+		if s == "" {
+			continue
+		}
+		if _, ok := knownScopes[s]; !ok && s != ScopeAll {
+			return nil, ErrInvalidScope
+		}
+		if _, dup := seen[s]; dup {
+			continue
+		}
+		seen[s] = struct{}{}
+		out = append(out, s)
+	}
+	if _, ok := seen[ScopeAll]; ok {
+		kept := []string{ScopeAll}
+		for _, s := range out {
+			if _, excluded := scopeAllExcluded[s]; excluded {
+				kept = append(kept, s)
+			}
+		}
+		out = kept
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// HasScope 判断已授予的 scope 是否覆盖 required；"*" 不覆盖 scopeAllExcluded 中的 scope
+func HasScope(granted []string, required string) bool {
+	_, excluded := scopeAllExcluded[required]
+	for _, g := range granted {
+		if g == required || (g == ScopeAll && !excluded) {
+			return true
+		}
+		for _, implied := range scopeImplies[g] {
+			if implied == required {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ScopesSubset 判断 requested 是否不超出 granted
+func ScopesSubset(requested, granted []string) bool {
+	for _, r := range requested {
+		if r == ScopeAll && !containsScope(granted, ScopeAll) {
+			return false
+		}
+		if !HasScope(granted, r) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// formatScopes / parseScopes 落库格式：逗号分隔
+func formatScopes(scopes []string) string {
+	if len(scopes) == 0 {
+		return ""
+	}
+	return strings.Join(scopes, ",")
+}
+
+func parseScopes(val string) []string {
+	if strings.TrimSpace(val) == "" {
+		return []string{}
+	}
+	parts := strings.Split(val, ",")
+	out := make([]string, 0, len(parts))
+	for _, p := range parts {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
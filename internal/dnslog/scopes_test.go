@@ -0,0 +1,89 @@
+package dnslog
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/genwilliam/dnslog_for_go/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeScopes(t *testing.T) {
+	scopes, err := NormalizeScopes([]string{" Tokens:Write ", "records:read", "tokens:write", ""})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"records:read", "tokens:write"}, scopes)
+
+	scopes, err = NormalizeScopes([]string{"records:read", "*"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{ScopeAll}, scopes)
+
+	scopes, err = NormalizeScopes([]string{"admin:tenants", "records:read", "*"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{ScopeAll, ScopeAdminTenants}, scopes)
+
+	_, err = NormalizeScopes([]string{"admin:everything"})
+	assert.ErrorIs(t, err, ErrInvalidScope)
+}
+
+func TestHasScope(t *testing.T) {
+	assert.True(t, HasScope([]string{ScopeAll}, ScopeAdminKeys))
+	assert.False(t, HasScope([]string{ScopeAll}, ScopeAdminTenants), "跨租户访问需显式授予")
+	assert.True(t, HasScope(bootstrapAPIKeyScopes, ScopeAdminTenants))
+	assert.False(t, HasScope(defaultAPIKeyScopes, ScopeAll))
+	assert.True(t, HasScope([]string{ScopeTokensWrite}, ScopeTokensRead))
+	assert.True(t, HasScope([]string{ScopeAdminConfig}, ScopeConfigRead))
+	assert.False(t, HasScope([]string{ScopeTokensRead}, ScopeTokensWrite))
+	assert.False(t, HasScope([]string{ScopeRecordsRead}, ScopeAll))
+	assert.False(t, HasScope(nil, ScopeRecordsRead))
+}
+
+func TestScopesSubset(t *testing.T) {
+	caller := []string{ScopeAdminKeys, ScopeTokensWrite}
+	assert.True(t, ScopesSubset([]string{ScopeTokensRead}, caller))
+	assert.True(t, ScopesSubset([]string{ScopeAdminKeys, ScopeTokensWrite}, caller))
+	assert.False(t, ScopesSubset([]string{ScopeAdminConfig}, caller))
+	assert.False(t, ScopesSubset([]string{ScopeAll}, caller))
+	assert.True(t, ScopesSubset([]string{ScopeAll}, []string{ScopeAll}))
+}
+
+func TestRoleScopes(t *testing.T) {
+	roles, err := NormalizeRoles([]string{" Viewer", "operator", "viewer"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{RoleOperator, RoleViewer}, roles)
+
+	_, err = NormalizeRoles([]string{"root"})
+	assert.ErrorIs(t, err, ErrInvalidRole)
+
+	assert.Equal(t, []string{ScopeAll, ScopeAdminTenants}, RoleScopes([]string{RoleViewer, RoleAdmin}))
+	assert.True(t, HasScope(RoleScopes([]string{RoleAdmin}), ScopeAdminTenants), "admin 可查看其他租户与无归属 token")
+	assert.False(t, HasScope(RoleScopes([]string{RoleOperator}), ScopeAdminTenants))
+	scopes := RoleScopes([]string{RoleViewer})
+	assert.True(t, HasScope(scopes, ScopeTokensRead))
+	assert.False(t, HasScope(scopes, ScopeTokensWrite))
+	assert.Empty(t, RoleScopes(nil))
+}
+
+func TestAuthorizeAPIKeyTarget(t *testing.T) {
+	newCtx := func(scopes []string) (*gin.Context, *httptest.ResponseRecorder) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Set("api_key_scopes", scopes)
+		c.Set("api_key_tenant", DefaultTenant)
+		return c, w
+	}
+
+	c, w := newCtx([]string{ScopeAdminKeys})
+	assert.False(t, authorizeAPIKeyTarget(c, APIKey{Scopes: []string{ScopeAll}, Tenant: DefaultTenant}))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), response.CodeScopeExceeded)
+
+	c, w = newCtx([]string{ScopeAdminKeys})
+	assert.False(t, authorizeAPIKeyTarget(c, APIKey{Scopes: []string{ScopeTokensRead}, Tenant: "team-b"}))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	c, _ = newCtx([]string{ScopeAdminKeys, ScopeTokensWrite})
+	assert.True(t, authorizeAPIKeyTarget(c, APIKey{Scopes: []string{ScopeTokensRead}, Tenant: DefaultTenant}))
+}
@@ -12,15 +12,45 @@ import (
 )
 
 // CreateAPIKeyHandler 创建 API Key（返回明文 key，仅一次）
+// scopes 不能超出调用方自身的权限；未指定时为 defaultAPIKeyScopes（records:read、tokens:write）
 func CreateAPIKeyHandler(c *gin.Context) {
 	var req struct {
-		Name    string `json:"name" binding:"required"`
-		Comment string `json:"comment"`
+		Name      string   `json:"name" binding:"required"`
+		Comment   string   `json:"comment"`
+		Scopes    []string `json:"scopes"`
+		ExpiresAt int64    `json:"expires_at"`
+		Tenant    string   `json:"tenant"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		response.Error(c, http.StatusBadRequest, response.CodeBadRequest)
 		return
 	}
+	scopes, err := NormalizeScopes(req.Scopes)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, response.CodeInvalidScope)
+		return
+	}
+	nowMs := time.Now().UnixMilli()
+	if !validKeyExpiry(req.ExpiresAt, nowMs) {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest)
+		return
+	}
+	// 只有跨租户管理员可以为其他租户建 key
+	tenant, all := RequestTenant(c)
+	if req.Tenant != "" && req.Tenant != tenant {
+		if !ValidTenant(req.Tenant) {
+			response.Error(c, http.StatusBadRequest, response.CodeInvalidTenant)
+			return
+		}
+		if !all {
+			response.Error(c, http.StatusForbidden, response.CodeForbidden)
+			return
+		}
+		tenant = req.Tenant
+	}
+	if tenant == "" {
+		tenant = DefaultTenant
+	}
 
 	plain, hash, err := GenerateAPIKey()
 	if err != nil {
@@ -29,9 +59,8 @@ func CreateAPIKeyHandler(c *gin.Context) {
 	}
 
 	cfg := config.Get()
-	nowMs := time.Now().UnixMilli()
 	if cfg != nil && cfg.APIKeyRequired {
-		if _, ok := c.Get("api_key_id"); !ok {
+		if _, ok := callerAPIKeyScopes(c); !ok {
 			id, err := CreateBootstrapAPIKeyWithContext(c.Request.Context(), req.Name, hash, req.Comment, nowMs)
 			if err == ErrBootstrapConflict {
 				response.Error(c, http.StatusConflict, response.CodeAPIKeyAlreadyInitialized)
@@ -41,28 +70,172 @@ func CreateAPIKeyHandler(c *gin.Context) {
 				response.Error(c, http.StatusInternalServerError, response.CodeInternalError)
 				return
 			}
+			RecordAdminEvent(c, AdminActionAPIKeyCreate, strconv.FormatInt(id, 10), nil, gin.H{
+				"id":     id,
+				"name":   req.Name,
+				"scopes": bootstrapAPIKeyScopes,
+				"tenant": DefaultTenant,
+			})
 			response.Success(c, gin.H{
-				"id":   id,
-				"name": req.Name,
-				"key":  plain,
+				"id":     id,
+				"name":   req.Name,
+				"key":    plain,
+				"scopes": bootstrapAPIKeyScopes,
 			})
 			return
 		}
 	}
 
-	id, err := CreateAPIKeyWithContext(c.Request.Context(), req.Name, hash, req.Comment, nowMs)
+	// 未指定时使用默认 scopes，而不是继承调用方（常为 "*"）的全部权限
+	if len(scopes) == 0 {
+		scopes = defaultAPIKeyScopes
+	}
+	if callerScopes, ok := callerAPIKeyScopes(c); ok && !ScopesSubset(scopes, callerScopes) {
+		response.Error(c, http.StatusForbidden, response.CodeScopeExceeded)
+		return
+	}
+
+	id, err := CreateAPIKeyWithContext(c.Request.Context(), req.Name, hash, req.Comment, tenant, scopes, req.ExpiresAt, nowMs)
 	if err != nil {
 		response.Error(c, http.StatusInternalServerError, response.CodeInternalError)
 		return
 	}
+	RecordAdminEvent(c, AdminActionAPIKeyCreate, strconv.FormatInt(id, 10), nil, gin.H{
+		"id":         id,
+		"name":       req.Name,
+		"scopes":     scopes,
+		"tenant":     tenant,
+		"expires_at": req.ExpiresAt,
+	})
 
 	response.Success(c, gin.H{
-		"id":   id,
-		"name": req.Name,
-		"key":  plain,
+		"id":         id,
+		"name":       req.Name,
+		"key":        plain,
+		"scopes":     scopes,
+		"tenant":     tenant,
+		"expires_at": req.ExpiresAt,
 	})
 }
 
+// RotateAPIKeyHandler 轮换 API Key：签发继承 scopes 的新 key，旧 key 在宽限期内仍可用
+func RotateAPIKeyHandler(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest)
+		return
+	}
+	var req struct {
+		GraceSeconds *int  `json:"grace_seconds"`
+		ExpiresAt    int64 `json:"expires_at"`
+	}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.Error(c, http.StatusBadRequest, response.CodeBadRequest)
+			return
+		}
+	}
+	nowMs := time.Now().UnixMilli()
+	if !validKeyExpiry(req.ExpiresAt, nowMs) || (req.GraceSeconds != nil && *req.GraceSeconds < 0) {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest)
+		return
+	}
+	graceMs := int64(86400 * 1000)
+	if cfg := config.Get(); cfg != nil && cfg.APIKeyRotationGraceSeconds > 0 {
+		graceMs = int64(cfg.APIKeyRotationGraceSeconds) * 1000
+	}
+	if req.GraceSeconds != nil {
+		graceMs = int64(*req.GraceSeconds) * 1000
+	}
+
+	old, err := GetAPIKeyByIDWithContext(c.Request.Context(), id)
+	if err == ErrAPIKeyNotFound {
+		response.Error(c, http.StatusNotFound, response.CodeNotFound)
+		return
+	}
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError)
+		return
+	}
+	// 新 key 继承旧 key 的 scopes，调用方不能借轮换拿到更高权限
+	if !authorizeAPIKeyTarget(c, old) {
+		return
+	}
+
+	plain, hash, err := GenerateAPIKey()
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError)
+		return
+	}
+	key, err := RotateAPIKeyWithContext(c.Request.Context(), id, hash, req.ExpiresAt, graceMs, nowMs)
+	if err == ErrAPIKeyNotFound {
+		response.Error(c, http.StatusNotFound, response.CodeNotFound)
+		return
+	}
+	if err == ErrAPIKeyNotRotatable {
+		response.Error(c, http.StatusConflict, response.CodeAPIKeyNotRotatable)
+		return
+	}
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError)
+		return
+	}
+
+	oldExpiresAt := nowMs + graceMs
+	if old.ExpiresAt > 0 && old.ExpiresAt < oldExpiresAt {
+		oldExpiresAt = old.ExpiresAt
+	}
+	RecordAdminEvent(c, AdminActionAPIKeyRotate, strconv.FormatInt(id, 10),
+		gin.H{"id": old.ID, "expires_at": old.ExpiresAt},
+		gin.H{"id": old.ID, "expires_at": oldExpiresAt, "new_key_id": key.ID, "new_key_expires_at": key.ExpiresAt})
+	response.Success(c, gin.H{
+		"id":                 key.ID,
+		"name":               key.Name,
+		"key":                plain,
+		"scopes":             key.Scopes,
+		"tenant":             key.Tenant,
+		"expires_at":         key.ExpiresAt,
+		"rotated_from":       key.RotatedFrom,
+		"old_key_expires_at": oldExpiresAt,
+	})
+}
+
+// validKeyExpiry expires_at 为 0（不过期）或未来时间
+func validKeyExpiry(expiresAt, nowMs int64) bool {
+	return expiresAt == 0 || expiresAt > nowMs
+}
+
+func queryPositiveInt(c *gin.Context, name string, def int) int {
+	v, err := strconv.Atoi(c.Query(name))
+	if err != nil || v <= 0 {
+		return def
+	}
+	return v
+}
+
+// authorizeAPIKeyTarget 管理（轮换、禁用）其他 key 前检查：同租户，且目标 scopes 不超出调用方
+func authorizeAPIKeyTarget(c *gin.Context, target APIKey) bool {
+	if !canAccessTenant(c, target.Tenant) {
+		response.Error(c, http.StatusNotFound, response.CodeNotFound)
+		return false
+	}
+	if callerScopes, ok := callerAPIKeyScopes(c); ok && !ScopesSubset(target.Scopes, callerScopes) {
+		response.Error(c, http.StatusForbidden, response.CodeScopeExceeded)
+		return false
+	}
+	return true
+}
+
+// callerAPIKeyScopes 返回当前请求所用 key 的 scopes（未启用鉴权时不存在）
+func callerAPIKeyScopes(c *gin.Context) ([]string, bool) {
+	v, ok := c.Get("api_key_scopes")
+	if !ok {
+		return nil, false
+	}
+	scopes, ok := v.([]string)
+	return scopes, ok
+}
+
 // ListAPIKeysHandler 列出 API Keys
 func ListAPIKeysHandler(c *gin.Context) {
 	cfg := config.Get()
@@ -81,7 +254,28 @@ func ListAPIKeysHandler(c *gin.Context) {
 		pageSize = cfg.MaxPageSize
 	}
 
-	items, total, err := ListAPIKeysWithContext(c.Request.Context(), page, pageSize)
+	nowMs := time.Now().UnixMilli()
+	filter := APIKeyListFilter{
+		Page:     page,
+		PageSize: pageSize,
+		Status:   c.Query("status"),
+		NowMs:    nowMs,
+		Tenant:   RequestListTenant(c),
+	}
+	switch filter.Status {
+	case "", "active", "disabled", "expired":
+	case "expiring":
+		days := queryPositiveInt(c, "within_days", 7)
+		filter.ExpiringBefore = nowMs + int64(days)*86400*1000
+	case "stale":
+		days := queryPositiveInt(c, "stale_days", 90)
+		filter.StaleBefore = nowMs - int64(days)*86400*1000
+	default:
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest)
+		return
+	}
+
+	items, total, err := ListAPIKeysWithContext(c.Request.Context(), filter)
 	if err != nil {
 		response.Error(c, http.StatusInternalServerError, response.CodeInternalError)
 		return
@@ -99,7 +293,12 @@ func ListAPIKeysHandler(c *gin.Context) {
 			"enabled":      k.Enabled,
 			"created_at":   k.CreatedAt,
 			"last_used_at": k.LastUsedAt,
+			"expires_at":   k.ExpiresAt,
+			"expired":      k.Expired(nowMs),
+			"rotated_from": k.RotatedFrom,
 			"comment":      k.Comment,
+			"scopes":       k.Scopes,
+			"tenant":       k.Tenant,
 			"hash_prefix":  hashPrefix,
 		})
 	}
@@ -120,6 +319,19 @@ func DisableAPIKeyHandler(c *gin.Context) {
 		response.Error(c, http.StatusBadRequest, response.CodeBadRequest)
 		return
 	}
+	key, err := GetAPIKeyByIDWithContext(c.Request.Context(), id)
+	if err == ErrAPIKeyNotFound {
+		response.Error(c, http.StatusNotFound, response.CodeNotFound)
+		return
+	}
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError)
+		return
+	}
+	// 与轮换相同，不能禁用权限高于自己的 key（如 bootstrap 管理员 key）
+	if !authorizeAPIKeyTarget(c, key) {
+		return
+	}
 	if err := SetAPIKeyEnabledWithContext(c.Request.Context(), id, false); err != nil {
 		if err == ErrAPIKeyNotFound {
 			response.Error(c, http.StatusNotFound, response.CodeNotFound)
@@ -128,6 +340,7 @@ func DisableAPIKeyHandler(c *gin.Context) {
 		response.Error(c, http.StatusInternalServerError, response.CodeInternalError)
 		return
 	}
+	RecordAdminEvent(c, AdminActionAPIKeyDisable, idStr, gin.H{"enabled": key.Enabled}, gin.H{"enabled": false})
 	response.Success(c, gin.H{"id": id, "disabled": true})
 }
 
@@ -145,13 +358,28 @@ func CreateAPIKeyWithBootstrapHandler(c *gin.Context) {
 	}
 
 	var req struct {
-		Name    string `json:"name" binding:"required"`
-		Comment string `json:"comment"`
+		Name      string   `json:"name" binding:"required"`
+		Comment   string   `json:"comment"`
+		Scopes    []string `json:"scopes"`
+		ExpiresAt int64    `json:"expires_at"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		response.Error(c, http.StatusBadRequest, response.CodeBadRequest)
 		return
 	}
+	scopes, err := NormalizeScopes(req.Scopes)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, response.CodeInvalidScope)
+		return
+	}
+	if len(scopes) == 0 {
+		scopes = bootstrapAPIKeyScopes
+	}
+	nowMs := time.Now().UnixMilli()
+	if !validKeyExpiry(req.ExpiresAt, nowMs) {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest)
+		return
+	}
 
 	plain, hash, err := GenerateAPIKey()
 	if err != nil {
@@ -159,35 +387,64 @@ func CreateAPIKeyWithBootstrapHandler(c *gin.Context) {
 		return
 	}
 
-	nowMs := time.Now().UnixMilli()
-	id, err := CreateAPIKeyWithContext(c.Request.Context(), req.Name, hash, req.Comment, nowMs)
+	id, err := CreateAPIKeyWithContext(c.Request.Context(), req.Name, hash, req.Comment, DefaultTenant, scopes, req.ExpiresAt, nowMs)
 	if err != nil {
 		response.Error(c, http.StatusInternalServerError, response.CodeInternalError)
 		return
 	}
 
 	response.Success(c, gin.H{
-		"id":   id,
-		"name": req.Name,
-		"key":  plain,
+		"id":     id,
+		"name":   req.Name,
+		"key":    plain,
+		"scopes": scopes,
 	})
 }
 
-// AddBlacklistHandler 添加 IP 黑名单
+// AddBlacklistHandler 添加 IP 或 CIDR 黑名单，可指定生效范围与过期时间
 func AddBlacklistHandler(c *gin.Context) {
 	var req struct {
-		IP     string `json:"ip" binding:"required"`
-		Reason string `json:"reason"`
+		IP        string `json:"ip" binding:"required"`
+		Reason    string `json:"reason"`
+		Scope     string `json:"scope"`
+		ExpiresAt int64  `json:"expires_at"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		response.Error(c, http.StatusBadRequest, response.CodeBadRequest)
 		return
 	}
-	if err := AddBlacklistIPWithContext(c.Request.Context(), req.IP, req.Reason, time.Now().UnixMilli()); err != nil {
+	scope, ok := NormalizeBlacklistScope(req.Scope)
+	if !ok {
+		response.Error(c, http.StatusBadRequest, response.CodeInvalidBlacklistScope)
+		return
+	}
+	nowMs := time.Now().UnixMilli()
+	if !validKeyExpiry(req.ExpiresAt, nowMs) {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest)
+		return
+	}
+	prefix, err := ParseBlacklistTarget(req.IP)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, response.CodeInvalidBlacklistIP)
+		return
+	}
+	var before interface{}
+	if old, err := GetBlacklistEntryWithContext(c.Request.Context(), 0, FormatBlacklistTarget(prefix), scope); err == nil {
+		before = old
+	}
+	ip, err := AddBlacklistIPWithContext(c.Request.Context(), req.IP, req.Reason, scope, req.ExpiresAt, nowMs)
+	if err != nil {
 		response.Error(c, http.StatusInternalServerError, response.CodeInternalError)
 		return
 	}
-	response.Success(c, gin.H{"ip": req.IP, "enabled": true})
+	RecordAdminEvent(c, AdminActionBlacklistAdd, ip, before, gin.H{
+		"ip":         ip,
+		"reason":     req.Reason,
+		"scope":      scope,
+		"enabled":    true,
+		"expires_at": req.ExpiresAt,
+	})
+	response.Success(c, gin.H{"ip": ip, "scope": scope, "expires_at": req.ExpiresAt, "enabled": true})
 }
 
 // ListBlacklistHandler 列出黑名单
@@ -230,6 +487,15 @@ func DisableBlacklistHandler(c *gin.Context) {
 		response.Error(c, http.StatusBadRequest, response.CodeBadRequest)
 		return
 	}
+	entry, err := GetBlacklistEntryWithContext(c.Request.Context(), id, "", "")
+	if err == ErrBlacklistNotFound {
+		response.Error(c, http.StatusNotFound, response.CodeNotFound)
+		return
+	}
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError)
+		return
+	}
 	if err := DisableBlacklistIPWithContext(c.Request.Context(), id); err != nil {
 		if err == ErrBlacklistNotFound {
 			response.Error(c, http.StatusNotFound, response.CodeNotFound)
@@ -238,5 +504,8 @@ func DisableBlacklistHandler(c *gin.Context) {
 		response.Error(c, http.StatusInternalServerError, response.CodeInternalError)
 		return
 	}
+	after := entry
+	after.Enabled = false
+	RecordAdminEvent(c, AdminActionBlacklistDisable, entry.IP, entry, after)
 	response.Success(c, gin.H{"id": id, "disabled": true})
 }
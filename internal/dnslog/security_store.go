@@ -4,17 +4,39 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"strings"
 	"time"
 )
 
 type APIKey struct {
-	ID         int64
-	Name       string
-	APIKey     string
-	Enabled    bool
-	CreatedAt  int64
-	LastUsedAt int64
-	Comment    string
+	ID          int64
+	Name        string
+	APIKey      string
+	Enabled     bool
+	CreatedAt   int64
+	LastUsedAt  int64
+	Comment     string
+	Scopes      []string
+	ExpiresAt   int64 // 0 表示永不过期
+	RotatedFrom int64 // 轮换生成时记录被替换的 key ID
+	Tenant      string
+}
+
+// Expired 判断 key 在 nowMs 时是否已过期
+func (k APIKey) Expired(nowMs int64) bool {
+	return k.ExpiresAt > 0 && nowMs >= k.ExpiresAt
+}
+
+// APIKeyListFilter API Key 列表过滤条件
+type APIKeyListFilter struct {
+	Page     int
+	PageSize int
+	// Status: active | disabled | expired | expiring | stale，空表示全部
+	Status         string
+	NowMs          int64
+	ExpiringBefore int64  // expiring：在该时间之前过期
+	StaleBefore    int64  // stale：最后使用（从未使用则为创建时间）早于该时间
+	Tenant         string // 非空时只返回该租户的 key
 }
 
 type AuditLog struct {
@@ -30,6 +52,7 @@ type AuditLog struct {
 }
 
 var ErrAPIKeyNotFound = errors.New("api_key_not_found")
+var ErrAPIKeyNotRotatable = errors.New("api_key_not_rotatable")
 var ErrBootstrapConflict = errors.New("bootstrap_key_conflict")
 
 func GetAPIKeyByHashWithContext(ctx context.Context, hash string) (APIKey, error) {
@@ -41,14 +64,16 @@ func GetAPIKeyByHashWithContext(ctx context.Context, hash string) (APIKey, error
 	defer cancel()
 
 	var k APIKey
+	var scopes string
 	err := db.QueryRowContext(ctx, `
-SELECT id, name, api_key, enabled, created_at, last_used_at, comment
+SELECT id, name, api_key, enabled, created_at, last_used_at, comment, scopes, expires_at, rotated_from, tenant
 FROM api_keys
 WHERE api_key = ?
-`, hash).Scan(&k.ID, &k.Name, &k.APIKey, &k.Enabled, &k.CreatedAt, &k.LastUsedAt, &k.Comment)
+`, hash).Scan(&k.ID, &k.Name, &k.APIKey, &k.Enabled, &k.CreatedAt, &k.LastUsedAt, &k.Comment, &scopes, &k.ExpiresAt, &k.RotatedFrom, &k.Tenant)
 	if errors.Is(err, sql.ErrNoRows) {
 		return APIKey{}, ErrAPIKeyNotFound
 	}
+	k.Scopes = parseScopes(scopes)
 	return k, err
 }
 
@@ -56,6 +81,32 @@ func GetAPIKeyByHash(hash string) (APIKey, error) {
 	return GetAPIKeyByHashWithContext(context.Background(), hash)
 }
 
+func GetAPIKeyByIDWithContext(ctx context.Context, id int64) (APIKey, error) {
+	if db == nil {
+		return APIKey{}, errors.New("store not initialized")
+	}
+	ctx = ensureContext(ctx)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	var k APIKey
+	var scopes string
+	err := db.QueryRowContext(ctx, `
+SELECT id, name, api_key, enabled, created_at, last_used_at, comment, scopes, expires_at, rotated_from, tenant
+FROM api_keys
+WHERE id = ?
+`, id).Scan(&k.ID, &k.Name, &k.APIKey, &k.Enabled, &k.CreatedAt, &k.LastUsedAt, &k.Comment, &scopes, &k.ExpiresAt, &k.RotatedFrom, &k.Tenant)
+	if errors.Is(err, sql.ErrNoRows) {
+		return APIKey{}, ErrAPIKeyNotFound
+	}
+	k.Scopes = parseScopes(scopes)
+	return k, err
+}
+
+func GetAPIKeyByID(id int64) (APIKey, error) {
+	return GetAPIKeyByIDWithContext(context.Background(), id)
+}
+
 func TouchAPIKeyLastUsedWithContext(ctx context.Context, id int64, nowMs int64) error {
 	if db == nil {
 		return errors.New("store not initialized")
@@ -91,7 +142,7 @@ func AddAuditLog(log AuditLog) error {
 	return AddAuditLogWithContext(context.Background(), log)
 }
 
-func CreateAPIKeyWithContext(ctx context.Context, name, hash, comment string, nowMs int64) (int64, error) {
+func CreateAPIKeyWithContext(ctx context.Context, name, hash, comment, tenant string, scopes []string, expiresAt, nowMs int64) (int64, error) {
 	if db == nil {
 		return 0, errors.New("store not initialized")
 	}
@@ -99,9 +150,9 @@ func CreateAPIKeyWithContext(ctx context.Context, name, hash, comment string, no
 	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 	res, err := db.ExecContext(ctx, `
-INSERT INTO api_keys (name, api_key, enabled, created_at, last_used_at, comment)
-VALUES (?, ?, 1, ?, 0, ?)
-`, name, hash, nowMs, comment)
+INSERT INTO api_keys (name, api_key, enabled, created_at, last_used_at, comment, scopes, expires_at, tenant)
+VALUES (?, ?, 1, ?, 0, ?, ?, ?, ?)
+`, name, hash, nowMs, comment, formatScopes(scopes), expiresAt, tenant)
 	if err != nil {
 		return 0, err
 	}
@@ -109,8 +160,82 @@ VALUES (?, ?, 1, ?, 0, ?)
 	return id, nil
 }
 
-func CreateAPIKey(name, hash, comment string, nowMs int64) (int64, error) {
-	return CreateAPIKeyWithContext(context.Background(), name, hash, comment, nowMs)
+func CreateAPIKey(name, hash, comment, tenant string, scopes []string, expiresAt, nowMs int64) (int64, error) {
+	return CreateAPIKeyWithContext(context.Background(), name, hash, comment, tenant, scopes, expiresAt, nowMs)
+}
+
+// RotateAPIKeyWithContext 为 id 签发替换 key（继承名称、备注、租户与 scopes），
+// 旧 key 在 graceMs 后过期（已有更早的过期时间则保持不变）
+func RotateAPIKeyWithContext(ctx context.Context, id int64, hash string, expiresAt, graceMs, nowMs int64) (APIKey, error) {
+	if db == nil {
+		return APIKey{}, errors.New("store not initialized")
+	}
+	ctx = ensureContext(ctx)
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return APIKey{}, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	var old APIKey
+	var enabled int
+	var scopes string
+	err = tx.QueryRowContext(ctx, `
+SELECT id, name, enabled, comment, scopes, expires_at, tenant
+FROM api_keys
+WHERE id = ?
+FOR UPDATE
+`, id).Scan(&old.ID, &old.Name, &enabled, &old.Comment, &scopes, &old.ExpiresAt, &old.Tenant)
+	if errors.Is(err, sql.ErrNoRows) {
+		return APIKey{}, ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return APIKey{}, err
+	}
+	old.Scopes = parseScopes(scopes)
+	if enabled != 1 || old.Expired(nowMs) {
+		return APIKey{}, ErrAPIKeyNotRotatable
+	}
+
+	res, err := tx.ExecContext(ctx, `
+INSERT INTO api_keys (name, api_key, enabled, created_at, last_used_at, comment, scopes, expires_at, rotated_from, tenant)
+VALUES (?, ?, 1, ?, 0, ?, ?, ?, ?, ?)
+`, old.Name, hash, nowMs, old.Comment, scopes, expiresAt, old.ID, old.Tenant)
+	if err != nil {
+		return APIKey{}, err
+	}
+	newID, _ := res.LastInsertId()
+
+	graceUntil := nowMs + graceMs
+	if old.ExpiresAt > 0 && old.ExpiresAt < graceUntil {
+		graceUntil = old.ExpiresAt
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE api_keys SET expires_at = ? WHERE id = ?`, graceUntil, old.ID); err != nil {
+		return APIKey{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return APIKey{}, err
+	}
+	return APIKey{
+		ID:          newID,
+		Name:        old.Name,
+		Enabled:     true,
+		CreatedAt:   nowMs,
+		Comment:     old.Comment,
+		Scopes:      old.Scopes,
+		ExpiresAt:   expiresAt,
+		RotatedFrom: old.ID,
+		Tenant:      old.Tenant,
+	}, nil
+}
+
+func RotateAPIKey(id int64, hash string, expiresAt, graceMs, nowMs int64) (APIKey, error) {
+	return RotateAPIKeyWithContext(context.Background(), id, hash, expiresAt, graceMs, nowMs)
 }
 
 func SetAPIKeyEnabledWithContext(ctx context.Context, id int64, enabled bool) error {
@@ -141,32 +266,63 @@ func SetAPIKeyEnabled(id int64, enabled bool) error {
 	return SetAPIKeyEnabledWithContext(context.Background(), id, enabled)
 }
 
-func ListAPIKeysWithContext(ctx context.Context, page, pageSize int) ([]APIKey, int, error) {
+func ListAPIKeysWithContext(ctx context.Context, filter APIKeyListFilter) ([]APIKey, int, error) {
 	if db == nil {
 		return nil, 0, errors.New("store not initialized")
 	}
-	if page < 1 {
-		page = 1
+	if filter.Page < 1 {
+		filter.Page = 1
 	}
-	if pageSize <= 0 {
-		pageSize = 20
+	if filter.PageSize <= 0 {
+		filter.PageSize = 20
+	}
+	if filter.NowMs <= 0 {
+		filter.NowMs = time.Now().UnixMilli()
 	}
 	ctx = ensureContext(ctx)
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
+	where := make([]string, 0)
+	args := make([]interface{}, 0)
+	switch filter.Status {
+	case "active":
+		where = append(where, "enabled = 1 AND (expires_at = 0 OR expires_at > ?)")
+		args = append(args, filter.NowMs)
+	case "disabled":
+		where = append(where, "enabled = 0")
+	case "expired":
+		where = append(where, "expires_at > 0 AND expires_at <= ?")
+		args = append(args, filter.NowMs)
+	case "expiring":
+		where = append(where, "enabled = 1 AND expires_at > ? AND expires_at <= ?")
+		args = append(args, filter.NowMs, filter.ExpiringBefore)
+	case "stale":
+		where = append(where, "enabled = 1 AND (expires_at = 0 OR expires_at > ?) AND GREATEST(last_used_at, created_at) < ?")
+		args = append(args, filter.NowMs, filter.StaleBefore)
+	}
+	if filter.Tenant != "" {
+		where = append(where, "tenant = ?")
+		args = append(args, filter.Tenant)
+	}
+	whereSQL := ""
+	if len(where) > 0 {
+		whereSQL = "WHERE " + strings.Join(where, " AND ")
+	}
+
 	var total int
-	if err := db.QueryRowContext(ctx, `SELECT COUNT(1) FROM api_keys`).Scan(&total); err != nil {
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(1) FROM api_keys `+whereSQL, args...).Scan(&total); err != nil {
 		return nil, 0, err
 	}
 
-	offset := (page - 1) * pageSize
+	offset := (filter.Page - 1) * filter.PageSize
 	rows, err := db.QueryContext(ctx, `
-SELECT id, name, api_key, enabled, created_at, last_used_at, comment
+SELECT id, name, api_key, enabled, created_at, last_used_at, comment, scopes, expires_at, rotated_from, tenant
 FROM api_keys
+`+whereSQL+`
 ORDER BY id DESC
 LIMIT ? OFFSET ?
-`, pageSize, offset)
+`, append(args, filter.PageSize, offset)...)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -176,17 +332,19 @@ LIMIT ? OFFSET ?
 	for rows.Next() {
 		var k APIKey
 		var enabled int
-		if err := rows.Scan(&k.ID, &k.Name, &k.APIKey, &enabled, &k.CreatedAt, &k.LastUsedAt, &k.Comment); err != nil {
+		var scopes string
+		if err := rows.Scan(&k.ID, &k.Name, &k.APIKey, &enabled, &k.CreatedAt, &k.LastUsedAt, &k.Comment, &scopes, &k.ExpiresAt, &k.RotatedFrom, &k.Tenant); err != nil {
 			return nil, 0, err
 		}
 		k.Enabled = enabled == 1
+		k.Scopes = parseScopes(scopes)
 		items = append(items, k)
 	}
 	return items, total, nil
 }
 
-func ListAPIKeys(page, pageSize int) ([]APIKey, int, error) {
-	return ListAPIKeysWithContext(context.Background(), page, pageSize)
+func ListAPIKeys(filter APIKeyListFilter) ([]APIKey, int, error) {
+	return ListAPIKeysWithContext(context.Background(), filter)
 }
 
 func HasAPIKeysWithContext(ctx context.Context) (bool, error) {
@@ -263,10 +421,11 @@ func CreateBootstrapAPIKeyWithContext(ctx context.Context, name, hash, comment s
 		return 0, ErrBootstrapConflict
 	}
 
+	// 首个 key 拥有全部权限（含跨租户访问），用于后续分配细粒度 key
 	res, err := tx.ExecContext(ctx, `
-INSERT INTO api_keys (name, api_key, enabled, created_at, last_used_at, comment)
-VALUES (?, ?, 1, ?, 0, ?)
-`, name, hash, nowMs, comment)
+INSERT INTO api_keys (name, api_key, enabled, created_at, last_used_at, comment, scopes, tenant)
+VALUES (?, ?, 1, ?, 0, ?, ?, ?)
+`, name, hash, nowMs, comment, formatScopes(bootstrapAPIKeyScopes), DefaultTenant)
 	if err != nil {
 		return 0, err
 	}
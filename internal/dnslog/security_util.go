@@ -4,8 +4,22 @@ import (
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	minPasswordLength = 8
+	maxPasswordLength = 72 // bcrypt 只使用前 72 字节
+	passwordHashCost  = 12
 )
 
+var ErrWeakPassword = errors.New("weak_password")
+
+// dummyPasswordHash 用户不存在时仍执行一次 bcrypt 比较，避免通过耗时枚举用户名
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("dnslog-dummy-password"), passwordHashCost)
+
 func HashAPIKey(key string) string {
 	sum := sha256.Sum256([]byte(key))
 	return hex.EncodeToString(sum[:])
@@ -19,3 +33,40 @@ func GenerateAPIKey() (string, string, error) {
 	plain := hex.EncodeToString(raw)
 	return plain, HashAPIKey(plain), nil
 }
+
+// MaskSecret 脱敏敏感值：较长时仅保留末 4 位
+func MaskSecret(val string) string {
+	if val == "" {
+		return ""
+	}
+	if len(val) <= 8 {
+		return "****"
+	}
+	return "****" + val[len(val)-4:]
+}
+
+// HashPassword 使用 bcrypt 生成密码哈希
+func HashPassword(password string) (string, error) {
+	if len(password) < minPasswordLength || len(password) > maxPasswordLength {
+		return "", ErrWeakPassword
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), passwordHashCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// CheckPassword 校验密码；hash 为空时与占位哈希比较，保持耗时一致
+func CheckPassword(hash, password string) bool {
+	if hash == "" {
+		_ = bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+		return false
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// GenerateSessionToken 生成会话 token（明文给浏览器，库中只存哈希）
+func GenerateSessionToken() (string, string, error) {
+	return GenerateAPIKey()
+}
@@ -1,6 +1,7 @@
 package dnslog
 
 import (
+	"context"
 	"net"
 	"strings"
 
@@ -49,6 +50,7 @@ func StartDNSServer(cfg *config.Config) {
 	}
 	StartExpireWorker()
 	StartRetentionWorker(cfg)
+	StartDNSBackfillWorker()
 
 	dns.HandleFunc(".", handleDNSQuery)
 
@@ -99,12 +101,16 @@ func handleDNSQuery(w dns.ResponseWriter, r *dns.Msg) {
 
 	remoteAddr := w.RemoteAddr()
 	clientIP := parseClientIP(remoteAddr)
-	if blocked, _ := IsIPBlacklisted(clientIP); blocked {
+	if blocked, _ := IsIPBlacklisted(clientIP, BlacklistScopeDNS); blocked {
 		m := new(dns.Msg)
 		m.SetRcode(r, dns.RcodeRefused)
 		_ = w.WriteMsg(m)
 		return
 	}
+	// ACME DNS-01 校验，不计入限流与记录
+	if answerACMEChallenge(w, r) {
+		return
+	}
 	if !AllowDNSQuery(clientIP) {
 		m := new(dns.Msg)
 		m.SetRcode(r, dns.RcodeRefused)
@@ -152,7 +158,7 @@ func handleDNSQuery(w dns.ResponseWriter, r *dns.Msg) {
 			token = parts[0]
 		}
 
-		if err := AddRecord(Record{
+		rec := Record{
 			Domain:    qName, // 完整域名
 			ClientIP:  clientIP,
 			Protocol:  proto,
@@ -160,24 +166,14 @@ func handleDNSQuery(w dns.ResponseWriter, r *dns.Msg) {
 			Timestamp: nowMillis(),
 			Server:    listenAddr,
 			Token:     token,
-		}); err != nil {
+		}
+		if err := AddRecord(rec); err != nil {
 			log.Error("保存 DNS 记录失败", zap.Error(err))
 		}
 
+		// 带 token 的查询同时写入交互时间线，与其他协议合并展示
 		if token != "" && token != "(none)" {
-			ttlMs := int64(activeConfig.TokenTTLSeconds) * 1000
-			if ttlMs <= 0 {
-				ttlMs = int64(3600 * 1000)
-			}
-			isFirst, err := UpsertTokenHit(token, qName, nowMillis(), ttlMs)
-			if err != nil {
-				log.Error("更新 token 状态失败", zap.Error(err))
-			} else {
-				metrics.TokenHitsTotal.Inc()
-				if err := MaybeEnqueueWebhook(token, isFirst, qName); err != nil {
-					log.Error("触发 webhook 失败", zap.Error(err))
-				}
-			}
+			RecordInteraction(context.Background(), dnsInteraction(rec), qName)
 		}
 
 		log.Info("Captured DNS query",
@@ -237,3 +233,16 @@ func selectMatchedRoot(qName string) string {
 	}
 	return ""
 }
+
+// matchRootDomain 返回 name 所在的根域（name 必须是其子域名，不受 captureAll 影响），不匹配时返回 ""
+func matchRootDomain(name string) string {
+	if rootDomain != "" && strings.HasSuffix(name, "."+rootDomain) {
+		return rootDomain
+	}
+	for _, rd := range rootDomains {
+		if strings.HasSuffix(name, "."+rd) {
+			return rd
+		}
+	}
+	return ""
+}
@@ -0,0 +1,105 @@
+package dnslog
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"time"
+
+	"github.com/genwilliam/dnslog_for_go/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 控制台会话使用的 cookie 与请求头
+const (
+	SessionCookieName = "dnslog_session"
+	CSRFCookieName    = "dnslog_csrf"
+	CSRFHeader        = "X-CSRF-Token"
+)
+
+// AuthenticateSession 校验会话 cookie，返回会话与所属（启用中的）用户
+func AuthenticateSession(ctx context.Context, cookie string, nowMs int64) (UserSession, User, error) {
+	if cookie == "" {
+		return UserSession{}, User{}, ErrSessionNotFound
+	}
+	sess, err := GetSessionByHashWithContext(ctx, HashAPIKey(cookie), nowMs)
+	if err != nil {
+		return UserSession{}, User{}, err
+	}
+	user, err := GetUserByIDWithContext(ctx, sess.UserID)
+	if err == ErrUserNotFound || (err == nil && !user.Enabled) {
+		return UserSession{}, User{}, ErrSessionNotFound
+	}
+	if err != nil {
+		return UserSession{}, User{}, err
+	}
+	return sess, user, nil
+}
+
+// ValidCSRFToken 常量时间比较请求头中的 CSRF token
+func ValidCSRFToken(sess UserSession, header string) bool {
+	return header != "" && subtle.ConstantTimeCompare([]byte(sess.CSRFToken), []byte(header)) == 1
+}
+
+// RequiresCSRF 非幂等方法需要校验 CSRF token
+func RequiresCSRF(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+	return true
+}
+
+// sessionTTL 会话有效期，默认 12 小时
+func sessionTTL() time.Duration {
+	if cfg := config.Get(); cfg != nil && cfg.SessionTTLSeconds > 0 {
+		return time.Duration(cfg.SessionTTLSeconds) * time.Second
+	}
+	return 12 * time.Hour
+}
+
+// setSessionCookies 写入会话 cookie（HttpOnly）与供前端读取的 CSRF cookie；maxAge<0 表示清除
+func setSessionCookies(c *gin.Context, session, csrf string, maxAge int) {
+	secure := c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+	if cfg := config.Get(); cfg != nil && cfg.SessionCookieSecure {
+		secure = true
+	}
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     SessionCookieName,
+		Value:    session,
+		Path:     "/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   secure,
+		SameSite: http.SameSiteStrictMode,
+	})
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     CSRFCookieName,
+		Value:    csrf,
+		Path:     "/",
+		MaxAge:   maxAge,
+		HttpOnly: false,
+		Secure:   secure,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+// requestUserID 返回会话登录用户的 ID（API Key 请求不存在）
+func requestUserID(c *gin.Context) (int64, bool) {
+	v, ok := c.Get("user_id")
+	if !ok {
+		return 0, false
+	}
+	id, ok := v.(int64)
+	return id, ok && id > 0
+}
+
+func requestSessionHash(c *gin.Context) string {
+	if v, ok := c.Get("session_hash"); ok {
+		if hash, ok := v.(string); ok {
+			return hash
+		}
+	}
+	return ""
+}
@@ -0,0 +1,201 @@
+package dnslog
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/genwilliam/dnslog_for_go/config"
+	"github.com/genwilliam/dnslog_for_go/pkg/log"
+	"github.com/genwilliam/dnslog_for_go/pkg/response"
+	"github.com/genwilliam/dnslog_for_go/pkg/smtpd"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// smtpExcerptLimit 交互记录中保存的正文摘录长度，完整报文通过 .eml 下载
+const smtpExcerptLimit = 4096
+
+var (
+	smtpCaptureMu     sync.Mutex
+	smtpCaptureServer *smtpd.Server
+)
+
+// smtpInteractionDetails SMTP 回连的结构化信息
+type smtpInteractionDetails struct {
+	Helo     string              `json:"helo"`
+	MailFrom string              `json:"mail_from"`
+	RcptTo   []string            `json:"rcpt_to"`
+	Subject  string              `json:"subject"`
+	Headers  map[string][]string `json:"headers"`
+	TLS      bool                `json:"tls"`
+}
+
+// StartSMTPCapture 启动 SMTP 捕获监听，接收发往 <任意>@<token>.<root> 的邮件；tlsCfg 非空时支持 STARTTLS
+func StartSMTPCapture(cfg *config.Config, tlsCfg *tls.Config) {
+	if cfg == nil || !cfg.SMTPCaptureEnabled || cfg.SMTPCaptureAddr == "" {
+		return
+	}
+	hostname := cfg.SMTPCaptureHostname
+	if hostname == "" {
+		hostname = strings.TrimSuffix(cfg.RootDomain, ".")
+	}
+	srv := &smtpd.Server{
+		Hostname: hostname,
+		MaxSize:  int64(cfg.SMTPCaptureMaxBytes),
+		AcceptConn: func(remote net.Addr) bool {
+			blocked, _ := IsIPBlacklisted(parseClientIP(remote), BlacklistScopeAll)
+			return !blocked
+		},
+		AcceptRcpt: func(_ net.Addr, rcpt string) bool {
+			_, _, ok := tokenFromMailbox(rcpt)
+			return ok
+		},
+		Handler: smtpCaptureHandler(cfg.SMTPCaptureAddr),
+	}
+	if tlsCfg != nil {
+		captureTLS := tlsCfg.Clone()
+		captureTLS.ClientAuth = tls.NoClientCert
+		captureTLS.ClientCAs = nil
+		srv.TLSConfig = captureTLS
+	}
+	smtpCaptureMu.Lock()
+	smtpCaptureServer = srv
+	smtpCaptureMu.Unlock()
+
+	go func() {
+		log.Info("SMTP capture listening", zap.String("addr", cfg.SMTPCaptureAddr), zap.Bool("starttls", srv.TLSConfig != nil))
+		if err := srv.ListenAndServe(cfg.SMTPCaptureAddr); err != nil && !errors.Is(err, smtpd.ErrServerClosed) {
+			log.Error("SMTP capture failed", zap.String("addr", cfg.SMTPCaptureAddr), zap.Error(err))
+		}
+	}()
+}
+
+// ShutdownSMTPCapture 关闭 SMTP 捕获监听
+func ShutdownSMTPCapture(ctx context.Context) {
+	smtpCaptureMu.Lock()
+	srv := smtpCaptureServer
+	smtpCaptureServer = nil
+	smtpCaptureMu.Unlock()
+	if srv == nil {
+		return
+	}
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Error("SMTP capture shutdown failed", zap.Error(err))
+	}
+}
+
+// smtpCaptureHandler 按 token 分组收件人，每个 token 记录一次交互；原始报文只随第一条保存，其余交互引用它
+func smtpCaptureHandler(listener string) func(env *smtpd.Envelope) error {
+	return func(env *smtpd.Envelope) error {
+		clientIP := parseClientIP(env.RemoteAddr)
+		createdAt := nowMillis()
+		headers, subject, excerpt := parseMailExcerpt(env.Data)
+
+		var tokens []string
+		rcpts := make(map[string][]string)
+		domains := make(map[string]string)
+		for _, rcpt := range env.To {
+			token, domain, ok := tokenFromMailbox(rcpt)
+			if !ok {
+				continue
+			}
+			if _, seen := rcpts[token]; !seen {
+				tokens = append(tokens, token)
+				domains[token] = domain
+			}
+			rcpts[token] = append(rcpts[token], rcpt)
+		}
+
+		var rawID int64
+		for _, token := range tokens {
+			details, _ := json.Marshal(smtpInteractionDetails{
+				Helo:     env.Helo,
+				MailFrom: env.From,
+				RcptTo:   rcpts[token],
+				Subject:  subject,
+				Headers:  headers,
+				TLS:      env.TLS,
+			})
+			it := Interaction{
+				Token:     token,
+				Protocol:  InteractionSMTP,
+				ClientIP:  clientIP,
+				Listener:  listener,
+				Summary:   "MAIL FROM:<" + env.From + "> TO:<" + strings.Join(rcpts[token], ",") + "> " + subject,
+				Details:   details,
+				Data:      excerpt,
+				DataSize:  env.Size,
+				CreatedAt: createdAt,
+			}
+			if rawID > 0 {
+				it.RawID = rawID
+			} else {
+				it.Raw = env.Data
+			}
+			if id := RecordInteraction(context.Background(), it, domains[token]); rawID == 0 {
+				rawID = id
+			}
+		}
+		return nil
+	}
+}
+
+// tokenFromMailbox 从 local@<token>.<root> 中提取 token
+func tokenFromMailbox(addr string) (string, string, bool) {
+	at := strings.LastIndexByte(addr, '@')
+	if at < 0 {
+		return "", "", false
+	}
+	return tokenFromHost(addr[at+1:])
+}
+
+// parseMailExcerpt 解析邮件头、解码后的主题与正文摘录；无法解析时整段作为正文
+func parseMailExcerpt(data []byte) (map[string][]string, string, string) {
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", strings.ToValidUTF8(truncate(string(data), smtpExcerptLimit), "")
+	}
+	subject := msg.Header.Get("Subject")
+	dec := new(mime.WordDecoder)
+	if decoded, err := dec.DecodeHeader(subject); err == nil {
+		subject = decoded
+	}
+	body, _ := io.ReadAll(io.LimitReader(msg.Body, smtpExcerptLimit))
+	return msg.Header, strings.ToValidUTF8(truncate(subject, 256), ""), strings.ToValidUTF8(string(body), "")
+}
+
+// GetInteractionRawHandler 下载 SMTP 交互的原始邮件（.eml）
+func GetInteractionRawHandler(c *gin.Context) {
+	token := c.Param("token")
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if token == "" || err != nil || id <= 0 {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest)
+		return
+	}
+	if !authorizeToken(c, token) {
+		return
+	}
+	owner, raw, err := GetInteractionRawWithContext(c.Request.Context(), id)
+	if err == ErrInteractionRawNotFound || (err == nil && owner != token) {
+		response.Error(c, http.StatusNotFound, response.CodeNotFound)
+		return
+	}
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError)
+		return
+	}
+	c.Header("Content-Disposition", `attachment; filename="`+token+"-"+strconv.FormatInt(id, 10)+`.eml"`)
+	c.Data(http.StatusOK, "message/rfc822", raw)
+}
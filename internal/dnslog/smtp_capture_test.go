@@ -0,0 +1,37 @@
+package dnslog
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenFromMailbox(t *testing.T) {
+	oldRoot := rootDomain
+	rootDomain = "demo.com"
+	t.Cleanup(func() { rootDomain = oldRoot })
+
+	token, domain, ok := tokenFromMailbox("Canary@ABC123.demo.com")
+	assert.True(t, ok)
+	assert.Equal(t, "abc123", token)
+	assert.Equal(t, "abc123.demo.com", domain)
+
+	for _, addr := range []string{"abc123@demo.com", "a@abc123.other.com", "postmaster", "a@[127.0.0.1]"} {
+		_, _, ok := tokenFromMailbox(addr)
+		assert.False(t, ok, addr)
+	}
+}
+
+func TestParseMailExcerpt(t *testing.T) {
+	raw := "From: a@b.com\r\nSubject: =?UTF-8?B?5rWL6K+V?=\r\n\r\n" + strings.Repeat("x", smtpExcerptLimit+10)
+	headers, subject, excerpt := parseMailExcerpt([]byte(raw))
+	assert.Equal(t, []string{"a@b.com"}, headers["From"])
+	assert.Equal(t, "测试", subject)
+	assert.Len(t, excerpt, smtpExcerptLimit)
+
+	headers, subject, excerpt = parseMailExcerpt([]byte("not a message"))
+	assert.Nil(t, headers)
+	assert.Empty(t, subject)
+	assert.Equal(t, "not a message", excerpt)
+}
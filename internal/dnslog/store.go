@@ -38,6 +38,8 @@ type ListFilter struct {
 
 	Start int64 // 起始时间戳（毫秒）
 	End   int64 // 结束时间戳（毫秒）
+
+	Tenant string // 非空时只返回该租户 token 下的记录
 }
 
 var db *sql.DB
@@ -94,6 +96,27 @@ func InitStore(dsn string) error {
 	if err := createWebhookJobsTable(conn); err != nil {
 		return err
 	}
+	if err := createUsersTables(conn); err != nil {
+		return err
+	}
+	if err := createAdminEventsTable(conn); err != nil {
+		return err
+	}
+	if err := createInteractionsTable(conn); err != nil {
+		return err
+	}
+	if err := createTokenHTTPResponsesTable(conn); err != nil {
+		return err
+	}
+	if err := createInteractionRawTable(conn); err != nil {
+		return err
+	}
+	if err := createInteractionBackfillTable(conn); err != nil {
+		return err
+	}
+	if err := prepareDNSBackfill(conn); err != nil {
+		return err
+	}
 
 	db = conn
 	return nil
@@ -139,10 +162,13 @@ CREATE TABLE IF NOT EXISTS dns_tokens (
     created_at  BIGINT NOT NULL,
     updated_at  BIGINT NOT NULL,
     expires_at  BIGINT NOT NULL,
+    owner_tenant VARCHAR(64) NOT NULL DEFAULT '',
+    owner_key_id BIGINT NOT NULL DEFAULT 0,
     INDEX idx_status (status),
     INDEX idx_expires (expires_at),
     INDEX idx_status_created (status, created_at),
-    INDEX idx_status_last (status, last_seen)
+    INDEX idx_status_last (status, last_seen),
+    INDEX idx_owner_created (owner_tenant, created_at)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
 `
 	if _, err := conn.Exec(schema); err != nil {
@@ -161,7 +187,12 @@ CREATE TABLE IF NOT EXISTS api_keys (
     created_at BIGINT NOT NULL,
     last_used_at BIGINT NOT NULL DEFAULT 0,
     comment VARCHAR(255) DEFAULT '',
-    INDEX idx_enabled (enabled)
+    scopes VARCHAR(512) NOT NULL DEFAULT '*',
+    expires_at BIGINT NOT NULL DEFAULT 0,
+    rotated_from BIGINT NOT NULL DEFAULT 0,
+    tenant VARCHAR(64) NOT NULL DEFAULT 'default',
+    INDEX idx_enabled (enabled),
+    INDEX idx_expires_at (expires_at)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
 `
 	if _, err := conn.Exec(schema); err != nil {
@@ -185,7 +216,9 @@ CREATE TABLE IF NOT EXISTS audit_logs (
     created_at BIGINT NOT NULL,
     INDEX idx_created (created_at),
     INDEX idx_path (path),
-    INDEX idx_ip (client_ip)
+    INDEX idx_ip (client_ip),
+    INDEX idx_api_key (api_key_id),
+    INDEX idx_token (token)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
 `
 	if _, err := conn.Exec(schema); err != nil {
@@ -198,11 +231,15 @@ func createIPBlacklistTable(conn *sql.DB) error {
 	schema := `
 CREATE TABLE IF NOT EXISTS ip_blacklist (
     id BIGINT AUTO_INCREMENT PRIMARY KEY,
-    ip VARCHAR(64) NOT NULL UNIQUE,
+    ip VARCHAR(64) NOT NULL,
     reason VARCHAR(255) DEFAULT '',
+    scope VARCHAR(16) NOT NULL DEFAULT 'all',
     enabled TINYINT NOT NULL DEFAULT 1,
+    expires_at BIGINT NOT NULL DEFAULT 0,
     created_at BIGINT NOT NULL,
-    INDEX idx_enabled (enabled)
+    UNIQUE KEY uniq_ip_scope (ip, scope),
+    INDEX idx_enabled (enabled),
+    INDEX idx_expires_at (expires_at)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
 `
 	if _, err := conn.Exec(schema); err != nil {
@@ -218,6 +255,11 @@ CREATE TABLE IF NOT EXISTS token_webhooks (
     token VARCHAR(128) NOT NULL,
     webhook_url VARCHAR(512) NOT NULL,
     secret VARCHAR(128) DEFAULT '',
+    prev_secret VARCHAR(128) DEFAULT '',
+    prev_secret_expires_at BIGINT NOT NULL DEFAULT 0,
+    headers TEXT NULL,
+    auth_type VARCHAR(16) NOT NULL DEFAULT 'none',
+    auth_credentials TEXT NULL,
     mode ENUM('FIRST_HIT','EACH_HIT') NOT NULL DEFAULT 'FIRST_HIT',
     enabled TINYINT NOT NULL DEFAULT 1,
     created_at BIGINT NOT NULL,
@@ -236,10 +278,12 @@ func createWebhookJobsTable(conn *sql.DB) error {
 	schema := `
 CREATE TABLE IF NOT EXISTS webhook_jobs (
     id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    kind VARCHAR(16) NOT NULL DEFAULT 'token',
     token VARCHAR(128) NOT NULL,
     url VARCHAR(512) NOT NULL,
     payload TEXT NOT NULL,
     secret VARCHAR(128) DEFAULT '',
+    prev_secret VARCHAR(128) DEFAULT '',
     status ENUM('PENDING','SUCCESS','FAILED') NOT NULL DEFAULT 'PENDING',
     retry_count INT NOT NULL DEFAULT 0,
     next_retry_at BIGINT NOT NULL DEFAULT 0,
@@ -254,6 +298,146 @@ CREATE TABLE IF NOT EXISTS webhook_jobs (
 	return nil
 }
 
+func createUsersTables(conn *sql.DB) error {
+	schema := `
+CREATE TABLE IF NOT EXISTS users (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    username VARCHAR(64) NOT NULL UNIQUE,
+    password_hash VARCHAR(128) NOT NULL,
+    roles VARCHAR(255) NOT NULL DEFAULT '',
+    tenant VARCHAR(64) NOT NULL DEFAULT 'default',
+    totp_secret VARCHAR(255) NOT NULL DEFAULT '',
+    totp_enabled TINYINT NOT NULL DEFAULT 0,
+    totp_last_step BIGINT NOT NULL DEFAULT 0,
+    enabled TINYINT NOT NULL DEFAULT 1,
+    created_at BIGINT NOT NULL,
+    last_login_at BIGINT NOT NULL DEFAULT 0,
+    oidc_subject VARCHAR(255) NULL,
+    UNIQUE KEY uk_oidc_subject (oidc_subject)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+`
+	if _, err := conn.Exec(schema); err != nil {
+		return fmt.Errorf("create table users: %w", err)
+	}
+	schema = `
+CREATE TABLE IF NOT EXISTS user_sessions (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    session_hash VARCHAR(128) NOT NULL UNIQUE,
+    user_id BIGINT NOT NULL,
+    csrf_token VARCHAR(64) NOT NULL,
+    client_ip VARCHAR(64) NOT NULL DEFAULT '',
+    user_agent VARCHAR(255) NOT NULL DEFAULT '',
+    created_at BIGINT NOT NULL,
+    expires_at BIGINT NOT NULL,
+    INDEX idx_user (user_id),
+    INDEX idx_expires_at (expires_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+`
+	if _, err := conn.Exec(schema); err != nil {
+		return fmt.Errorf("create table user_sessions: %w", err)
+	}
+	return nil
+}
+
+func createAdminEventsTable(conn *sql.DB) error {
+	schema := `
+CREATE TABLE IF NOT EXISTS admin_events (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    action VARCHAR(64) NOT NULL,
+    target VARCHAR(128) NOT NULL DEFAULT '',
+    actor_key_id BIGINT DEFAULT NULL,
+    actor_user_id BIGINT DEFAULT NULL,
+    actor_name VARCHAR(64) NOT NULL DEFAULT '',
+    tenant VARCHAR(64) NOT NULL DEFAULT '',
+    before_value TEXT,
+    after_value TEXT,
+    reason VARCHAR(255) NOT NULL DEFAULT '',
+    trace_id VARCHAR(64) NOT NULL DEFAULT '',
+    client_ip VARCHAR(64) NOT NULL DEFAULT '',
+    created_at BIGINT NOT NULL,
+    INDEX idx_created (created_at),
+    INDEX idx_action (action),
+    INDEX idx_actor_key (actor_key_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+`
+	if _, err := conn.Exec(schema); err != nil {
+		return fmt.Errorf("create table admin_events: %w", err)
+	}
+	return nil
+}
+
+func createInteractionsTable(conn *sql.DB) error {
+	schema := `
+CREATE TABLE IF NOT EXISTS interactions (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    token VARCHAR(128) NOT NULL,
+    protocol VARCHAR(16) NOT NULL,
+    client_ip VARCHAR(64) NOT NULL DEFAULT '',
+    listener VARCHAR(64) NOT NULL DEFAULT '',
+    summary VARCHAR(512) NOT NULL DEFAULT '',
+    details TEXT,
+    data BLOB,
+    data_size BIGINT NOT NULL DEFAULT 0,
+    raw_id BIGINT NOT NULL DEFAULT 0,
+    created_at BIGINT NOT NULL,
+    INDEX idx_token_created (token, created_at),
+    INDEX idx_created (created_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+`
+	if _, err := conn.Exec(schema); err != nil {
+		return fmt.Errorf("create table interactions: %w", err)
+	}
+	return nil
+}
+
+func createTokenHTTPResponsesTable(conn *sql.DB) error {
+	schema := `
+CREATE TABLE IF NOT EXISTS token_http_responses (
+    token VARCHAR(128) NOT NULL PRIMARY KEY,
+    status_code INT NOT NULL DEFAULT 200,
+    headers TEXT,
+    body MEDIUMBLOB,
+    redirect_url VARCHAR(2048) NOT NULL DEFAULT '',
+    created_at BIGINT NOT NULL,
+    updated_at BIGINT NOT NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+`
+	if _, err := conn.Exec(schema); err != nil {
+		return fmt.Errorf("create table token_http_responses: %w", err)
+	}
+	return nil
+}
+
+func createInteractionRawTable(conn *sql.DB) error {
+	schema := `
+CREATE TABLE IF NOT EXISTS interaction_raw (
+    interaction_id BIGINT NOT NULL PRIMARY KEY,
+    content MEDIUMBLOB NOT NULL,
+    created_at BIGINT NOT NULL,
+    INDEX idx_created (created_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+`
+	if _, err := conn.Exec(schema); err != nil {
+		return fmt.Errorf("create table interaction_raw: %w", err)
+	}
+	return nil
+}
+
+func createInteractionBackfillTable(conn *sql.DB) error {
+	schema := `
+CREATE TABLE IF NOT EXISTS interaction_backfill (
+    name VARCHAR(32) NOT NULL PRIMARY KEY,
+    last_id BIGINT NOT NULL DEFAULT 0,
+    max_id BIGINT NOT NULL DEFAULT 0,
+    updated_at BIGINT NOT NULL DEFAULT 0
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+`
+	if _, err := conn.Exec(schema); err != nil {
+		return fmt.Errorf("create table interaction_backfill: %w", err)
+	}
+	return nil
+}
+
 // AddRecord 持久化一条记录。
 func AddRecordWithContext(ctx context.Context, rec Record) error {
 	if db == nil {
@@ -317,6 +501,10 @@ func ListRecordsWithContext(ctx context.Context, filter ListFilter) ([]Record, i
 	addLike("token", filter.Token)
 	addEq("protocol", filter.Protocol)
 	addEq("qtype", filter.QType)
+	if filter.Tenant != "" {
+		where = append(where, "token IN (SELECT token FROM dns_tokens WHERE owner_tenant = ?)")
+		args = append(args, filter.Tenant)
+	}
 
 	if filter.Start > 0 {
 		where = append(where, "timestamp >= ?")
@@ -382,6 +570,32 @@ func ListRecords(filter ListFilter) ([]Record, int, error) {
 	return ListRecordsWithContext(context.Background(), filter)
 }
 
+// LatestTokenRecordByClientIPWithContext 返回该 IP 在 since 之后最近一次命中 token 的 DNS 记录
+func LatestTokenRecordByClientIPWithContext(ctx context.Context, clientIP string, since int64) (Record, error) {
+	if db == nil {
+		return Record{}, errors.New("store not initialized")
+	}
+	ctx = ensureContext(ctx)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	var rec Record
+	err := db.QueryRowContext(ctx, `
+SELECT id, domain, client_ip, protocol, qtype, timestamp, server, token
+FROM dns_records
+WHERE client_ip = ? AND timestamp >= ? AND token NOT IN ('', '(none)')
+ORDER BY timestamp DESC
+LIMIT 1
+`, clientIP, since).Scan(&rec.ID, &rec.Domain, &rec.ClientIP, &rec.Protocol, &rec.QType, &rec.Timestamp, &rec.Server, &rec.Token)
+	if err != nil {
+		return Record{}, err
+	}
+	return rec, nil
+}
+
+func LatestTokenRecordByClientIP(clientIP string, since int64) (Record, error) {
+	return LatestTokenRecordByClientIPWithContext(context.Background(), clientIP, since)
+}
+
 // nowMillis 返回当前时间的毫秒时间戳
 func nowMillis() int64 {
 	return time.Now().UnixMilli()
@@ -0,0 +1,152 @@
+package dnslog
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/genwilliam/dnslog_for_go/pkg/log"
+
+	"go.uber.org/zap"
+)
+
+const (
+	tcpCaptureMaxConns      = 1024 // 单个监听同时处理的连接上限，超出时直接断开
+	tcpCaptureMaxConnsPerIP = 16   // 同一来源 IP 同时处理的连接上限
+)
+
+// tcpCaptureListener 一个 TCP 回连捕获监听（LDAP、RMI 等），serve 处理单个连接，返回后连接被关闭
+type tcpCaptureListener struct {
+	name  string
+	ln    net.Listener
+	serve func(conn net.Conn)
+
+	mu     sync.Mutex
+	conns  map[net.Conn]struct{}
+	perIP  map[string]int
+	closed bool
+	wg     sync.WaitGroup
+}
+
+var (
+	tcpCaptureMu        sync.Mutex
+	tcpCaptureListeners []*tcpCaptureListener
+)
+
+// startTCPCapture 在 addr 上监听；all 作用域黑名单中的 IP 直接断开
+func startTCPCapture(name, addr string, serve func(conn net.Conn)) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.Error("capture listen failed", zap.String("listener", name), zap.String("addr", addr), zap.Error(err))
+		return
+	}
+	l := &tcpCaptureListener{name: name, ln: ln, serve: serve, conns: make(map[net.Conn]struct{}), perIP: make(map[string]int)}
+	tcpCaptureMu.Lock()
+	tcpCaptureListeners = append(tcpCaptureListeners, l)
+	tcpCaptureMu.Unlock()
+
+	log.Info("capture listening", zap.String("listener", name), zap.String("addr", ln.Addr().String()))
+	go l.acceptLoop()
+}
+
+func (l *tcpCaptureListener) acceptLoop() {
+	for {
+		conn, err := l.ln.Accept()
+		if err != nil {
+			l.mu.Lock()
+			closed := l.closed
+			l.mu.Unlock()
+			if closed {
+				return
+			}
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() {
+				time.Sleep(50 * time.Millisecond)
+				continue
+			}
+			log.Error("capture accept failed", zap.String("listener", l.name), zap.Error(err))
+			return
+		}
+		clientIP := parseClientIP(conn.RemoteAddr())
+		if blocked, _ := IsIPBlacklisted(clientIP, BlacklistScopeAll); blocked {
+			conn.Close()
+			continue
+		}
+		admitted, closed := l.track(conn, clientIP)
+		if closed {
+			conn.Close()
+			return
+		}
+		if !admitted {
+			conn.Close()
+			continue
+		}
+
+		go func() {
+			defer l.untrack(conn, clientIP)
+			l.serve(conn)
+		}()
+	}
+}
+
+// track 登记连接；超过总数或单 IP 并发上限时 admitted 为 false，监听已关闭时 closed 为 true
+func (l *tcpCaptureListener) track(conn net.Conn, clientIP string) (admitted, closed bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return false, true
+	}
+	if len(l.conns) >= tcpCaptureMaxConns || l.perIP[clientIP] >= tcpCaptureMaxConnsPerIP {
+		return false, false
+	}
+	l.conns[conn] = struct{}{}
+	l.perIP[clientIP]++
+	l.wg.Add(1)
+	return true, false
+}
+
+func (l *tcpCaptureListener) untrack(conn net.Conn, clientIP string) {
+	conn.Close()
+	l.mu.Lock()
+	delete(l.conns, conn)
+	if l.perIP[clientIP]--; l.perIP[clientIP] <= 0 {
+		delete(l.perIP, clientIP)
+	}
+	l.mu.Unlock()
+	l.wg.Done()
+}
+
+func (l *tcpCaptureListener) shutdown(ctx context.Context) {
+	l.mu.Lock()
+	l.closed = true
+	l.ln.Close()
+	l.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		l.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		l.mu.Lock()
+		for c := range l.conns {
+			c.Close()
+		}
+		l.mu.Unlock()
+	}
+}
+
+// ShutdownTCPCapture 关闭所有 TCP 回连捕获监听，ctx 到期后强制断开剩余连接
+func ShutdownTCPCapture(ctx context.Context) {
+	tcpCaptureMu.Lock()
+	listeners := tcpCaptureListeners
+	tcpCaptureListeners = nil
+	tcpCaptureMu.Unlock()
+	for _, l := range listeners {
+		l.shutdown(ctx)
+	}
+}
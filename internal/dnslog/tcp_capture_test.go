@@ -0,0 +1,33 @@
+package dnslog
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTCPCaptureTrackLimitsPerIP(t *testing.T) {
+	l := &tcpCaptureListener{conns: make(map[net.Conn]struct{}), perIP: make(map[string]int)}
+	var conns []net.Conn
+	for i := 0; i < tcpCaptureMaxConnsPerIP; i++ {
+		c, _ := net.Pipe()
+		admitted, closed := l.track(c, "1.2.3.4")
+		assert.True(t, admitted)
+		assert.False(t, closed)
+		conns = append(conns, c)
+	}
+	extra, _ := net.Pipe()
+	admitted, _ := l.track(extra, "1.2.3.4")
+	assert.False(t, admitted, "同一 IP 超过并发上限")
+	admitted, _ = l.track(extra, "5.6.7.8")
+	assert.True(t, admitted, "其他 IP 不受影响")
+
+	l.untrack(conns[0], "1.2.3.4")
+	admitted, _ = l.track(conns[0], "1.2.3.4")
+	assert.True(t, admitted, "连接结束后释放名额")
+
+	l.closed = true
+	_, closed := l.track(conns[1], "9.9.9.9")
+	assert.True(t, closed)
+}
@@ -0,0 +1,99 @@
+package dnslog
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/genwilliam/dnslog_for_go/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultTenant 未指定租户时 API Key 所属的租户
+const DefaultTenant = "default"
+
+const maxTenantLength = 64
+
+var ErrInvalidTenant = errors.New("invalid_tenant")
+
+// TokenOwner token 的归属；Tenant 为空表示无归属（如直接被 DNS 命中的 token），仅管理员可见
+type TokenOwner struct {
+	Tenant string
+	KeyID  int64
+}
+
+// ValidTenant 租户名：1~64 位小写字母、数字、-、_
+func ValidTenant(tenant string) bool {
+	if tenant == "" || len(tenant) > maxTenantLength {
+		return false
+	}
+	for _, ch := range tenant {
+		if (ch >= 'a' && ch <= 'z') || (ch >= '0' && ch <= '9') || ch == '-' || ch == '_' {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+// RequestTenant 返回请求方所属租户；all=true 表示可跨租户访问（admin:tenants 或未启用 API Key 鉴权）
+func RequestTenant(c *gin.Context) (tenant string, all bool) {
+	scopes, ok := callerAPIKeyScopes(c)
+	if !ok || HasScope(scopes, ScopeAdminTenants) {
+		return "", true
+	}
+	if v, ok := c.Get("api_key_tenant"); ok {
+		if t, ok := v.(string); ok && t != "" {
+			return t, false
+		}
+	}
+	return DefaultTenant, false
+}
+
+// canAccessTenant 请求方能否访问属于 owner 租户的数据
+func canAccessTenant(c *gin.Context, owner string) bool {
+	tenant, all := RequestTenant(c)
+	return all || owner == tenant
+}
+
+// RequestTokenOwner 返回当前请求创建 token 时应记录的归属
+func RequestTokenOwner(c *gin.Context) TokenOwner {
+	owner := TokenOwner{}
+	if v, ok := c.Get("api_key_id"); ok {
+		if id, ok := v.(int64); ok {
+			owner.KeyID = id
+		}
+	}
+	if v, ok := c.Get("api_key_tenant"); ok {
+		if t, ok := v.(string); ok {
+			owner.Tenant = t
+		}
+	}
+	return owner
+}
+
+// RequestListTenant 返回列表查询使用的租户过滤值，跨租户访问时为空
+func RequestListTenant(c *gin.Context) string {
+	tenant, all := RequestTenant(c)
+	if all {
+		return ""
+	}
+	return tenant
+}
+
+// authorizeToken 校验请求方能否访问 token；不属于本租户时按不存在处理，避免泄露 token 是否存在
+func authorizeToken(c *gin.Context, token string) bool {
+	if _, all := RequestTenant(c); all {
+		return true
+	}
+	ts, err := GetTokenStatusWithContext(c.Request.Context(), token)
+	if err == nil && canAccessTenant(c, ts.Tenant) {
+		return true
+	}
+	if err != nil && err != ErrTokenNotFound {
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError)
+		return false
+	}
+	response.Error(c, http.StatusNotFound, response.CodeTokenNotFound)
+	return false
+}
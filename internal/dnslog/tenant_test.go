@@ -0,0 +1,50 @@
+package dnslog
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidTenant(t *testing.T) {
+	assert.True(t, ValidTenant("team-a_1"))
+	assert.False(t, ValidTenant(""))
+	assert.False(t, ValidTenant("Team"))
+	assert.False(t, ValidTenant("a b"))
+}
+
+func TestRequestTenant(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	tenant, all := RequestTenant(c)
+	assert.True(t, all, "auth disabled sees everything")
+	assert.Equal(t, "", tenant)
+
+	c.Set("api_key_scopes", []string{ScopeTokensWrite})
+	c.Set("api_key_tenant", "team-a")
+	tenant, all = RequestTenant(c)
+	assert.False(t, all)
+	assert.Equal(t, "team-a", tenant)
+	assert.Equal(t, "team-a", RequestListTenant(c))
+
+	c.Set("api_key_scopes", []string{ScopeAdminTenants})
+	_, all = RequestTenant(c)
+	assert.True(t, all)
+	assert.Equal(t, "", RequestListTenant(c))
+}
+
+func TestStarKeyIsTenantScoped(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Set("api_key_scopes", []string{ScopeAll})
+	c.Set("api_key_tenant", "team-a")
+	tenant, all := RequestTenant(c)
+	assert.False(t, all, "\"*\" 不再隐含 admin:tenants")
+	assert.Equal(t, "team-a", tenant)
+	assert.True(t, canAccessTenant(c, "team-a"))
+	assert.False(t, canAccessTenant(c, "team-b"), "租户 A 的 * key 不能读取租户 B 的 token")
+	assert.False(t, canAccessTenant(c, ""), "无归属 token 仅跨租户管理员可见")
+
+	c.Set("api_key_scopes", []string{ScopeAll, ScopeAdminTenants})
+	assert.True(t, canAccessTenant(c, "team-b"))
+}
@@ -20,6 +20,9 @@ func GetTokenStatusHandler(c *gin.Context) {
 	}
 
 	ts, err := GetTokenStatusWithContext(c.Request.Context(), token)
+	if err == nil && !canAccessTenant(c, ts.Tenant) {
+		err = ErrTokenNotFound
+	}
 	if err == ErrTokenNotFound {
 		response.Error(c, http.StatusNotFound, response.CodeTokenNotFound)
 		return
@@ -57,6 +60,9 @@ func GetTokenRecordsHandler(c *gin.Context) {
 		response.Error(c, http.StatusBadRequest, response.CodeBadRequest)
 		return
 	}
+	if !authorizeToken(c, token) {
+		return
+	}
 
 	pageStr := c.DefaultQuery("page", "1")
 	pageSizeStr := c.DefaultQuery("pageSize", strconv.Itoa(cfg.DefaultPageSize))
@@ -122,6 +128,7 @@ func ListTokensHandler(c *gin.Context) {
 		Status:      c.Query("status"),
 		Order:       order,
 		OrderBy:     orderBy,
+		Tenant:      RequestListTenant(c),
 	}
 	if v := c.Query("keyword"); v != "" {
 		filter.Keyword = v
@@ -0,0 +1,221 @@
+package dnslog
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/genwilliam/dnslog_for_go/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	maxTokenResponseBody    = 1 << 20
+	maxTokenResponseHeaders = 20
+	maxTokenRedirectURL     = 2048
+)
+
+var ErrTokenResponseInvalid = errors.New("token_response_invalid")
+
+// reservedTokenResponseHeaders 由 HTTP 服务自身管理；跳转地址通过 redirect_url 设置。
+// Set-Cookie 可带 Domain=<root> 写入控制台所在域，禁止设置以免篡改控制台会话
+var reservedTokenResponseHeaders = map[string]struct{}{
+	"Content-Length":    {},
+	"Transfer-Encoding": {},
+	"Connection":        {},
+	"Keep-Alive":        {},
+	"Te":                {},
+	"Trailer":           {},
+	"Upgrade":           {},
+	"Date":              {},
+	"Location":          {},
+	"Set-Cookie":        {},
+}
+
+// tokenHTTPResponseRequest 设置自定义响应的请求体；body 与 body_base64 二选一
+type tokenHTTPResponseRequest struct {
+	StatusCode  int               `json:"status_code"`
+	Headers     map[string]string `json:"headers"`
+	Body        string            `json:"body"`
+	BodyBase64  string            `json:"body_base64"`
+	RedirectURL string            `json:"redirect_url"`
+}
+
+// normalizeTokenHTTPResponse 校验请求并补全默认状态码（跳转 302，其余 200）
+func normalizeTokenHTTPResponse(token string, req tokenHTTPResponseRequest) (TokenHTTPResponse, error) {
+	resp := TokenHTTPResponse{Token: token, StatusCode: req.StatusCode, RedirectURL: strings.TrimSpace(req.RedirectURL)}
+
+	switch {
+	case req.Body != "" && req.BodyBase64 != "":
+		return TokenHTTPResponse{}, ErrTokenResponseInvalid
+	case req.BodyBase64 != "":
+		body, err := base64.StdEncoding.DecodeString(req.BodyBase64)
+		if err != nil {
+			return TokenHTTPResponse{}, ErrTokenResponseInvalid
+		}
+		resp.Body = body
+	case req.Body != "":
+		resp.Body = []byte(req.Body)
+	}
+	if len(resp.Body) > maxTokenResponseBody {
+		return TokenHTTPResponse{}, ErrTokenResponseInvalid
+	}
+
+	if resp.RedirectURL != "" {
+		u, err := url.Parse(resp.RedirectURL)
+		if err != nil || !u.IsAbs() || len(resp.RedirectURL) > maxTokenRedirectURL || strings.ContainsAny(resp.RedirectURL, "\r\n\x00") {
+			return TokenHTTPResponse{}, ErrTokenResponseInvalid
+		}
+		if resp.StatusCode == 0 {
+			resp.StatusCode = http.StatusFound
+		}
+		switch resp.StatusCode {
+		case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		default:
+			return TokenHTTPResponse{}, ErrTokenResponseInvalid
+		}
+	} else {
+		if resp.StatusCode == 0 {
+			resp.StatusCode = http.StatusOK
+		}
+		if resp.StatusCode < 200 || resp.StatusCode > 599 {
+			return TokenHTTPResponse{}, ErrTokenResponseInvalid
+		}
+	}
+
+	if len(req.Headers) > maxTokenResponseHeaders {
+		return TokenHTTPResponse{}, ErrTokenResponseInvalid
+	}
+	for name, val := range req.Headers {
+		name = strings.TrimSpace(name)
+		if !isHeaderToken(name) || len(val) > maxWebhookHeaderValue || strings.ContainsAny(val, "\r\n\x00") {
+			return TokenHTTPResponse{}, ErrTokenResponseInvalid
+		}
+		canonical := http.CanonicalHeaderKey(name)
+		if _, reserved := reservedTokenResponseHeaders[canonical]; reserved {
+			return TokenHTTPResponse{}, ErrTokenResponseInvalid
+		}
+		if resp.Headers == nil {
+			resp.Headers = make(map[string]string, len(req.Headers))
+		}
+		resp.Headers[canonical] = val
+	}
+	return resp, nil
+}
+
+// writeTokenHTTPResponse 按 token 配置写出回连响应；未设置 Content-Type 时由 net/http 根据内容推断
+func writeTokenHTTPResponse(w http.ResponseWriter, resp TokenHTTPResponse) {
+	for name, val := range resp.Headers {
+		// 跳过保留头：修复前保存的响应可能仍包含 Set-Cookie
+		if _, reserved := reservedTokenResponseHeaders[http.CanonicalHeaderKey(name)]; reserved {
+			continue
+		}
+		w.Header().Set(name, val)
+	}
+	if resp.RedirectURL != "" {
+		w.Header().Set("Location", resp.RedirectURL)
+	}
+	w.WriteHeader(resp.StatusCode)
+	_, _ = w.Write(resp.Body)
+}
+
+func tokenHTTPResponseView(resp TokenHTTPResponse) gin.H {
+	view := gin.H{
+		"token":        resp.Token,
+		"status_code":  resp.StatusCode,
+		"headers":      resp.Headers,
+		"redirect_url": resp.RedirectURL,
+		"body_size":    len(resp.Body),
+		"created_at":   resp.CreatedAt,
+		"updated_at":   resp.UpdatedAt,
+	}
+	if utf8.Valid(resp.Body) {
+		view["body"] = string(resp.Body)
+	} else {
+		view["body_base64"] = base64.StdEncoding.EncodeToString(resp.Body)
+	}
+	return view
+}
+
+// SetTokenHTTPResponseHandler 设置 token 在 HTTP 回连监听上返回的内容
+func SetTokenHTTPResponseHandler(c *gin.Context) {
+	token := c.Param("token")
+	if token == "" {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest)
+		return
+	}
+	if !authorizeToken(c, token) {
+		return
+	}
+
+	var req tokenHTTPResponseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest)
+		return
+	}
+	resp, err := normalizeTokenHTTPResponse(token, req)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, response.CodeTokenResponseInvalid)
+		return
+	}
+	now := time.Now().UnixMilli()
+	if err := UpsertTokenHTTPResponseWithContext(c.Request.Context(), resp, now); err != nil {
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError)
+		return
+	}
+	saved, err := GetTokenHTTPResponseWithContext(c.Request.Context(), token)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError)
+		return
+	}
+	response.Success(c, tokenHTTPResponseView(saved))
+}
+
+// GetTokenHTTPResponseHandler 获取 token 的自定义 HTTP 响应
+func GetTokenHTTPResponseHandler(c *gin.Context) {
+	token := c.Param("token")
+	if token == "" {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest)
+		return
+	}
+	if !authorizeToken(c, token) {
+		return
+	}
+	resp, err := GetTokenHTTPResponseWithContext(c.Request.Context(), token)
+	if err == ErrTokenResponseNotFound {
+		response.Error(c, http.StatusNotFound, response.CodeNotFound)
+		return
+	}
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError)
+		return
+	}
+	response.Success(c, tokenHTTPResponseView(resp))
+}
+
+// DeleteTokenHTTPResponseHandler 删除自定义响应，恢复默认的 200 空响应
+func DeleteTokenHTTPResponseHandler(c *gin.Context) {
+	token := c.Param("token")
+	if token == "" {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest)
+		return
+	}
+	if !authorizeToken(c, token) {
+		return
+	}
+	deleted, err := DeleteTokenHTTPResponseWithContext(c.Request.Context(), token)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError)
+		return
+	}
+	if !deleted {
+		response.Error(c, http.StatusNotFound, response.CodeNotFound)
+		return
+	}
+	response.Success(c, gin.H{"token": token, "deleted": true})
+}
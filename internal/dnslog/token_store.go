@@ -12,15 +12,17 @@ import (
 )
 
 type TokenStatus struct {
-	Token     string `json:"token"`
-	Domain    string `json:"domain"`
-	Status    string `json:"status"`
-	FirstSeen int64  `json:"first_seen"`
-	LastSeen  int64  `json:"last_seen"`
-	HitCount  int64  `json:"hit_count"`
-	CreatedAt int64  `json:"created_at"`
-	UpdatedAt int64  `json:"updated_at"`
-	ExpiresAt int64  `json:"expires_at"`
+	Token      string `json:"token"`
+	Domain     string `json:"domain"`
+	Status     string `json:"status"`
+	FirstSeen  int64  `json:"first_seen"`
+	LastSeen   int64  `json:"last_seen"`
+	HitCount   int64  `json:"hit_count"`
+	CreatedAt  int64  `json:"created_at"`
+	UpdatedAt  int64  `json:"updated_at"`
+	ExpiresAt  int64  `json:"expires_at"`
+	Tenant     string `json:"tenant"`
+	OwnerKeyID int64  `json:"owner_key_id"`
 }
 
 type TokenListFilter struct {
@@ -34,11 +36,12 @@ type TokenListFilter struct {
 	Keyword     string
 	OrderBy     string
 	Order       string
+	Tenant      string // 非空时只返回该租户的 token
 }
 
 var ErrTokenNotFound = errors.New("token_not_found")
 
-func CreateTokenInitWithContext(ctx context.Context, token, domain string, nowMs, expiresAtMs int64) error {
+func CreateTokenInitWithContext(ctx context.Context, token, domain string, owner TokenOwner, nowMs, expiresAtMs int64) error {
 	if db == nil {
 		return errors.New("store not initialized")
 	}
@@ -47,17 +50,17 @@ func CreateTokenInitWithContext(ctx context.Context, token, domain string, nowMs
 	defer cancel()
 
 	_, err := db.ExecContext(ctx, `
-INSERT INTO dns_tokens (token, domain, status, hit_count, first_seen, last_seen, created_at, updated_at, expires_at)
-VALUES (?, ?, 'INIT', 0, 0, 0, ?, ?, ?)
-`, token, domain, nowMs, nowMs, expiresAtMs)
+INSERT INTO dns_tokens (token, domain, status, hit_count, first_seen, last_seen, created_at, updated_at, expires_at, owner_tenant, owner_key_id)
+VALUES (?, ?, 'INIT', 0, 0, 0, ?, ?, ?, ?, ?)
+`, token, domain, nowMs, nowMs, expiresAtMs, owner.Tenant, owner.KeyID)
 	if isDuplicateKey(err) {
 		return fmt.Errorf("token exists: %w", err)
 	}
 	return err
 }
 
-func CreateTokenInit(token, domain string, nowMs, expiresAtMs int64) error {
-	return CreateTokenInitWithContext(context.Background(), token, domain, nowMs, expiresAtMs)
+func CreateTokenInit(token, domain string, owner TokenOwner, nowMs, expiresAtMs int64) error {
+	return CreateTokenInitWithContext(context.Background(), token, domain, owner, nowMs, expiresAtMs)
 }
 
 func UpsertTokenHitWithContext(ctx context.Context, token, domain string, nowMs, ttlMs int64) (bool, error) {
@@ -112,10 +115,10 @@ func GetTokenStatusWithContext(ctx context.Context, token string) (TokenStatus,
 
 	var ts TokenStatus
 	err := db.QueryRowContext(ctx, `
-SELECT token, domain, status, first_seen, last_seen, hit_count, created_at, updated_at, expires_at
+SELECT token, domain, status, first_seen, last_seen, hit_count, created_at, updated_at, expires_at, owner_tenant, owner_key_id
 FROM dns_tokens
 WHERE token = ?
-`, token).Scan(&ts.Token, &ts.Domain, &ts.Status, &ts.FirstSeen, &ts.LastSeen, &ts.HitCount, &ts.CreatedAt, &ts.UpdatedAt, &ts.ExpiresAt)
+`, token).Scan(&ts.Token, &ts.Domain, &ts.Status, &ts.FirstSeen, &ts.LastSeen, &ts.HitCount, &ts.CreatedAt, &ts.UpdatedAt, &ts.ExpiresAt, &ts.Tenant, &ts.OwnerKeyID)
 	if errors.Is(err, sql.ErrNoRows) {
 		return TokenStatus{}, ErrTokenNotFound
 	}
@@ -221,6 +224,10 @@ func ListTokensWithContext(ctx context.Context, filter TokenListFilter) ([]Token
 		where = append(where, "status = ?")
 		args = append(args, filter.Status)
 	}
+	if filter.Tenant != "" {
+		where = append(where, "owner_tenant = ?")
+		args = append(args, filter.Tenant)
+	}
 	if filter.Keyword != "" {
 		where = append(where, "(token LIKE ? OR domain LIKE ?)")
 		kw := "%" + filter.Keyword + "%"
@@ -256,7 +263,7 @@ func ListTokensWithContext(ctx context.Context, filter TokenListFilter) ([]Token
 
 	offset := (filter.Page - 1) * filter.PageSize
 	querySQL := `
-SELECT token, domain, status, first_seen, last_seen, hit_count, created_at, updated_at, expires_at
+SELECT token, domain, status, first_seen, last_seen, hit_count, created_at, updated_at, expires_at, owner_tenant, owner_key_id
 FROM dns_tokens
 ` + whereSQL + `
 ORDER BY ` + orderBy + ` ` + order + `
@@ -272,7 +279,7 @@ LIMIT ? OFFSET ?`
 	var items []TokenStatus
 	for rows.Next() {
 		var ts TokenStatus
-		if err := rows.Scan(&ts.Token, &ts.Domain, &ts.Status, &ts.FirstSeen, &ts.LastSeen, &ts.HitCount, &ts.CreatedAt, &ts.UpdatedAt, &ts.ExpiresAt); err != nil {
+		if err := rows.Scan(&ts.Token, &ts.Domain, &ts.Status, &ts.FirstSeen, &ts.LastSeen, &ts.HitCount, &ts.CreatedAt, &ts.UpdatedAt, &ts.ExpiresAt, &ts.Tenant, &ts.OwnerKeyID); err != nil {
 			return nil, 0, fmt.Errorf("scan token: %w", err)
 		}
 		items = append(items, ts)
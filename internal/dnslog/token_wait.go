@@ -0,0 +1,229 @@
+package dnslog
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/genwilliam/dnslog_for_go/config"
+	"github.com/genwilliam/dnslog_for_go/internal/infra"
+	"github.com/genwilliam/dnslog_for_go/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// tokenHitChannel 多实例间广播 token 命中，消息为 "<实例 id> <token>"
+	tokenHitChannel = "token:hit"
+	// tokenWaitRecheck 未收到通知时的兜底复查间隔，覆盖 Redis 消息丢失的情况
+	tokenWaitRecheck  = 5 * time.Second
+	tokenWaitDefault  = 30 * time.Second
+	maxTokenWaiters   = 10000
+	tokenWaitDeadline = 5 * time.Second // 写超时在等待时间之外预留的余量
+)
+
+// hitNotifier 进程内按 token 唤醒等待者；通道带 1 个缓冲，通知不阻塞命中路径
+type hitNotifier struct {
+	mu      sync.Mutex
+	waiters map[string]map[chan struct{}]struct{}
+	count   int
+}
+
+var (
+	tokenHits  = &hitNotifier{waiters: make(map[string]map[chan struct{}]struct{})}
+	instanceID = newInstanceID()
+)
+
+func newInstanceID() string {
+	raw := make([]byte, 8)
+	_, _ = rand.Read(raw)
+	return hex.EncodeToString(raw)
+}
+
+// subscribe 注册等待者，超过上限时返回 false；调用方必须调用返回的 cancel
+func (n *hitNotifier) subscribe(token string) (<-chan struct{}, func(), bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.count >= maxTokenWaiters {
+		return nil, nil, false
+	}
+	ch := make(chan struct{}, 1)
+	set := n.waiters[token]
+	if set == nil {
+		set = make(map[chan struct{}]struct{})
+		n.waiters[token] = set
+	}
+	set[ch] = struct{}{}
+	n.count++
+	cancel := func() {
+		n.mu.Lock()
+		defer n.mu.Unlock()
+		if _, ok := n.waiters[token][ch]; !ok {
+			return
+		}
+		delete(n.waiters[token], ch)
+		if len(n.waiters[token]) == 0 {
+			delete(n.waiters, token)
+		}
+		n.count--
+	}
+	return ch, cancel, true
+}
+
+func (n *hitNotifier) notify(token string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for ch := range n.waiters[token] {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// publishTokenHit 唤醒本实例的等待者，并通过 Redis 通知其他实例
+func publishTokenHit(token string) {
+	tokenHits.notify(token)
+	if client := infra.GetRedis(); client != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+		_ = client.Publish(ctx, tokenHitChannel, instanceID+" "+token).Err()
+	}
+}
+
+// parseTokenHitMessage 解析其他实例的命中通知，忽略本实例发出的消息
+func parseTokenHitMessage(payload string) (string, bool) {
+	from, token, ok := strings.Cut(payload, " ")
+	if !ok || from == instanceID || token == "" {
+		return "", false
+	}
+	return token, true
+}
+
+// StartTokenHitSync Redis 可用时订阅其他实例的 token 命中通知
+func StartTokenHitSync() {
+	client := infra.GetRedis()
+	if client == nil {
+		return
+	}
+	go func() {
+		sub := client.Subscribe(context.Background(), tokenHitChannel)
+		for msg := range sub.Channel() {
+			if token, ok := parseTokenHitMessage(msg.Payload); ok {
+				tokenHits.notify(token)
+			}
+		}
+	}()
+}
+
+// parseWaitTimeout 支持 Go 时长（30s、1m）或秒数
+func parseWaitTimeout(v string, max time.Duration) (time.Duration, bool) {
+	if v == "" {
+		return min(tokenWaitDefault, max), true
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		n, nerr := strconv.Atoi(v)
+		if nerr != nil {
+			return 0, false
+		}
+		d = time.Duration(n) * time.Second
+	}
+	if d < 0 {
+		return 0, false
+	}
+	return min(d, max), true
+}
+
+// WaitTokenHitHandler 长轮询：hit_count 达到 min_hits 或超时后返回 token 状态；
+// 等待期间只监听通知，不占用数据库连接，收到通知后再查一次状态
+func WaitTokenHitHandler(c *gin.Context) {
+	token := c.Param("token")
+	if token == "" {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest)
+		return
+	}
+	maxWait := 60 * time.Second
+	if cfg := config.Get(); cfg != nil && cfg.TokenWaitMaxSeconds > 0 {
+		maxWait = time.Duration(cfg.TokenWaitMaxSeconds) * time.Second
+	}
+	timeout, ok := parseWaitTimeout(c.Query("timeout"), maxWait)
+	if !ok {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest)
+		return
+	}
+	minHits := int64(1)
+	if v := c.Query("min_hits"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 1 {
+			response.Error(c, http.StatusBadRequest, response.CodeBadRequest)
+			return
+		}
+		minHits = n
+	}
+	if !authorizeToken(c, token) {
+		return
+	}
+
+	// 先订阅再查询，避免两者之间的命中被漏掉
+	notified, cancel, ok := tokenHits.subscribe(token)
+	if !ok {
+		response.Error(c, http.StatusServiceUnavailable, response.CodeRateLimited)
+		return
+	}
+	defer cancel()
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Now().Add(timeout + tokenWaitDeadline))
+
+	ctx := c.Request.Context()
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	recheck := time.NewTicker(tokenWaitRecheck)
+	defer recheck.Stop()
+	for {
+		ts, err := GetTokenStatusWithContext(ctx, token)
+		if err == ErrTokenNotFound {
+			response.Error(c, http.StatusNotFound, response.CodeTokenNotFound)
+			return
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			response.Error(c, http.StatusInternalServerError, response.CodeInternalError)
+			return
+		}
+		hit := ts.HitCount >= minHits
+		if hit || ts.Status == "EXPIRED" {
+			writeTokenWaitResult(c, ts, hit)
+			return
+		}
+		select {
+		case <-notified:
+		case <-recheck.C:
+		case <-deadline.C:
+			writeTokenWaitResult(c, ts, false)
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func writeTokenWaitResult(c *gin.Context, ts TokenStatus, hit bool) {
+	response.Success(c, gin.H{
+		"token":      ts.Token,
+		"domain":     ts.Domain,
+		"status":     ts.Status,
+		"first_seen": ts.FirstSeen,
+		"last_seen":  ts.LastSeen,
+		"hit_count":  ts.HitCount,
+		"expires_at": ts.ExpiresAt,
+		"expired":    ts.Status == "EXPIRED",
+		"hit":        hit,
+	})
+}
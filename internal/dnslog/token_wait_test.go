@@ -0,0 +1,65 @@
+package dnslog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHitNotifier(t *testing.T) {
+	n := &hitNotifier{waiters: make(map[string]map[chan struct{}]struct{})}
+	ch, cancel, ok := n.subscribe("abc123")
+	require.True(t, ok)
+
+	n.notify("other")
+	n.notify("abc123")
+	n.notify("abc123") // 缓冲已满时不阻塞
+	select {
+	case <-ch:
+	case <-time.After(time.Second):
+		t.Fatal("waiter not notified")
+	}
+	select {
+	case <-ch:
+		t.Fatal("notifications should coalesce")
+	default:
+	}
+
+	cancel()
+	cancel()
+	assert.Empty(t, n.waiters)
+	assert.Equal(t, 0, n.count)
+}
+
+func TestParseTokenHitMessage(t *testing.T) {
+	token, ok := parseTokenHitMessage("remote01 abc123")
+	assert.True(t, ok)
+	assert.Equal(t, "abc123", token)
+
+	_, ok = parseTokenHitMessage(instanceID + " abc123")
+	assert.False(t, ok, "本实例的消息已在本地通知")
+	_, ok = parseTokenHitMessage("abc123")
+	assert.False(t, ok)
+}
+
+func TestParseWaitTimeout(t *testing.T) {
+	max := time.Minute
+	for in, want := range map[string]time.Duration{
+		"":     30 * time.Second,
+		"10s":  10 * time.Second,
+		"15":   15 * time.Second,
+		"5m":   time.Minute,
+		"0":    0,
+		"1.5s": 1500 * time.Millisecond,
+	} {
+		got, ok := parseWaitTimeout(in, max)
+		assert.True(t, ok, in)
+		assert.Equal(t, want, got, in)
+	}
+	for _, bad := range []string{"abc", "-1s", "-3"} {
+		_, ok := parseWaitTimeout(bad, max)
+		assert.False(t, ok, bad)
+	}
+}
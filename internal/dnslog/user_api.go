@@ -0,0 +1,502 @@
+package dnslog
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/genwilliam/dnslog_for_go/config"
+	"github.com/genwilliam/dnslog_for_go/pkg/response"
+	"github.com/genwilliam/dnslog_for_go/pkg/totp"
+
+	"github.com/gin-gonic/gin"
+)
+
+const totpIssuer = "dnslog"
+
+// LoginHandler 用户名密码（及可选 TOTP）登录，签发 HttpOnly 会话 cookie 与 CSRF token
+func LoginHandler(c *gin.Context) {
+	var req struct {
+		Username string `json:"username" binding:"required"`
+		Password string `json:"password" binding:"required"`
+		TOTPCode string `json:"totp_code"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest)
+		return
+	}
+
+	user, err := GetUserByUsernameWithContext(c.Request.Context(), req.Username)
+	if err != nil && err != ErrUserNotFound {
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError)
+		return
+	}
+	// 用户不存在、已禁用与密码错误返回相同错误，避免枚举用户名
+	if !CheckPassword(user.PasswordHash, req.Password) || !user.Enabled {
+		response.Error(c, http.StatusUnauthorized, response.CodeInvalidCredentials)
+		return
+	}
+
+	now := time.Now()
+	if user.TOTPEnabled {
+		if req.TOTPCode == "" {
+			response.Error(c, http.StatusUnauthorized, response.CodeTOTPRequired)
+			return
+		}
+		if !verifyUserTOTP(c, user, req.TOTPCode, now) {
+			return
+		}
+	}
+
+	sess, ok := startSession(c, user, now)
+	if !ok {
+		return
+	}
+	resp := userView(user)
+	resp["csrf_token"] = sess.CSRFToken
+	resp["expires_at"] = sess.ExpiresAt
+	response.Success(c, resp)
+}
+
+// startSession 创建会话并写入 cookie，失败时写入响应
+func startSession(c *gin.Context, user User, now time.Time) (UserSession, bool) {
+	plain, hash, err := GenerateSessionToken()
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError)
+		return UserSession{}, false
+	}
+	csrf, _, err := GenerateAPIKey()
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError)
+		return UserSession{}, false
+	}
+	ttl := sessionTTL()
+	sess := UserSession{
+		SessionHash: hash,
+		UserID:      user.ID,
+		CSRFToken:   csrf,
+		ClientIP:    c.ClientIP(),
+		UserAgent:   c.Request.UserAgent(),
+		CreatedAt:   now.UnixMilli(),
+		ExpiresAt:   now.Add(ttl).UnixMilli(),
+	}
+	if err := CreateSessionWithContext(c.Request.Context(), sess); err != nil {
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError)
+		return UserSession{}, false
+	}
+	_ = TouchUserLoginWithContext(c.Request.Context(), user.ID, now.UnixMilli())
+	setSessionCookies(c, plain, csrf, int(ttl/time.Second))
+	return sess, true
+}
+
+// LogoutHandler 注销当前会话
+func LogoutHandler(c *gin.Context) {
+	if hash := requestSessionHash(c); hash != "" {
+		if err := DeleteSessionWithContext(c.Request.Context(), hash); err != nil {
+			response.Error(c, http.StatusInternalServerError, response.CodeInternalError)
+			return
+		}
+	}
+	setSessionCookies(c, "", "", -1)
+	response.Success(c, gin.H{"logged_out": true})
+}
+
+// MeHandler 返回当前登录主体：会话用户或 API Key
+func MeHandler(c *gin.Context) {
+	scopes, _ := callerAPIKeyScopes(c)
+	tenant, _ := RequestTenant(c)
+	userID, ok := requestUserID(c)
+	if !ok {
+		keyID, _ := c.Get("api_key_id")
+		response.Success(c, gin.H{
+			"api_key_id": keyID,
+			"scopes":     scopes,
+			"tenant":     tenant,
+		})
+		return
+	}
+	user, ok := loadSessionUser(c, userID)
+	if !ok {
+		return
+	}
+	resp := userView(user)
+	resp["scopes"] = RoleScopes(user.Roles)
+	response.Success(c, resp)
+}
+
+// ChangePasswordHandler 修改当前用户密码，并注销其他会话
+func ChangePasswordHandler(c *gin.Context) {
+	var req struct {
+		CurrentPassword string `json:"current_password" binding:"required"`
+		NewPassword     string `json:"new_password" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest)
+		return
+	}
+	user, ok := currentSessionUser(c)
+	if !ok {
+		return
+	}
+	if !CheckPassword(user.PasswordHash, req.CurrentPassword) {
+		response.Error(c, http.StatusUnauthorized, response.CodeInvalidCredentials)
+		return
+	}
+	hash, err := HashPassword(req.NewPassword)
+	if err == ErrWeakPassword {
+		response.Error(c, http.StatusBadRequest, response.CodeWeakPassword)
+		return
+	}
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError)
+		return
+	}
+	if err := SetUserPasswordWithContext(c.Request.Context(), user.ID, hash, requestSessionHash(c)); err != nil {
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError)
+		return
+	}
+	response.Success(c, gin.H{"id": user.ID, "password_changed": true})
+}
+
+// SetupTOTPHandler 生成新的 TOTP 密钥（未启用），需随后调用 enable 以验证码确认
+func SetupTOTPHandler(c *gin.Context) {
+	user, ok := currentSessionUser(c)
+	if !ok {
+		return
+	}
+	if user.TOTPEnabled {
+		response.Error(c, http.StatusConflict, response.CodeConflict)
+		return
+	}
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError)
+		return
+	}
+	enc, err := EncryptWebhookSecret(secret)
+	if err == ErrSecretKeyRequired {
+		response.Error(c, http.StatusBadRequest, response.CodeEncryptionKeyRequired)
+		return
+	}
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError)
+		return
+	}
+	if err := SetUserTOTPWithContext(c.Request.Context(), user.ID, enc, false); err != nil {
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError)
+		return
+	}
+	response.Success(c, gin.H{
+		"secret": secret,
+		"uri":    totp.URI(totpIssuer, user.Username, secret),
+	})
+}
+
+// EnableTOTPHandler 以当前验证码确认并启用 TOTP
+func EnableTOTPHandler(c *gin.Context) {
+	var req struct {
+		Code string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest)
+		return
+	}
+	user, ok := currentSessionUser(c)
+	if !ok {
+		return
+	}
+	if user.TOTPEnabled {
+		response.Error(c, http.StatusConflict, response.CodeConflict)
+		return
+	}
+	if user.TOTPSecret == "" {
+		response.Error(c, http.StatusBadRequest, response.CodeTOTPNotSetup)
+		return
+	}
+	if !verifyUserTOTP(c, user, req.Code, time.Now()) {
+		return
+	}
+	if err := SetUserTOTPWithContext(c.Request.Context(), user.ID, user.TOTPSecret, true); err != nil {
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError)
+		return
+	}
+	response.Success(c, gin.H{"id": user.ID, "totp_enabled": true})
+}
+
+// DisableTOTPHandler 关闭 TOTP，需要当前密码
+func DisableTOTPHandler(c *gin.Context) {
+	var req struct {
+		Password string `json:"password" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest)
+		return
+	}
+	user, ok := currentSessionUser(c)
+	if !ok {
+		return
+	}
+	if !CheckPassword(user.PasswordHash, req.Password) {
+		response.Error(c, http.StatusUnauthorized, response.CodeInvalidCredentials)
+		return
+	}
+	if err := SetUserTOTPWithContext(c.Request.Context(), user.ID, "", false); err != nil {
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError)
+		return
+	}
+	response.Success(c, gin.H{"id": user.ID, "totp_enabled": false})
+}
+
+// CreateUserHandler 创建控制台用户；角色展开后的 scope 不能超出调用方权限
+func CreateUserHandler(c *gin.Context) {
+	var req struct {
+		Username string   `json:"username" binding:"required"`
+		Password string   `json:"password" binding:"required"`
+		Roles    []string `json:"roles"`
+		Tenant   string   `json:"tenant"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || !validUsername(req.Username) {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest)
+		return
+	}
+	roles, err := NormalizeRoles(req.Roles)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, response.CodeInvalidRole)
+		return
+	}
+	if len(roles) == 0 {
+		roles = []string{RoleViewer}
+	}
+	if !rolesWithinCaller(c, roles) {
+		return
+	}
+	tenant, all := RequestTenant(c)
+	if req.Tenant != "" && req.Tenant != tenant {
+		if !ValidTenant(req.Tenant) {
+			response.Error(c, http.StatusBadRequest, response.CodeInvalidTenant)
+			return
+		}
+		if !all {
+			response.Error(c, http.StatusForbidden, response.CodeForbidden)
+			return
+		}
+		tenant = req.Tenant
+	}
+	if tenant == "" {
+		tenant = DefaultTenant
+	}
+	hash, err := HashPassword(req.Password)
+	if err == ErrWeakPassword {
+		response.Error(c, http.StatusBadRequest, response.CodeWeakPassword)
+		return
+	}
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError)
+		return
+	}
+
+	nowMs := time.Now().UnixMilli()
+	id, err := CreateUserWithContext(c.Request.Context(), req.Username, hash, roles, tenant, nowMs)
+	if err == ErrUserExists {
+		response.Error(c, http.StatusConflict, response.CodeUserExists)
+		return
+	}
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError)
+		return
+	}
+	response.Success(c, userView(User{
+		ID:        id,
+		Username:  req.Username,
+		Roles:     roles,
+		Tenant:    tenant,
+		Enabled:   true,
+		CreatedAt: nowMs,
+	}))
+}
+
+// ListUsersHandler 列出控制台用户
+func ListUsersHandler(c *gin.Context) {
+	cfg := config.Get()
+	page := queryPositiveInt(c, "page", 1)
+	pageSize := queryPositiveInt(c, "pageSize", cfg.DefaultPageSize)
+	if pageSize > cfg.MaxPageSize {
+		pageSize = cfg.MaxPageSize
+	}
+	items, total, err := ListUsersWithContext(c.Request.Context(), UserListFilter{
+		Page:     page,
+		PageSize: pageSize,
+		Tenant:   RequestListTenant(c),
+	})
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError)
+		return
+	}
+	respItems := make([]gin.H, 0, len(items))
+	for _, u := range items {
+		respItems = append(respItems, userView(u))
+	}
+	response.Success(c, gin.H{
+		"items": respItems,
+		"total": total,
+		"page":  page,
+		"size":  pageSize,
+	})
+}
+
+// SetUserRolesHandler 替换用户角色
+func SetUserRolesHandler(c *gin.Context) {
+	user, ok := loadTenantUser(c)
+	if !ok {
+		return
+	}
+	var req struct {
+		Roles []string `json:"roles" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest)
+		return
+	}
+	roles, err := NormalizeRoles(req.Roles)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, response.CodeInvalidRole)
+		return
+	}
+	// 调用方既不能授予、也不能撤销超出自身权限的角色
+	if !rolesWithinCaller(c, roles) || !rolesWithinCaller(c, user.Roles) {
+		return
+	}
+	if err := SetUserRolesWithContext(c.Request.Context(), user.ID, roles); err != nil {
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError)
+		return
+	}
+	user.Roles = roles
+	response.Success(c, userView(user))
+}
+
+// DisableUserHandler 禁用用户并注销其全部会话
+func DisableUserHandler(c *gin.Context) {
+	user, ok := loadTenantUser(c)
+	if !ok {
+		return
+	}
+	if !rolesWithinCaller(c, user.Roles) {
+		return
+	}
+	if err := SetUserEnabledWithContext(c.Request.Context(), user.ID, false); err != nil {
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError)
+		return
+	}
+	response.Success(c, gin.H{"id": user.ID, "disabled": true})
+}
+
+// verifyUserTOTP 校验验证码并登记时间步，失败时写入响应
+func verifyUserTOTP(c *gin.Context, user User, code string, now time.Time) bool {
+	secret, err := DecryptWebhookSecret(user.TOTPSecret)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError)
+		return false
+	}
+	step, err := totp.Validate(secret, code, now)
+	if err == nil {
+		err = UseTOTPStepWithContext(c.Request.Context(), user.ID, step)
+	}
+	if err == totp.ErrInvalidCode || err == ErrTOTPReplayed {
+		response.Error(c, http.StatusUnauthorized, response.CodeInvalidTOTP)
+		return false
+	}
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError)
+		return false
+	}
+	return true
+}
+
+// currentSessionUser 返回会话登录用户；API Key 请求返回 401
+func currentSessionUser(c *gin.Context) (User, bool) {
+	userID, ok := requestUserID(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeInvalidSession)
+		return User{}, false
+	}
+	return loadSessionUser(c, userID)
+}
+
+func loadSessionUser(c *gin.Context, userID int64) (User, bool) {
+	user, err := GetUserByIDWithContext(c.Request.Context(), userID)
+	if err == ErrUserNotFound {
+		response.Error(c, http.StatusUnauthorized, response.CodeInvalidSession)
+		return User{}, false
+	}
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError)
+		return User{}, false
+	}
+	return user, true
+}
+
+// loadTenantUser 读取路径中的用户，其他租户的用户按不存在处理
+func loadTenantUser(c *gin.Context) (User, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest)
+		return User{}, false
+	}
+	user, err := GetUserByIDWithContext(c.Request.Context(), id)
+	if err == nil {
+		if !canAccessTenant(c, user.Tenant) {
+			err = ErrUserNotFound
+		}
+	}
+	if err == ErrUserNotFound {
+		response.Error(c, http.StatusNotFound, response.CodeNotFound)
+		return User{}, false
+	}
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError)
+		return User{}, false
+	}
+	return user, true
+}
+
+// rolesWithinCaller 角色展开的 scope 必须在调用方权限之内
+func rolesWithinCaller(c *gin.Context, roles []string) bool {
+	callerScopes, ok := callerAPIKeyScopes(c)
+	if ok && !ScopesSubset(RoleScopes(roles), callerScopes) {
+		response.Error(c, http.StatusForbidden, response.CodeScopeExceeded)
+		return false
+	}
+	return true
+}
+
+// validUsername 用户名：1~64 位字母、数字及 . _ @ -
+func validUsername(name string) bool {
+	if name == "" || len(name) > 64 {
+		return false
+	}
+	for _, ch := range name {
+		if (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || (ch >= '0' && ch <= '9') {
+			continue
+		}
+		switch ch {
+		case '.', '_', '@', '-':
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+func userView(u User) gin.H {
+	return gin.H{
+		"id":            u.ID,
+		"username":      u.Username,
+		"roles":         u.Roles,
+		"tenant":        u.Tenant,
+		"enabled":       u.Enabled,
+		"totp_enabled":  u.TOTPEnabled,
+		"created_at":    u.CreatedAt,
+		"last_login_at": u.LastLoginAt,
+		"sso":           u.OIDCSubject != "",
+	}
+}
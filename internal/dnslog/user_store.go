@@ -0,0 +1,448 @@
+package dnslog
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// User 控制台用户；权限由 Roles 展开为 scope
+type User struct {
+	ID           int64
+	Username     string
+	PasswordHash string
+	Roles        []string
+	Tenant       string
+	TOTPSecret   string // 加密后的 base32 密钥
+	TOTPEnabled  bool
+	TOTPLastStep int64 // 最近一次通过校验的时间步，防止验证码重放
+	Enabled      bool
+	CreatedAt    int64
+	LastLoginAt  int64
+	OIDCSubject  string // SSO 用户的 sub，本地用户为空
+}
+
+// UserSession 登录会话；SessionHash 为 cookie 值的 SHA-256
+type UserSession struct {
+	ID          int64
+	SessionHash string
+	UserID      int64
+	CSRFToken   string
+	ClientIP    string
+	UserAgent   string
+	CreatedAt   int64
+	ExpiresAt   int64
+}
+
+// UserListFilter 用户列表过滤条件
+type UserListFilter struct {
+	Page     int
+	PageSize int
+	Tenant   string // 非空时只返回该租户的用户
+}
+
+var (
+	ErrUserNotFound    = errors.New("user_not_found")
+	ErrUserExists      = errors.New("user_exists")
+	ErrSessionNotFound = errors.New("session_not_found")
+	ErrTOTPReplayed    = errors.New("totp_replayed")
+)
+
+const userColumns = `id, username, password_hash, roles, tenant, totp_secret, totp_enabled, totp_last_step, enabled, created_at, last_login_at, COALESCE(oidc_subject, '')`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanUser(row rowScanner) (User, error) {
+	var u User
+	var roles string
+	var totpEnabled, enabled int
+	err := row.Scan(&u.ID, &u.Username, &u.PasswordHash, &roles, &u.Tenant, &u.TOTPSecret, &totpEnabled, &u.TOTPLastStep, &enabled, &u.CreatedAt, &u.LastLoginAt, &u.OIDCSubject)
+	if err != nil {
+		return User{}, err
+	}
+	u.Roles = parseScopes(roles)
+	u.TOTPEnabled = totpEnabled == 1
+	u.Enabled = enabled == 1
+	return u, nil
+}
+
+func CreateUserWithContext(ctx context.Context, username, passwordHash string, roles []string, tenant string, nowMs int64) (int64, error) {
+	if db == nil {
+		return 0, errors.New("store not initialized")
+	}
+	ctx = ensureContext(ctx)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	res, err := db.ExecContext(ctx, `
+INSERT INTO users (username, password_hash, roles, tenant, enabled, created_at)
+VALUES (?, ?, ?, ?, 1, ?)
+`, username, passwordHash, formatScopes(roles), tenant, nowMs)
+	if isDuplicateKey(err) {
+		return 0, ErrUserExists
+	}
+	if err != nil {
+		return 0, err
+	}
+	id, _ := res.LastInsertId()
+	return id, nil
+}
+
+func CreateUser(username, passwordHash string, roles []string, tenant string, nowMs int64) (int64, error) {
+	return CreateUserWithContext(context.Background(), username, passwordHash, roles, tenant, nowMs)
+}
+
+// UpsertOIDCUserWithContext 按 sub 查找或创建 SSO 用户，并以 IdP 的组映射结果覆盖角色；
+// 新用户的用户名与本地用户冲突时追加 sub 哈希后缀。SSO 用户没有本地密码
+func UpsertOIDCUserWithContext(ctx context.Context, subject, username string, roles []string, tenant string, nowMs int64) (User, error) {
+	if db == nil {
+		return User{}, errors.New("store not initialized")
+	}
+	ctx = ensureContext(ctx)
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	u, err := scanUser(db.QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE oidc_subject = ?`, subject))
+	if err == nil {
+		if _, err := db.ExecContext(ctx, `UPDATE users SET roles = ? WHERE id = ?`, formatScopes(roles), u.ID); err != nil {
+			return User{}, err
+		}
+		u.Roles = roles
+		return u, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return User{}, err
+	}
+
+	candidates := []string{username, truncate(username, 55) + "-" + HashAPIKey(subject)[:8]}
+	for _, name := range candidates {
+		res, err := db.ExecContext(ctx, `
+INSERT INTO users (username, password_hash, roles, tenant, enabled, created_at, oidc_subject)
+VALUES (?, '', ?, ?, 1, ?, ?)
+`, name, formatScopes(roles), tenant, nowMs, subject)
+		if isDuplicateKey(err) {
+			continue
+		}
+		if err != nil {
+			return User{}, err
+		}
+		id, _ := res.LastInsertId()
+		return User{
+			ID:          id,
+			Username:    name,
+			Roles:       roles,
+			Tenant:      tenant,
+			Enabled:     true,
+			CreatedAt:   nowMs,
+			OIDCSubject: subject,
+		}, nil
+	}
+	return User{}, ErrUserExists
+}
+
+func UpsertOIDCUser(subject, username string, roles []string, tenant string, nowMs int64) (User, error) {
+	return UpsertOIDCUserWithContext(context.Background(), subject, username, roles, tenant, nowMs)
+}
+
+func GetUserByUsernameWithContext(ctx context.Context, username string) (User, error) {
+	if db == nil {
+		return User{}, errors.New("store not initialized")
+	}
+	ctx = ensureContext(ctx)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	u, err := scanUser(db.QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE username = ?`, username))
+	if errors.Is(err, sql.ErrNoRows) {
+		return User{}, ErrUserNotFound
+	}
+	return u, err
+}
+
+func GetUserByUsername(username string) (User, error) {
+	return GetUserByUsernameWithContext(context.Background(), username)
+}
+
+func GetUserByIDWithContext(ctx context.Context, id int64) (User, error) {
+	if db == nil {
+		return User{}, errors.New("store not initialized")
+	}
+	ctx = ensureContext(ctx)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	u, err := scanUser(db.QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return User{}, ErrUserNotFound
+	}
+	return u, err
+}
+
+func GetUserByID(id int64) (User, error) {
+	return GetUserByIDWithContext(context.Background(), id)
+}
+
+func ListUsersWithContext(ctx context.Context, filter UserListFilter) ([]User, int, error) {
+	if db == nil {
+		return nil, 0, errors.New("store not initialized")
+	}
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PageSize <= 0 {
+		filter.PageSize = 20
+	}
+	ctx = ensureContext(ctx)
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	whereSQL := ""
+	args := make([]interface{}, 0)
+	if filter.Tenant != "" {
+		whereSQL = "WHERE tenant = ?"
+		args = append(args, filter.Tenant)
+	}
+
+	var total int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(1) FROM users `+whereSQL, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	offset := (filter.Page - 1) * filter.PageSize
+	rows, err := db.QueryContext(ctx, `
+SELECT `+userColumns+`
+FROM users
+`+whereSQL+`
+ORDER BY id DESC
+LIMIT ? OFFSET ?
+`, append(args, filter.PageSize, offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var items []User
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		items = append(items, u)
+	}
+	return items, total, rows.Err()
+}
+
+func ListUsers(filter UserListFilter) ([]User, int, error) {
+	return ListUsersWithContext(context.Background(), filter)
+}
+
+func updateUser(ctx context.Context, query string, args ...interface{}) error {
+	if db == nil {
+		return errors.New("store not initialized")
+	}
+	ctx = ensureContext(ctx)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	res, err := db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	affected, _ := res.RowsAffected()
+	if affected == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// SetUserEnabledWithContext 启用/禁用用户；禁用时同时注销其全部会话
+func SetUserEnabledWithContext(ctx context.Context, id int64, enabled bool) error {
+	val := 0
+	if enabled {
+		val = 1
+	}
+	if _, err := GetUserByIDWithContext(ctx, id); err != nil {
+		return err
+	}
+	if err := updateUser(ctx, `UPDATE users SET enabled = ? WHERE id = ?`, val, id); err != nil && err != ErrUserNotFound {
+		return err
+	}
+	if !enabled {
+		return DeleteUserSessionsWithContext(ctx, id, "")
+	}
+	return nil
+}
+
+func SetUserEnabled(id int64, enabled bool) error {
+	return SetUserEnabledWithContext(context.Background(), id, enabled)
+}
+
+func SetUserRolesWithContext(ctx context.Context, id int64, roles []string) error {
+	if _, err := GetUserByIDWithContext(ctx, id); err != nil {
+		return err
+	}
+	err := updateUser(ctx, `UPDATE users SET roles = ? WHERE id = ?`, formatScopes(roles), id)
+	if err == ErrUserNotFound {
+		// 角色未变化时 RowsAffected 为 0
+		return nil
+	}
+	return err
+}
+
+func SetUserRoles(id int64, roles []string) error {
+	return SetUserRolesWithContext(context.Background(), id, roles)
+}
+
+// SetUserPasswordWithContext 更新密码并注销除 keepSessionHash 以外的全部会话
+func SetUserPasswordWithContext(ctx context.Context, id int64, passwordHash, keepSessionHash string) error {
+	if err := updateUser(ctx, `UPDATE users SET password_hash = ? WHERE id = ?`, passwordHash, id); err != nil {
+		return err
+	}
+	return DeleteUserSessionsWithContext(ctx, id, keepSessionHash)
+}
+
+func SetUserPassword(id int64, passwordHash, keepSessionHash string) error {
+	return SetUserPasswordWithContext(context.Background(), id, passwordHash, keepSessionHash)
+}
+
+// SetUserTOTPWithContext 保存（加密后的）TOTP 密钥及启用状态；已用时间步单调递增，无需重置
+func SetUserTOTPWithContext(ctx context.Context, id int64, encSecret string, enabled bool) error {
+	val := 0
+	if enabled {
+		val = 1
+	}
+	if _, err := GetUserByIDWithContext(ctx, id); err != nil {
+		return err
+	}
+	err := updateUser(ctx, `UPDATE users SET totp_secret = ?, totp_enabled = ? WHERE id = ?`, encSecret, val, id)
+	if err == ErrUserNotFound {
+		return nil
+	}
+	return err
+}
+
+func SetUserTOTP(id int64, encSecret string, enabled bool) error {
+	return SetUserTOTPWithContext(context.Background(), id, encSecret, enabled)
+}
+
+// UseTOTPStepWithContext 原子地记录已使用的时间步；同一步或更早的验证码再次提交返回 ErrTOTPReplayed
+func UseTOTPStepWithContext(ctx context.Context, id int64, step int64) error {
+	err := updateUser(ctx, `UPDATE users SET totp_last_step = ? WHERE id = ? AND totp_last_step < ?`, step, id, step)
+	if err == ErrUserNotFound {
+		return ErrTOTPReplayed
+	}
+	return err
+}
+
+func UseTOTPStep(id int64, step int64) error {
+	return UseTOTPStepWithContext(context.Background(), id, step)
+}
+
+func TouchUserLoginWithContext(ctx context.Context, id int64, nowMs int64) error {
+	return updateUser(ctx, `UPDATE users SET last_login_at = ? WHERE id = ?`, nowMs, id)
+}
+
+func TouchUserLogin(id int64, nowMs int64) error {
+	return TouchUserLoginWithContext(context.Background(), id, nowMs)
+}
+
+func CreateSessionWithContext(ctx context.Context, s UserSession) error {
+	if db == nil {
+		return errors.New("store not initialized")
+	}
+	ctx = ensureContext(ctx)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	_, err := db.ExecContext(ctx, `
+INSERT INTO user_sessions (session_hash, user_id, csrf_token, client_ip, user_agent, created_at, expires_at)
+VALUES (?, ?, ?, ?, ?, ?, ?)
+`, s.SessionHash, s.UserID, s.CSRFToken, s.ClientIP, truncate(s.UserAgent, 255), s.CreatedAt, s.ExpiresAt)
+	return err
+}
+
+func CreateSession(s UserSession) error {
+	return CreateSessionWithContext(context.Background(), s)
+}
+
+// GetSessionByHashWithContext 查询未过期的会话
+func GetSessionByHashWithContext(ctx context.Context, hash string, nowMs int64) (UserSession, error) {
+	if db == nil {
+		return UserSession{}, errors.New("store not initialized")
+	}
+	ctx = ensureContext(ctx)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	var s UserSession
+	err := db.QueryRowContext(ctx, `
+SELECT id, session_hash, user_id, csrf_token, client_ip, user_agent, created_at, expires_at
+FROM user_sessions
+WHERE session_hash = ? AND expires_at > ?
+`, hash, nowMs).Scan(&s.ID, &s.SessionHash, &s.UserID, &s.CSRFToken, &s.ClientIP, &s.UserAgent, &s.CreatedAt, &s.ExpiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return UserSession{}, ErrSessionNotFound
+	}
+	return s, err
+}
+
+func GetSessionByHash(hash string, nowMs int64) (UserSession, error) {
+	return GetSessionByHashWithContext(context.Background(), hash, nowMs)
+}
+
+func DeleteSessionWithContext(ctx context.Context, hash string) error {
+	if db == nil {
+		return errors.New("store not initialized")
+	}
+	ctx = ensureContext(ctx)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	_, err := db.ExecContext(ctx, `DELETE FROM user_sessions WHERE session_hash = ?`, hash)
+	return err
+}
+
+func DeleteSession(hash string) error {
+	return DeleteSessionWithContext(context.Background(), hash)
+}
+
+// DeleteUserSessionsWithContext 注销用户全部会话，exceptHash 非空时保留该会话
+func DeleteUserSessionsWithContext(ctx context.Context, userID int64, exceptHash string) error {
+	if db == nil {
+		return errors.New("store not initialized")
+	}
+	ctx = ensureContext(ctx)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	_, err := db.ExecContext(ctx, `DELETE FROM user_sessions WHERE user_id = ? AND session_hash <> ?`, userID, exceptHash)
+	return err
+}
+
+func DeleteUserSessions(userID int64, exceptHash string) error {
+	return DeleteUserSessionsWithContext(context.Background(), userID, exceptHash)
+}
+
+func DeleteExpiredSessions(nowMs int64, limit int) (int64, error) {
+	if db == nil {
+		return 0, errors.New("store not initialized")
+	}
+	if limit <= 0 {
+		limit = 1000
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	res, err := db.ExecContext(ctx, `
+DELETE FROM user_sessions
+WHERE expires_at <= ?
+LIMIT ?
+`, nowMs, limit)
+	if err != nil {
+		return 0, err
+	}
+	affected, _ := res.RowsAffected()
+	return affected, nil
+}
+
+func truncate(val string, n int) string {
+	if len(val) <= n {
+		return val
+	}
+	return val[:n]
+}
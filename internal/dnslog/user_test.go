@@ -0,0 +1,37 @@
+package dnslog
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHashPassword(t *testing.T) {
+	_, err := HashPassword("short")
+	assert.ErrorIs(t, err, ErrWeakPassword)
+
+	hash, err := HashPassword("correct horse battery")
+	assert.NoError(t, err)
+	assert.True(t, CheckPassword(hash, "correct horse battery"))
+	assert.False(t, CheckPassword(hash, "wrong password"))
+	assert.False(t, CheckPassword("", "correct horse battery"))
+}
+
+func TestValidCSRFToken(t *testing.T) {
+	sess := UserSession{CSRFToken: "abc123"}
+	assert.True(t, ValidCSRFToken(sess, "abc123"))
+	assert.False(t, ValidCSRFToken(sess, "abc124"))
+	assert.False(t, ValidCSRFToken(UserSession{}, ""))
+
+	assert.False(t, RequiresCSRF(http.MethodGet))
+	assert.True(t, RequiresCSRF(http.MethodPost))
+	assert.True(t, RequiresCSRF(http.MethodDelete))
+}
+
+func TestValidUsername(t *testing.T) {
+	assert.True(t, validUsername("alice.ops@example"))
+	assert.False(t, validUsername(""))
+	assert.False(t, validUsername("bob smith"))
+	assert.False(t, validUsername("root;--"))
+}
@@ -1,10 +1,14 @@
 package dnslog
 
 import (
+	"errors"
 	"net/http"
+	"strings"
 	"time"
 
+	"github.com/genwilliam/dnslog_for_go/config"
 	"github.com/genwilliam/dnslog_for_go/pkg/response"
+	"github.com/genwilliam/dnslog_for_go/pkg/webhook"
 
 	"github.com/gin-gonic/gin"
 )
@@ -12,15 +16,21 @@ import (
 // SetTokenWebhookHandler 绑定 token webhook（仅支持 FIRST_HIT）
 func SetTokenWebhookHandler(c *gin.Context) {
 	token := c.Param("token")
-	if token == "" {
+	// "_" 开头为内部占位 token（如管理事件投递），不允许绑定
+	if token == "" || strings.HasPrefix(token, "_") {
 		response.Error(c, http.StatusBadRequest, response.CodeBadRequest)
 		return
 	}
+	if !authorizeToken(c, token) {
+		return
+	}
 
 	var req struct {
-		URL    string `json:"webhook_url" binding:"required"`
-		Secret string `json:"secret"`
-		Mode   string `json:"mode"`
+		URL     string            `json:"webhook_url" binding:"required"`
+		Secret  string            `json:"secret"`
+		Mode    string            `json:"mode"`
+		Headers map[string]string `json:"headers"`
+		Auth    *WebhookAuth      `json:"auth"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		response.Error(c, http.StatusBadRequest, response.CodeBadRequest)
@@ -33,8 +43,27 @@ func SetTokenWebhookHandler(c *gin.Context) {
 		response.Error(c, http.StatusBadRequest, response.CodeBadRequest)
 		return
 	}
+	if err := webhookEgress().Validate(c.Request.Context(), req.URL); err != nil {
+		response.Error(c, http.StatusBadRequest, webhookEgressCode(err))
+		return
+	}
+	headers, err := NormalizeWebhookHeaders(req.Headers)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, response.CodeWebhookHeadersInvalid)
+		return
+	}
+	var auth WebhookAuth
+	if req.Auth != nil {
+		auth = *req.Auth
+	}
+	auth, err = NormalizeWebhookAuth(auth)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, response.CodeWebhookAuthInvalid)
+		return
+	}
 
-	if err := UpsertTokenWebhookWithContext(c.Request.Context(), token, req.URL, req.Secret, req.Mode, time.Now().UnixMilli()); err != nil {
+	overlapMs := webhookSecretOverlapMs(config.Get())
+	if err := UpsertTokenWebhookWithContext(c.Request.Context(), token, req.URL, req.Secret, req.Mode, headers, auth, time.Now().UnixMilli(), overlapMs); err != nil {
 		if err == ErrSecretKeyRequired {
 			response.Error(c, http.StatusBadRequest, response.CodeWebhookSecretKeyRequired)
 			return
@@ -42,7 +71,13 @@ func SetTokenWebhookHandler(c *gin.Context) {
 		response.Error(c, http.StatusInternalServerError, response.CodeInternalError)
 		return
 	}
-	response.Success(c, gin.H{"token": token, "webhook_url": req.URL, "mode": req.Mode})
+	response.Success(c, gin.H{
+		"token":       token,
+		"webhook_url": req.URL,
+		"mode":        req.Mode,
+		"headers":     maskedWebhookHeaders(headers),
+		"auth":        maskedWebhookAuth(auth),
+	})
 }
 
 // GetTokenWebhookHandler 获取 token webhook
@@ -52,6 +87,9 @@ func GetTokenWebhookHandler(c *gin.Context) {
 		response.Error(c, http.StatusBadRequest, response.CodeBadRequest)
 		return
 	}
+	if !authorizeToken(c, token) {
+		return
+	}
 	hook, err := GetTokenWebhookWithContext(c.Request.Context(), token)
 	if err == ErrWebhookNotFound {
 		response.Error(c, http.StatusNotFound, response.CodeNotFound)
@@ -61,13 +99,25 @@ func GetTokenWebhookHandler(c *gin.Context) {
 		response.Error(c, http.StatusInternalServerError, response.CodeInternalError)
 		return
 	}
-	response.Success(c, gin.H{
+	resp := gin.H{
 		"token":       hook.Token,
 		"webhook_url": hook.URL,
 		"mode":        hook.Mode,
 		"enabled":     hook.Enabled,
 		"created_at":  hook.CreatedAt,
-	})
+		"headers":     maskedWebhookHeaders(hook.Headers),
+		"auth":        maskedWebhookAuth(hook.Auth),
+	}
+	// 只返回密钥 ID，便于接收端确认当前/轮换中的 secret
+	if hook.Secret != "" {
+		resp["key_id"] = webhook.KeyID(hook.Secret)
+	}
+	secrets := hook.ActiveSecrets(time.Now().UnixMilli())
+	if len(secrets) > 1 {
+		resp["prev_key_id"] = webhook.KeyID(secrets[1])
+		resp["prev_key_expires_at"] = hook.PrevSecretExpiresAt
+	}
+	response.Success(c, resp)
 }
 
 // DisableTokenWebhookHandler 禁用 token webhook
@@ -77,9 +127,28 @@ func DisableTokenWebhookHandler(c *gin.Context) {
 		response.Error(c, http.StatusBadRequest, response.CodeBadRequest)
 		return
 	}
+	if !authorizeToken(c, token) {
+		return
+	}
 	if err := DisableTokenWebhookWithContext(c.Request.Context(), token); err != nil {
 		response.Error(c, http.StatusInternalServerError, response.CodeInternalError)
 		return
 	}
 	response.Success(c, gin.H{"token": token, "disabled": true})
 }
+
+// webhookEgressCode 将出站策略错误映射为响应码
+func webhookEgressCode(err error) string {
+	switch {
+	case errors.Is(err, ErrWebhookSchemeNotAllowed):
+		return response.CodeWebhookSchemeNotAllowed
+	case errors.Is(err, ErrWebhookHostDenied):
+		return response.CodeWebhookHostDenied
+	case errors.Is(err, ErrWebhookIPDenied):
+		return response.CodeWebhookIPDenied
+	case errors.Is(err, ErrWebhookResolveFailed):
+		return response.CodeWebhookResolveFailed
+	default:
+		return response.CodeWebhookURLInvalid
+	}
+}
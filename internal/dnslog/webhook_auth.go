@@ -0,0 +1,195 @@
+package dnslog
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+const (
+	WebhookAuthNone   = "none"
+	WebhookAuthBearer = "bearer"
+	WebhookAuthBasic  = "basic"
+
+	maxWebhookHeaders     = 20
+	maxWebhookHeaderValue = 1024
+)
+
+var (
+	ErrWebhookHeadersInvalid = errors.New("webhook_headers_invalid")
+	ErrWebhookAuthInvalid    = errors.New("webhook_auth_invalid")
+)
+
+// WebhookAuth 出站认证配置，落库前整体加密
+type WebhookAuth struct {
+	Type     string `json:"type"`
+	Token    string `json:"token,omitempty"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+}
+
+// reservedWebhookHeaders 由投递逻辑自身设置，不允许自定义覆盖
+var reservedWebhookHeaders = map[string]struct{}{
+	"Host":                {},
+	"Content-Type":        {},
+	"Content-Length":      {},
+	"Connection":          {},
+	"Transfer-Encoding":   {},
+	"Te":                  {},
+	"Upgrade":             {},
+	"Authorization":       {},
+	"Proxy-Authorization": {},
+	"X-Event-Id":          {},
+	"X-Signature":         {},
+	"X-Webhook-Timestamp": {},
+	"X-Webhook-Signature": {},
+}
+
+// NormalizeWebhookHeaders 校验并规范化自定义头（名称转为规范格式）
+func NormalizeWebhookHeaders(headers map[string]string) (map[string]string, error) {
+	if len(headers) == 0 {
+		return nil, nil
+	}
+	if len(headers) > maxWebhookHeaders {
+		return nil, ErrWebhookHeadersInvalid
+	}
+	out := make(map[string]string, len(headers))
+	for name, val := range headers {
+		name = strings.TrimSpace(name)
+		if !isHeaderToken(name) || len(val) > maxWebhookHeaderValue || strings.ContainsAny(val, "\r\n\x00") {
+			return nil, ErrWebhookHeadersInvalid
+		}
+		canonical := http.CanonicalHeaderKey(name)
+		if _, reserved := reservedWebhookHeaders[canonical]; reserved {
+			return nil, ErrWebhookHeadersInvalid
+		}
+		out[canonical] = val
+	}
+	return out, nil
+}
+
+// NormalizeWebhookAuth 校验认证配置，空类型视为不认证
+func NormalizeWebhookAuth(auth WebhookAuth) (WebhookAuth, error) {
+	auth.Type = strings.ToLower(strings.TrimSpace(auth.Type))
+	switch auth.Type {
+	case "", WebhookAuthNone:
+		return WebhookAuth{Type: WebhookAuthNone}, nil
+	case WebhookAuthBearer:
+		if auth.Token == "" || strings.ContainsAny(auth.Token, "\r\n\x00 ") {
+			return WebhookAuth{}, ErrWebhookAuthInvalid
+		}
+		return WebhookAuth{Type: WebhookAuthBearer, Token: auth.Token}, nil
+	case WebhookAuthBasic:
+		if auth.Username == "" || strings.Contains(auth.Username, ":") || strings.ContainsAny(auth.Username+auth.Password, "\r\n\x00") {
+			return WebhookAuth{}, ErrWebhookAuthInvalid
+		}
+		return WebhookAuth{Type: WebhookAuthBasic, Username: auth.Username, Password: auth.Password}, nil
+	default:
+		return WebhookAuth{}, ErrWebhookAuthInvalid
+	}
+}
+
+// encryptWebhookHeaders 自定义头可能携带网关密钥，按 webhook secret 同样方式加密
+func encryptWebhookHeaders(headers map[string]string) (string, error) {
+	if len(headers) == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal(headers)
+	if err != nil {
+		return "", err
+	}
+	return EncryptWebhookSecret(string(data))
+}
+
+func decryptWebhookHeaders(stored string) (map[string]string, error) {
+	plain, err := DecryptWebhookSecret(stored)
+	if err != nil || plain == "" {
+		return nil, err
+	}
+	var headers map[string]string
+	if err := json.Unmarshal([]byte(plain), &headers); err != nil {
+		return nil, err
+	}
+	return headers, nil
+}
+
+func encryptWebhookAuth(auth WebhookAuth) (string, error) {
+	if auth.Type == "" || auth.Type == WebhookAuthNone {
+		return "", nil
+	}
+	data, err := json.Marshal(auth)
+	if err != nil {
+		return "", err
+	}
+	return EncryptWebhookSecret(string(data))
+}
+
+func decryptWebhookAuth(authType, stored string) (WebhookAuth, error) {
+	if authType == "" || authType == WebhookAuthNone || stored == "" {
+		return WebhookAuth{Type: WebhookAuthNone}, nil
+	}
+	plain, err := DecryptWebhookSecret(stored)
+	if err != nil {
+		return WebhookAuth{}, err
+	}
+	var auth WebhookAuth
+	if err := json.Unmarshal([]byte(plain), &auth); err != nil {
+		return WebhookAuth{}, err
+	}
+	auth.Type = authType
+	return auth, nil
+}
+
+// applyWebhookAuth 为出站请求写入自定义头与认证信息
+func applyWebhookAuth(req *http.Request, headers map[string]string, auth WebhookAuth) {
+	for name, val := range headers {
+		if _, reserved := reservedWebhookHeaders[http.CanonicalHeaderKey(name)]; reserved {
+			continue
+		}
+		req.Header.Set(name, val)
+	}
+	switch auth.Type {
+	case WebhookAuthBearer:
+		req.Header.Set("Authorization", "Bearer "+auth.Token)
+	case WebhookAuthBasic:
+		req.SetBasicAuth(auth.Username, auth.Password)
+	}
+}
+
+// maskedWebhookHeaders 返回只保留头名称的脱敏副本，值一律替换为固定占位符（头的值常为完整密钥）
+func maskedWebhookHeaders(headers map[string]string) map[string]string {
+	out := make(map[string]string, len(headers))
+	for name := range headers {
+		out[name] = "****"
+	}
+	return out
+}
+
+// maskedWebhookAuth 返回脱敏后的认证配置，用户名保留便于核对
+func maskedWebhookAuth(auth WebhookAuth) map[string]string {
+	out := map[string]string{"type": auth.Type}
+	switch auth.Type {
+	case WebhookAuthBearer:
+		out["token"] = MaskSecret(auth.Token)
+	case WebhookAuthBasic:
+		out["username"] = auth.Username
+		out["password"] = MaskSecret(auth.Password)
+	}
+	return out
+}
+
+func isHeaderToken(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, ch := range name {
+		switch {
+		case ch >= 'a' && ch <= 'z', ch >= 'A' && ch <= 'Z', ch >= '0' && ch <= '9':
+		case strings.ContainsRune("!#$%&'*+-.^_`|~", ch):
+		default:
+			return false
+		}
+	}
+	return true
+}
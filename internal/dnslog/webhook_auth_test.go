@@ -0,0 +1,58 @@
+package dnslog
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeWebhookHeaders(t *testing.T) {
+	out, err := NormalizeWebhookHeaders(map[string]string{"x-tenant": "acme"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"X-Tenant": "acme"}, out)
+
+	bad := []map[string]string{
+		{"Authorization": "Bearer x"},
+		{"x-webhook-signature": "v2=x"},
+		{"Host": "evil"},
+		{"X-Bad Name": "v"},
+		{"X-Inject": "a\r\nX-Other: b"},
+	}
+	for _, h := range bad {
+		_, err := NormalizeWebhookHeaders(h)
+		assert.ErrorIs(t, err, ErrWebhookHeadersInvalid, h)
+	}
+}
+
+func TestNormalizeWebhookAuth(t *testing.T) {
+	auth, err := NormalizeWebhookAuth(WebhookAuth{})
+	assert.NoError(t, err)
+	assert.Equal(t, WebhookAuthNone, auth.Type)
+
+	auth, err = NormalizeWebhookAuth(WebhookAuth{Type: "Bearer", Token: "t", Username: "ignored"})
+	assert.NoError(t, err)
+	assert.Equal(t, WebhookAuth{Type: WebhookAuthBearer, Token: "t"}, auth)
+
+	_, err = NormalizeWebhookAuth(WebhookAuth{Type: "bearer"})
+	assert.ErrorIs(t, err, ErrWebhookAuthInvalid)
+	_, err = NormalizeWebhookAuth(WebhookAuth{Type: "basic", Username: "a:b"})
+	assert.ErrorIs(t, err, ErrWebhookAuthInvalid)
+	_, err = NormalizeWebhookAuth(WebhookAuth{Type: "digest"})
+	assert.ErrorIs(t, err, ErrWebhookAuthInvalid)
+}
+
+func TestApplyWebhookAuth(t *testing.T) {
+	req, _ := http.NewRequest("POST", "https://example.com/hook", nil)
+	applyWebhookAuth(req, map[string]string{"X-Tenant": "acme"}, WebhookAuth{Type: WebhookAuthBasic, Username: "u", Password: "p"})
+	assert.Equal(t, "acme", req.Header.Get("X-Tenant"))
+	user, pass, ok := req.BasicAuth()
+	assert.True(t, ok)
+	assert.Equal(t, "u", user)
+	assert.Equal(t, "p", pass)
+
+	masked := maskedWebhookAuth(WebhookAuth{Type: WebhookAuthBearer, Token: "abcdefghijkl"})
+	assert.Equal(t, "****ijkl", masked["token"])
+	assert.Equal(t, "****", MaskSecret("short"))
+	assert.Equal(t, map[string]string{"X-Api-Key": "****"}, maskedWebhookHeaders(map[string]string{"X-Api-Key": "abcdefghijkl"}))
+}
@@ -0,0 +1,176 @@
+package dnslog
+
+import (
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/genwilliam/dnslog_for_go/pkg/log"
+	"go.uber.org/zap"
+)
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// breakerProbeWait 半开探测名额已满时，其余任务的推迟时长
+const breakerProbeWait = 5 * time.Second
+
+// BreakerPolicy 熔断参数
+type BreakerPolicy struct {
+	Failures int           // 连续失败达到该值后熔断
+	OpenFor  time.Duration // 熔断持续时长
+	Probes   int           // 半开状态允许的并发探测数
+}
+
+// webhookEndpoint 单个投递目标（scheme://host:port）的熔断与并发状态，仅在本进程内生效
+type webhookEndpoint struct {
+	mu        sync.Mutex
+	key       string
+	state     breakerState
+	failures  int
+	openUntil time.Time
+	probes    int
+	inflight  int
+	lastUsed  time.Time
+}
+
+type webhookEndpointRegistry struct {
+	mu        sync.Mutex
+	endpoints map[string]*webhookEndpoint
+}
+
+var webhookEndpoints = &webhookEndpointRegistry{endpoints: make(map[string]*webhookEndpoint)}
+
+// webhookEndpointKey 将 URL 归一为端点标识，同一主机不同路径共享熔断状态
+func webhookEndpointKey(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return strings.ToLower(raw)
+	}
+	scheme := strings.ToLower(u.Scheme)
+	host := strings.ToLower(u.Hostname())
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if scheme == "https" {
+			port = "443"
+		}
+	}
+	return scheme + "://" + host + ":" + port
+}
+
+func (r *webhookEndpointRegistry) get(key string) *webhookEndpoint {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e, ok := r.endpoints[key]
+	if !ok {
+		e = &webhookEndpoint{key: key}
+		r.endpoints[key] = e
+	}
+	return e
+}
+
+// prune 清理长时间空闲且健康的端点，避免 map 无限增长
+func (r *webhookEndpointRegistry) prune(now time.Time, idle time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for key, e := range r.endpoints {
+		e.mu.Lock()
+		removable := e.state == breakerClosed && e.inflight == 0 && e.failures == 0 && now.Sub(e.lastUsed) > idle
+		e.mu.Unlock()
+		if removable {
+			delete(r.endpoints, key)
+		}
+	}
+}
+
+// tryAcquire 占用一个并发名额，达到上限时返回 false
+func (e *webhookEndpoint) tryAcquire(limit int, now time.Time) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.lastUsed = now
+	if limit > 0 && e.inflight >= limit {
+		return false
+	}
+	e.inflight++
+	return true
+}
+
+func (e *webhookEndpoint) release() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.inflight > 0 {
+		e.inflight--
+	}
+}
+
+// allow 判断熔断器是否放行；不放行时返回建议的下次尝试时间
+func (e *webhookEndpoint) allow(now time.Time, policy BreakerPolicy) (bool, time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.state == breakerOpen {
+		if now.Before(e.openUntil) {
+			return false, e.openUntil
+		}
+		e.state = breakerHalfOpen
+		e.probes = 0
+		log.Info("webhook endpoint half-open", zap.String("endpoint", e.key))
+	}
+	if e.state == breakerHalfOpen {
+		probes := policy.Probes
+		if probes <= 0 {
+			probes = 1
+		}
+		if e.probes >= probes {
+			return false, now.Add(breakerProbeWait)
+		}
+		e.probes++
+	}
+	return true, time.Time{}
+}
+
+// abandon 放弃一次已放行的尝试（未真正连接端点），只归还半开探测名额
+func (e *webhookEndpoint) abandon() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.state == breakerHalfOpen && e.probes > 0 {
+		e.probes--
+	}
+}
+
+// record 记录一次投递结果并推进熔断状态
+func (e *webhookEndpoint) record(ok bool, now time.Time, policy BreakerPolicy) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	halfOpen := e.state == breakerHalfOpen
+	if halfOpen && e.probes > 0 {
+		e.probes--
+	}
+	if ok {
+		if e.state != breakerClosed {
+			log.Info("webhook endpoint recovered", zap.String("endpoint", e.key))
+		}
+		e.state = breakerClosed
+		e.failures = 0
+		return
+	}
+	e.failures++
+	threshold := policy.Failures
+	if threshold <= 0 {
+		threshold = 5
+	}
+	if halfOpen || e.failures >= threshold {
+		if e.state != breakerOpen {
+			log.Warn("webhook endpoint circuit opened", zap.String("endpoint", e.key), zap.Int("failures", e.failures))
+		}
+		e.state = breakerOpen
+		e.openUntil = now.Add(policy.OpenFor)
+		e.probes = 0
+	}
+}
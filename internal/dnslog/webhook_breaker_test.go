@@ -0,0 +1,85 @@
+package dnslog
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookEndpointBreaker(t *testing.T) {
+	policy := BreakerPolicy{Failures: 2, OpenFor: time.Minute, Probes: 1}
+	e := &webhookEndpoint{key: "https://example.com:443"}
+	now := time.Unix(1700000000, 0)
+
+	ok, _ := e.allow(now, policy)
+	assert.True(t, ok)
+	e.record(false, now, policy)
+	ok, _ = e.allow(now, policy)
+	assert.True(t, ok, "below threshold stays closed")
+	e.record(false, now, policy)
+
+	ok, retryAt := e.allow(now.Add(time.Second), policy)
+	assert.False(t, ok, "threshold reached opens the circuit")
+	assert.Equal(t, now.Add(time.Minute), retryAt)
+
+	// 熔断期满进入半开，只放行一个探测
+	later := now.Add(time.Minute + time.Second)
+	ok, _ = e.allow(later, policy)
+	assert.True(t, ok)
+	ok, _ = e.allow(later, policy)
+	assert.False(t, ok)
+
+	// 探测失败立即重新熔断
+	e.record(false, later, policy)
+	ok, _ = e.allow(later.Add(time.Second), policy)
+	assert.False(t, ok)
+
+	// 再次半开后探测成功则恢复
+	recovered := later.Add(2 * time.Minute)
+	ok, _ = e.allow(recovered, policy)
+	assert.True(t, ok)
+	e.record(true, recovered, policy)
+	assert.Equal(t, breakerClosed, e.state)
+	assert.Equal(t, 0, e.failures)
+}
+
+func TestWebhookEndpointConcurrency(t *testing.T) {
+	e := &webhookEndpoint{}
+	now := time.Now()
+	assert.True(t, e.tryAcquire(2, now))
+	assert.True(t, e.tryAcquire(2, now))
+	assert.False(t, e.tryAcquire(2, now))
+	e.release()
+	assert.True(t, e.tryAcquire(2, now))
+}
+
+func TestWebhookEndpointKey(t *testing.T) {
+	assert.Equal(t, "https://example.com:443", webhookEndpointKey("https://Example.com/a?b=1"))
+	assert.Equal(t, "http://example.com:8080", webhookEndpointKey("http://example.com:8080/hook"))
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	p := RetryPolicy{Schedule: []time.Duration{time.Minute, 10 * time.Minute}, RetryAfterMax: time.Hour}
+	assert.Equal(t, time.Minute, p.Delay(1, 0))
+	assert.Equal(t, 10*time.Minute, p.Delay(2, 0))
+	assert.Equal(t, 10*time.Minute, p.Delay(7, 0), "last entry repeats")
+	assert.Equal(t, 30*time.Minute, p.Delay(1, 30*time.Minute), "longer Retry-After wins")
+	assert.Equal(t, time.Hour, p.Delay(1, 5*time.Hour), "Retry-After is capped")
+
+	p.JitterPercent = 20
+	for i := 0; i < 100; i++ {
+		d := p.Delay(1, 0)
+		assert.GreaterOrEqual(t, d, 48*time.Second)
+		assert.LessOrEqual(t, d, 72*time.Second)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, 120*time.Second, parseRetryAfter("120", now))
+	assert.Equal(t, time.Duration(0), parseRetryAfter("-1", now))
+	assert.Equal(t, 90*time.Second, parseRetryAfter(now.Add(90*time.Second).Format(http.TimeFormat), now))
+	assert.Equal(t, time.Duration(0), parseRetryAfter("soon", now))
+}
@@ -0,0 +1,251 @@
+package dnslog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/genwilliam/dnslog_for_go/config"
+)
+
+var (
+	ErrWebhookURLInvalid       = errors.New("webhook_url_invalid")
+	ErrWebhookSchemeNotAllowed = errors.New("webhook_scheme_not_allowed")
+	ErrWebhookHostDenied       = errors.New("webhook_host_denied")
+	ErrWebhookIPDenied         = errors.New("webhook_ip_denied")
+	ErrWebhookResolveFailed    = errors.New("webhook_resolve_failed")
+)
+
+// WebhookEgressPolicy 约束 webhook 可访问的目标，防止借 webhook 探测内网（SSRF）
+type WebhookEgressPolicy struct {
+	Schemes   []string
+	DenyHosts []string
+	DenyCIDRs []netip.Prefix
+	Resolver  *net.Resolver
+}
+
+var (
+	egressPolicy   *WebhookEgressPolicy
+	egressClient   *http.Client
+	egressPolicyMu sync.Mutex
+)
+
+// NewWebhookEgressPolicy 从配置构建出站策略，非法的 CIDR 直接报错
+func NewWebhookEgressPolicy(cfg *config.Config) (*WebhookEgressPolicy, error) {
+	p := &WebhookEgressPolicy{Resolver: net.DefaultResolver}
+	if cfg == nil {
+		return p, nil
+	}
+	for _, s := range cfg.WebhookAllowedSchemes {
+		if s = strings.ToLower(strings.TrimSpace(s)); s != "" {
+			p.Schemes = append(p.Schemes, s)
+		}
+	}
+	for _, h := range cfg.WebhookDenyHosts {
+		if h = normalizeEgressHost(h); h != "" {
+			p.DenyHosts = append(p.DenyHosts, h)
+		}
+	}
+	for _, c := range cfg.WebhookDenyCIDRs {
+		prefix, err := netip.ParsePrefix(strings.TrimSpace(c))
+		if err != nil {
+			return nil, fmt.Errorf("invalid webhook deny cidr %q: %w", c, err)
+		}
+		p.DenyCIDRs = append(p.DenyCIDRs, prefix.Masked())
+	}
+	return p, nil
+}
+
+// InitWebhookEgressPolicy 启动时加载出站策略，配置非法时返回错误
+func InitWebhookEgressPolicy(cfg *config.Config) error {
+	p, err := NewWebhookEgressPolicy(cfg)
+	if err != nil {
+		return err
+	}
+	egressPolicyMu.Lock()
+	egressPolicy = p
+	egressClient = nil
+	egressPolicyMu.Unlock()
+	return nil
+}
+
+// webhookEgress 返回进程级出站策略；未初始化且配置非法时返回拒绝一切的策略
+func webhookEgress() *WebhookEgressPolicy {
+	egressPolicyMu.Lock()
+	defer egressPolicyMu.Unlock()
+	if egressPolicy == nil {
+		p, err := NewWebhookEgressPolicy(config.Get())
+		if err != nil {
+			p = &WebhookEgressPolicy{}
+		}
+		egressPolicy = p
+	}
+	return egressPolicy
+}
+
+// webhookHTTPClient 返回复用连接的 webhook 投递客户端
+func webhookHTTPClient() *http.Client {
+	p := webhookEgress()
+	egressPolicyMu.Lock()
+	defer egressPolicyMu.Unlock()
+	if egressClient == nil {
+		egressClient = p.HTTPClient(5 * time.Second)
+	}
+	return egressClient
+}
+
+// CheckURL 校验 scheme 与主机名，不做 DNS 解析
+func (p *WebhookEgressPolicy) CheckURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Host == "" || u.Hostname() == "" {
+		return nil, ErrWebhookURLInvalid
+	}
+	if u.User != nil {
+		return nil, ErrWebhookURLInvalid
+	}
+	scheme := strings.ToLower(u.Scheme)
+	if !p.schemeAllowed(scheme) {
+		return nil, ErrWebhookSchemeNotAllowed
+	}
+	if p.hostDenied(u.Hostname()) {
+		return nil, ErrWebhookHostDenied
+	}
+	return u, nil
+}
+
+// Validate 保存 webhook 时调用：校验 URL 并确认当前解析结果全部落在允许范围内
+func (p *WebhookEgressPolicy) Validate(ctx context.Context, raw string) error {
+	u, err := p.CheckURL(raw)
+	if err != nil {
+		return err
+	}
+	_, err = p.resolve(ctx, u.Hostname())
+	return err
+}
+
+// CheckIP 判断单个地址是否被禁止
+func (p *WebhookEgressPolicy) CheckIP(addr netip.Addr) error {
+	addr = addr.Unmap()
+	for _, prefix := range p.DenyCIDRs {
+		if prefix.Contains(addr) {
+			return ErrWebhookIPDenied
+		}
+	}
+	return nil
+}
+
+// resolve 解析主机并校验所有地址；任一地址被禁止即拒绝，避免多 A 记录混入内网地址
+func (p *WebhookEgressPolicy) resolve(ctx context.Context, host string) ([]netip.Addr, error) {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		if err := p.CheckIP(addr); err != nil {
+			return nil, err
+		}
+		return []netip.Addr{addr.Unmap()}, nil
+	}
+	resolver := p.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	ctx, cancel := context.WithTimeout(ensureContext(ctx), 3*time.Second)
+	defer cancel()
+	addrs, err := resolver.LookupNetIP(ctx, "ip", host)
+	if err != nil || len(addrs) == 0 {
+		return nil, ErrWebhookResolveFailed
+	}
+	out := make([]netip.Addr, 0, len(addrs))
+	for _, a := range addrs {
+		if err := p.CheckIP(a); err != nil {
+			return nil, err
+		}
+		out = append(out, a.Unmap())
+	}
+	return out, nil
+}
+
+// DialContext 先解析并校验，再直接连接已校验的 IP（resolve-then-pin），防止 DNS rebinding
+func (p *WebhookEgressPolicy) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	if p.hostDenied(host) {
+		return nil, ErrWebhookHostDenied
+	}
+	addrs, err := p.resolve(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	var lastErr error
+	for _, a := range addrs {
+		conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(a.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// HTTPClient 返回受策略约束的 HTTP 客户端：不走环境代理，重定向目标同样校验
+func (p *WebhookEgressPolicy) HTTPClient(timeout time.Duration) *http.Client {
+	transport := &http.Transport{
+		Proxy:                 nil,
+		DialContext:           p.DialContext,
+		TLSHandshakeTimeout:   5 * time.Second,
+		ResponseHeaderTimeout: timeout,
+		MaxIdleConnsPerHost:   4,
+		IdleConnTimeout:       90 * time.Second,
+	}
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 3 {
+				return http.ErrUseLastResponse
+			}
+			_, err := p.CheckURL(req.URL.String())
+			return err
+		},
+	}
+}
+
+// IsWebhookEgressError 判断错误是否由出站策略拒绝（此类失败不应重试）
+func IsWebhookEgressError(err error) bool {
+	return errors.Is(err, ErrWebhookURLInvalid) ||
+		errors.Is(err, ErrWebhookSchemeNotAllowed) ||
+		errors.Is(err, ErrWebhookHostDenied) ||
+		errors.Is(err, ErrWebhookIPDenied)
+}
+
+func (p *WebhookEgressPolicy) schemeAllowed(scheme string) bool {
+	for _, s := range p.Schemes {
+		if s == scheme {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *WebhookEgressPolicy) hostDenied(host string) bool {
+	host = normalizeEgressHost(host)
+	for _, h := range p.DenyHosts {
+		if host == h || strings.HasSuffix(host, "."+h) {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeEgressHost(host string) string {
+	host = strings.ToLower(strings.TrimSpace(host))
+	host = strings.TrimPrefix(host, "*.")
+	return strings.TrimSuffix(host, ".")
+}
@@ -3,24 +3,23 @@ package dnslog
 import (
 	"bytes"
 	"context"
-	"crypto/hmac"
-	"crypto/sha256"
-	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"net/http"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/genwilliam/dnslog_for_go/config"
-	"github.com/genwilliam/dnslog_for_go/internal/infra"
 	"github.com/genwilliam/dnslog_for_go/pkg/log"
+	"github.com/genwilliam/dnslog_for_go/pkg/webhook"
 	"go.uber.org/zap"
 )
 
 const webhookQueueKey = "webhook:queue"
 
-func MaybeEnqueueWebhook(token string, isFirst bool, domain string) error {
+// MaybeEnqueueWebhook 按 token 的 webhook 配置投递命中事件；protocol 为命中来源（dns/http/https 等）
+func MaybeEnqueueWebhook(token string, isFirst bool, domain, protocol string) error {
 	cfg := config.Get()
 	if cfg == nil || !cfg.WebhookEnabled {
 		return nil
@@ -40,6 +39,7 @@ func MaybeEnqueueWebhook(token string, isFirst bool, domain string) error {
 	payloadMap := map[string]interface{}{
 		"token":     token,
 		"domain":    domain,
+		"protocol":  protocol,
 		"hit_count": 1,
 		"timestamp": time.Now().UnixMilli(),
 	}
@@ -49,11 +49,21 @@ func MaybeEnqueueWebhook(token string, isFirst bool, domain string) error {
 	if err != nil {
 		return err
 	}
+	// 轮换重叠期内同时保留旧 secret，投递时双签
+	encPrevSecret := ""
+	if secrets := hook.ActiveSecrets(time.Now().UnixMilli()); len(secrets) > 1 {
+		encPrevSecret, err = EncryptWebhookSecret(secrets[1])
+		if err != nil {
+			return err
+		}
+	}
 	job := WebhookJob{
+		Kind:        WebhookJobKindToken,
 		Token:       token,
 		URL:         hook.URL,
 		Payload:     string(payloadBytes),
 		Secret:      encSecret,
+		PrevSecret:  encPrevSecret,
 		NextRetryAt: time.Now().UnixMilli(),
 		CreatedAt:   time.Now().UnixMilli(),
 		UpdatedAt:   time.Now().UnixMilli(),
@@ -65,47 +75,68 @@ func MaybeEnqueueWebhook(token string, isFirst bool, domain string) error {
 	return EnqueueWebhookJob(jobID)
 }
 
+// EnqueueWebhookJob 任务已落库，入队失败时由到期扫描补投
 func EnqueueWebhookJob(jobID int64) error {
-	client := infra.GetRedis()
-	if client == nil {
-		return errors.New("redis not initialized")
+	q, err := getQueue(webhookQueueKey)
+	if err != nil {
+		return err
 	}
 	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
 	defer cancel()
-	return client.LPush(ctx, webhookQueueKey, jobID).Err()
+	return q.Push(ctx, strconv.FormatInt(jobID, 10))
 }
 
+// StartWebhookWorkers 启动 webhook 投递 worker 池与到期任务扫描
 func StartWebhookWorkers() {
-	client := infra.GetRedis()
-	if client == nil {
+	q, err := getQueue(webhookQueueKey)
+	if err != nil {
+		log.Error("webhook queue unavailable", zap.Error(err))
 		return
 	}
+	log.Info("webhook workers started", zap.String("queue", q.Backend()))
+	cfg := config.Get()
+	workers := cfg.WebhookWorkers
+	if workers <= 0 {
+		workers = 4
+	}
+	jobs := make(chan int64, workers)
+
 	go func() {
 		for {
-			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-			res, err := client.BRPop(ctx, 3*time.Second, webhookQueueKey).Result()
-			cancel()
-			if err != nil || len(res) < 2 {
+			val, ok, err := q.Pop(context.Background(), 3*time.Second)
+			if err != nil {
+				time.Sleep(time.Second)
+				continue
+			}
+			if !ok {
 				continue
 			}
-			jobID, err := parseInt64(res[1])
+			jobID, err := parseInt64(val)
 			if err != nil {
 				continue
 			}
-			processWebhookJob(jobID)
+			jobs <- jobID
 		}
 	}()
 
+	for i := 0; i < workers; i++ {
+		go func() {
+			for jobID := range jobs {
+				processWebhookJob(jobID)
+			}
+		}()
+	}
+
 	go func() {
-		interval := time.Duration(config.Get().WebhookRetryIntervalSeconds) * time.Second
+		interval := time.Duration(cfg.WebhookRetryIntervalSeconds) * time.Second
 		if interval <= 0 {
 			interval = 30 * time.Second
 		}
 		ticker := time.NewTicker(interval)
 		defer ticker.Stop()
 		for range ticker.C {
-			nowMs := time.Now().UnixMilli()
-			ids, err := ListDueWebhookJobs(nowMs, 200)
+			now := time.Now()
+			ids, err := ListDueWebhookJobs(now.UnixMilli(), 200)
 			if err != nil {
 				log.Error("list due webhook jobs failed", zap.Error(err))
 				continue
@@ -113,11 +144,20 @@ func StartWebhookWorkers() {
 			for _, id := range ids {
 				_ = EnqueueWebhookJob(id)
 			}
+			webhookEndpoints.prune(now, 10*time.Minute)
 		}
 	}()
 }
 
+// inflightWebhookJobs 防止同一任务被本进程的多个 worker 同时投递
+var inflightWebhookJobs sync.Map
+
 func processWebhookJob(jobID int64) {
+	if _, loaded := inflightWebhookJobs.LoadOrStore(jobID, struct{}{}); loaded {
+		return
+	}
+	defer inflightWebhookJobs.Delete(jobID)
+
 	cfg := config.Get()
 	job, err := GetWebhookJob(jobID)
 	if err != nil || job.Status != "PENDING" {
@@ -127,31 +167,83 @@ func processWebhookJob(jobID int64) {
 		return
 	}
 
-	client := &http.Client{Timeout: 5 * time.Second}
+	// 端点并发已满：稍后重新入队，不占用 worker 等待
+	endpoint := webhookEndpoints.get(webhookEndpointKey(job.URL))
+	if !endpoint.tryAcquire(cfg.WebhookEndpointConcurrency, time.Now()) {
+		time.AfterFunc(time.Second, func() {
+			_ = EnqueueWebhookJob(jobID)
+		})
+		return
+	}
+	defer endpoint.release()
+
+	client := webhookHTTPClient()
 	secret, err := DecryptWebhookSecret(job.Secret)
 	if err != nil {
 		nowMs := time.Now().UnixMilli()
 		_ = UpdateWebhookJob(jobID, "FAILED", job.RetryCount, job.NextRetryAt, nowMs)
 		return
 	}
+	prevSecret, err := DecryptWebhookSecret(job.PrevSecret)
+	if err != nil {
+		nowMs := time.Now().UnixMilli()
+		_ = UpdateWebhookJob(jobID, "FAILED", job.RetryCount, job.NextRetryAt, nowMs)
+		return
+	}
 	req, err := http.NewRequest("POST", job.URL, bytes.NewBufferString(job.Payload))
 	if err != nil {
 		nowMs := time.Now().UnixMilli()
 		_ = UpdateWebhookJob(jobID, "FAILED", job.RetryCount, job.NextRetryAt, nowMs)
 		return
 	}
+	// 自定义头与认证在投递时按当前配置读取，凭据轮换后待重试任务也使用新凭据
+	if job.usesTokenWebhook() {
+		if hook, err := GetTokenWebhook(job.Token); err == nil {
+			applyWebhookAuth(req, hook.Headers, hook.Auth)
+		} else if !errors.Is(err, ErrWebhookNotFound) {
+			_ = UpdateWebhookJob(jobID, "PENDING", job.RetryCount, time.Now().Add(time.Minute).UnixMilli(), time.Now().UnixMilli())
+			return
+		}
+	}
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-Event-ID", strconv.FormatInt(jobID, 10))
+	req.Header.Set(webhook.HeaderEventID, strconv.FormatInt(jobID, 10))
 	if secret != "" {
-		req.Header.Set("X-Signature", signWebhook(job.Payload, secret))
+		payload := []byte(job.Payload)
+		// 时间戳在每次投递时重新生成，重试不会被接收端当作重放拒绝
+		webhook.SetHeaders(req.Header, time.Now().Unix(), payload, secret, prevSecret)
+		if cfg.WebhookLegacySignature {
+			req.Header.Set(webhook.HeaderLegacySignature, webhook.SignLegacy(payload, secret))
+		}
+	}
+
+	// 熔断打开：推迟到恢复时间，不计入重试次数
+	breaker := newBreakerPolicy(cfg)
+	if ok, retryAt := endpoint.allow(time.Now(), breaker); !ok {
+		_ = UpdateWebhookJob(jobID, "PENDING", job.RetryCount, retryAt.UnixMilli(), time.Now().UnixMilli())
+		return
 	}
 	resp, err := client.Do(req)
-	if err == nil && resp != nil && resp.Body != nil {
-		_ = resp.Body.Close()
+	statusCode := 0
+	var retryAfter time.Duration
+	if err == nil && resp != nil {
+		statusCode = resp.StatusCode
+		retryAfter = parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+		if resp.Body != nil {
+			_ = resp.Body.Close()
+		}
 	}
 
-	nowMs := time.Now().UnixMilli()
-	if err == nil && resp != nil && resp.StatusCode >= 200 && resp.StatusCode < 300 {
+	now := time.Now()
+	nowMs := now.UnixMilli()
+	if IsWebhookEgressError(err) {
+		// 目标被出站策略拒绝（如解析到内网地址），重试没有意义
+		endpoint.abandon()
+		log.Warn("webhook blocked by egress policy", zap.Int64("job_id", jobID), zap.String("token", job.Token), zap.Error(err))
+		_ = UpdateWebhookJob(jobID, "FAILED", job.RetryCount, job.NextRetryAt, nowMs)
+		return
+	}
+	endpoint.record(!isEndpointFailure(err, statusCode), now, breaker)
+	if err == nil && statusCode >= 200 && statusCode < 300 {
 		_ = UpdateWebhookJob(jobID, "SUCCESS", job.RetryCount, job.NextRetryAt, nowMs)
 		return
 	}
@@ -162,27 +254,40 @@ func processWebhookJob(jobID int64) {
 		return
 	}
 
-	nextRetry := nowMs + retryBackoff(retryCount)
+	nextRetry := now.Add(newRetryPolicy(cfg).Delay(retryCount, retryAfter)).UnixMilli()
 	_ = UpdateWebhookJob(jobID, "PENDING", retryCount, nextRetry, nowMs)
 }
 
-func signWebhook(payload, secret string) string {
-	mac := hmac.New(sha256.New, []byte(secret))
-	mac.Write([]byte(payload))
-	return hex.EncodeToString(mac.Sum(nil))
+// isEndpointFailure 判断结果是否说明端点不可用（计入熔断）；普通 4xx 说明端点在线
+func isEndpointFailure(err error, statusCode int) bool {
+	if err != nil {
+		return true
+	}
+	return statusCode >= 500 || statusCode == http.StatusTooManyRequests || statusCode == http.StatusRequestTimeout
+}
+
+func newBreakerPolicy(cfg *config.Config) BreakerPolicy {
+	p := BreakerPolicy{Failures: 5, OpenFor: time.Minute, Probes: 1}
+	if cfg == nil {
+		return p
+	}
+	if cfg.WebhookBreakerFailures > 0 {
+		p.Failures = cfg.WebhookBreakerFailures
+	}
+	if cfg.WebhookBreakerOpenSeconds > 0 {
+		p.OpenFor = time.Duration(cfg.WebhookBreakerOpenSeconds) * time.Second
+	}
+	if cfg.WebhookBreakerProbes > 0 {
+		p.Probes = cfg.WebhookBreakerProbes
+	}
+	return p
 }
 
-func retryBackoff(retry int) int64 {
-	switch retry {
-	case 1:
-		return int64(60 * 1000)
-	case 2:
-		return int64(5 * 60 * 1000)
-	case 3:
-		return int64(15 * 60 * 1000)
-	default:
-		return int64(60 * 60 * 1000)
+func webhookSecretOverlapMs(cfg *config.Config) int64 {
+	if cfg == nil || cfg.WebhookSecretOverlapSeconds <= 0 {
+		return int64(86400 * 1000)
 	}
+	return int64(cfg.WebhookSecretOverlapSeconds) * 1000
 }
 
 func parseInt64(val string) (int64, error) {
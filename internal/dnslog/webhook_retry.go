@@ -0,0 +1,94 @@
+package dnslog
+
+import (
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/genwilliam/dnslog_for_go/config"
+)
+
+// RetryPolicy webhook 重试策略
+type RetryPolicy struct {
+	Schedule      []time.Duration // 第 N 次重试的等待，超出后沿用最后一项
+	JitterPercent int             // 随机抖动百分比（±）
+	RetryAfterMax time.Duration   // 服务端 Retry-After 的上限
+}
+
+var defaultRetrySchedule = []time.Duration{time.Minute, 5 * time.Minute, 15 * time.Minute, time.Hour}
+
+func newRetryPolicy(cfg *config.Config) RetryPolicy {
+	p := RetryPolicy{Schedule: defaultRetrySchedule, RetryAfterMax: time.Hour}
+	if cfg == nil {
+		return p
+	}
+	schedule := make([]time.Duration, 0, len(cfg.WebhookRetryScheduleSeconds))
+	for _, sec := range cfg.WebhookRetryScheduleSeconds {
+		if sec > 0 {
+			schedule = append(schedule, time.Duration(sec)*time.Second)
+		}
+	}
+	if len(schedule) > 0 {
+		p.Schedule = schedule
+	}
+	if cfg.WebhookRetryJitterPercent > 0 && cfg.WebhookRetryJitterPercent <= 100 {
+		p.JitterPercent = cfg.WebhookRetryJitterPercent
+	}
+	if cfg.WebhookRetryAfterMaxSeconds > 0 {
+		p.RetryAfterMax = time.Duration(cfg.WebhookRetryAfterMaxSeconds) * time.Second
+	}
+	return p
+}
+
+// Delay 计算第 retry 次重试前的等待；服务端给出更长的 Retry-After 时以其为准
+func (p RetryPolicy) Delay(retry int, retryAfter time.Duration) time.Duration {
+	schedule := p.Schedule
+	if len(schedule) == 0 {
+		schedule = defaultRetrySchedule
+	}
+	idx := retry - 1
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= len(schedule) {
+		idx = len(schedule) - 1
+	}
+	delay := schedule[idx]
+	if p.JitterPercent > 0 {
+		span := int64(delay) * int64(p.JitterPercent) / 100
+		if span > 0 {
+			delay += time.Duration(rand.Int64N(2*span+1) - span)
+		}
+	}
+	if retryAfter > 0 {
+		if p.RetryAfterMax > 0 && retryAfter > p.RetryAfterMax {
+			retryAfter = p.RetryAfterMax
+		}
+		if retryAfter > delay {
+			delay = retryAfter
+		}
+	}
+	return delay
+}
+
+// parseRetryAfter 解析 Retry-After（秒数或 HTTP 日期）
+func parseRetryAfter(val string, now time.Time) time.Duration {
+	val = strings.TrimSpace(val)
+	if val == "" {
+		return 0
+	}
+	if sec, err := strconv.Atoi(val); err == nil {
+		if sec <= 0 {
+			return 0
+		}
+		return time.Duration(sec) * time.Second
+	}
+	if t, err := http.ParseTime(val); err == nil {
+		if d := t.Sub(now); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
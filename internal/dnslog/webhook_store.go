@@ -8,31 +8,57 @@ import (
 )
 
 type TokenWebhook struct {
-	ID        int64
-	Token     string
-	URL       string
-	Secret    string
-	Mode      string
-	Enabled   bool
-	CreatedAt int64
+	ID                  int64
+	Token               string
+	URL                 string
+	Secret              string
+	PrevSecret          string
+	PrevSecretExpiresAt int64
+	Headers             map[string]string
+	Auth                WebhookAuth
+	Mode                string
+	Enabled             bool
+	CreatedAt           int64
 }
 
+// webhook 任务类型：token 命中通知使用该 token 绑定的自定义头与认证，管理事件不使用
+const (
+	WebhookJobKindToken      = "token"
+	WebhookJobKindAdminEvent = "admin_event"
+)
+
 type WebhookJob struct {
-	ID         int64
-	Token      string
-	URL        string
-	Payload    string
-	Secret     string
-	Status     string
-	RetryCount int
+	ID          int64
+	Kind        string
+	Token       string
+	URL         string
+	Payload     string
+	Secret      string
+	PrevSecret  string
+	Status      string
+	RetryCount  int
 	NextRetryAt int64
-	CreatedAt  int64
-	UpdatedAt  int64
+	CreatedAt   int64
+	UpdatedAt   int64
 }
 
 var ErrWebhookNotFound = errors.New("webhook_not_found")
 
-func UpsertTokenWebhookWithContext(ctx context.Context, token, url, secret, mode string, nowMs int64) error {
+// ActiveSecrets 返回当前应参与签名的 secret（新 secret 在前，旧 secret 仅在重叠期内返回）
+func (w TokenWebhook) ActiveSecrets(nowMs int64) []string {
+	secrets := make([]string, 0, 2)
+	if w.Secret != "" {
+		secrets = append(secrets, w.Secret)
+	}
+	if w.PrevSecret != "" && w.PrevSecret != w.Secret && nowMs < w.PrevSecretExpiresAt {
+		secrets = append(secrets, w.PrevSecret)
+	}
+	return secrets
+}
+
+// UpsertTokenWebhookWithContext 写入 webhook；secret 变化时旧 secret 保留 overlapMs 用于双签
+// 自定义头与认证凭据与 secret 一样加密落库
+func UpsertTokenWebhookWithContext(ctx context.Context, token, url, secret, mode string, headers map[string]string, auth WebhookAuth, nowMs, overlapMs int64) error {
 	if db == nil {
 		return errors.New("store not initialized")
 	}
@@ -40,19 +66,67 @@ func UpsertTokenWebhookWithContext(ctx context.Context, token, url, secret, mode
 	if err != nil {
 		return err
 	}
+	encHeaders, err := encryptWebhookHeaders(headers)
+	if err != nil {
+		return err
+	}
+	encAuth, err := encryptWebhookAuth(auth)
+	if err != nil {
+		return err
+	}
+	authType := auth.Type
+	if authType == "" {
+		authType = WebhookAuthNone
+	}
 	ctx = ensureContext(ctx)
 	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
-	_, err = db.ExecContext(ctx, `
-INSERT INTO token_webhooks (token, webhook_url, secret, mode, enabled, created_at)
-VALUES (?, ?, ?, ?, 1, ?)
-ON DUPLICATE KEY UPDATE webhook_url = VALUES(webhook_url), secret = VALUES(secret), mode = VALUES(mode), enabled = 1
-`, token, url, encSecret, mode, nowMs)
-	return err
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	var oldSecret, prevSecret string
+	var prevExpiresAt int64
+	err = tx.QueryRowContext(ctx, `
+SELECT secret, prev_secret, prev_secret_expires_at
+FROM token_webhooks
+WHERE token = ?
+FOR UPDATE
+`, token).Scan(&oldSecret, &prevSecret, &prevExpiresAt)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	if oldSecret != "" {
+		oldPlain, err := DecryptWebhookSecret(oldSecret)
+		if err != nil {
+			return err
+		}
+		if oldPlain != secret {
+			prevSecret = oldSecret
+			prevExpiresAt = nowMs + overlapMs
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `
+INSERT INTO token_webhooks (token, webhook_url, secret, prev_secret, prev_secret_expires_at, headers, auth_type, auth_credentials, mode, enabled, created_at)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, 1, ?)
+ON DUPLICATE KEY UPDATE webhook_url = VALUES(webhook_url), secret = VALUES(secret), prev_secret = VALUES(prev_secret),
+  prev_secret_expires_at = VALUES(prev_secret_expires_at), headers = VALUES(headers), auth_type = VALUES(auth_type),
+  auth_credentials = VALUES(auth_credentials), mode = VALUES(mode), enabled = 1
+`, token, url, encSecret, prevSecret, prevExpiresAt, encHeaders, authType, encAuth, mode, nowMs)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-func UpsertTokenWebhook(token, url, secret, mode string, nowMs int64) error {
-	return UpsertTokenWebhookWithContext(context.Background(), token, url, secret, mode, nowMs)
+func UpsertTokenWebhook(token, url, secret, mode string, headers map[string]string, auth WebhookAuth, nowMs, overlapMs int64) error {
+	return UpsertTokenWebhookWithContext(context.Background(), token, url, secret, mode, headers, auth, nowMs, overlapMs)
 }
 
 func GetTokenWebhookWithContext(ctx context.Context, token string) (TokenWebhook, error) {
@@ -64,14 +138,18 @@ func GetTokenWebhookWithContext(ctx context.Context, token string) (TokenWebhook
 	defer cancel()
 	var w TokenWebhook
 	var enabled int
+	var encHeaders, authType, encAuth string
 	err := db.QueryRowContext(ctx, `
-SELECT id, token, webhook_url, secret, mode, enabled, created_at
+SELECT id, token, webhook_url, secret, prev_secret, prev_secret_expires_at, COALESCE(headers, ''), auth_type, COALESCE(auth_credentials, ''), mode, enabled, created_at
 FROM token_webhooks
 WHERE token = ?
-`, token).Scan(&w.ID, &w.Token, &w.URL, &w.Secret, &w.Mode, &enabled, &w.CreatedAt)
+`, token).Scan(&w.ID, &w.Token, &w.URL, &w.Secret, &w.PrevSecret, &w.PrevSecretExpiresAt, &encHeaders, &authType, &encAuth, &w.Mode, &enabled, &w.CreatedAt)
 	if errors.Is(err, sql.ErrNoRows) {
 		return TokenWebhook{}, ErrWebhookNotFound
 	}
+	if err != nil {
+		return TokenWebhook{}, err
+	}
 	w.Enabled = enabled == 1
 	if w.Secret != "" {
 		plain, err := DecryptWebhookSecret(w.Secret)
@@ -80,7 +158,20 @@ WHERE token = ?
 		}
 		w.Secret = plain
 	}
-	return w, err
+	if w.PrevSecret != "" {
+		plain, err := DecryptWebhookSecret(w.PrevSecret)
+		if err != nil {
+			return TokenWebhook{}, err
+		}
+		w.PrevSecret = plain
+	}
+	if w.Headers, err = decryptWebhookHeaders(encHeaders); err != nil {
+		return TokenWebhook{}, err
+	}
+	if w.Auth, err = decryptWebhookAuth(authType, encAuth); err != nil {
+		return TokenWebhook{}, err
+	}
+	return w, nil
 }
 
 func GetTokenWebhook(token string) (TokenWebhook, error) {
@@ -109,10 +200,13 @@ func CreateWebhookJobWithContext(ctx context.Context, job WebhookJob) (int64, er
 	ctx = ensureContext(ctx)
 	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
+	if job.Kind == "" {
+		job.Kind = WebhookJobKindToken
+	}
 	res, err := db.ExecContext(ctx, `
-INSERT INTO webhook_jobs (token, url, payload, secret, status, retry_count, next_retry_at, created_at, updated_at)
-VALUES (?, ?, ?, ?, 'PENDING', 0, ?, ?, ?)
-`, job.Token, job.URL, job.Payload, job.Secret, job.NextRetryAt, job.CreatedAt, job.UpdatedAt)
+INSERT INTO webhook_jobs (kind, token, url, payload, secret, prev_secret, status, retry_count, next_retry_at, created_at, updated_at)
+VALUES (?, ?, ?, ?, ?, ?, 'PENDING', 0, ?, ?, ?)
+`, job.Kind, job.Token, job.URL, job.Payload, job.Secret, job.PrevSecret, job.NextRetryAt, job.CreatedAt, job.UpdatedAt)
 	if err != nil {
 		return 0, err
 	}
@@ -133,13 +227,19 @@ func GetWebhookJobWithContext(ctx context.Context, id int64) (WebhookJob, error)
 	defer cancel()
 	var job WebhookJob
 	err := db.QueryRowContext(ctx, `
-SELECT id, token, url, payload, secret, status, retry_count, next_retry_at, created_at, updated_at
+SELECT id, kind, token, url, payload, secret, prev_secret, status, retry_count, next_retry_at, created_at, updated_at
 FROM webhook_jobs
 WHERE id = ?
-`, id).Scan(&job.ID, &job.Token, &job.URL, &job.Payload, &job.Secret, &job.Status, &job.RetryCount, &job.NextRetryAt, &job.CreatedAt, &job.UpdatedAt)
+`, id).Scan(&job.ID, &job.Kind, &job.Token, &job.URL, &job.Payload, &job.Secret, &job.PrevSecret, &job.Status, &job.RetryCount, &job.NextRetryAt, &job.CreatedAt, &job.UpdatedAt)
 	return job, err
 }
 
+// usesTokenWebhook 是否在投递时读取 token 绑定的自定义头与认证；只有 token 任务会，
+// 避免有人给占位 token 绑定 webhook 而把自己的头注入到管理事件投递中
+func (j WebhookJob) usesTokenWebhook() bool {
+	return j.Kind == WebhookJobKindToken
+}
+
 func GetWebhookJob(id int64) (WebhookJob, error) {
 	return GetWebhookJobWithContext(context.Background(), id)
 }
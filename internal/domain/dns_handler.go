@@ -99,6 +99,7 @@ func SubmitDomain(c *gin.Context) {
 		Page:     1,
 		PageSize: cfg.MaxPageSize,
 		Domain:   normalizedDomain,
+		Tenant:   dnslog.RequestListTenant(c),
 	}
 	items, total, err := dnslog.ListRecordsWithContext(c.Request.Context(), filter)
 	if err != nil {
@@ -138,7 +139,8 @@ func RandomDomain(c *gin.Context) {
 		return
 	}
 
-	domainName, token, err := GenerateAndInitDomainWithContext(c.Request.Context())
+	// token 归属于创建它的 API Key 及其租户
+	domainName, token, err := GenerateAndInitDomainWithContext(c.Request.Context(), dnslog.RequestTokenOwner(c))
 	if err != nil {
 		log.Error("生成域名失败", zap.Error(err))
 		response.Error(c, 500, response.CodeInternalError)
@@ -167,8 +169,10 @@ func ChangeServer(c *gin.Context) {
 		return
 	}
 
+	before := cfg.CurrentUpstream()
 	cfg.SetUpstreamIndex(dnsRequest.Num)
 	server := cfg.CurrentUpstream()
+	dnslog.RecordAdminEvent(c, dnslog.AdminActionUpstreamChange, "upstream", gin.H{"server": before}, gin.H{"server": server, "num": dnsRequest.Num})
 	log.Info("DNS 服务器已更改为", zap.String("server", server))
 	response.Success(c, gin.H{"message": "DNS 服务器已更改为 " + server})
 }
@@ -183,14 +187,17 @@ func ChangePact(c *gin.Context) {
 	}
 
 	cfg := config.Get()
+	before := cfg.GetProtocol()
 
 	switch pactRequest.Pact {
 	case "udp":
 		cfg.SetProtocol("udp")
+		dnslog.RecordAdminEvent(c, dnslog.AdminActionProtocolChange, "protocol", gin.H{"protocol": before}, gin.H{"protocol": "udp"})
 		log.Info("协议已更改为 UDP")
 		response.Success(c, gin.H{"message": "协议已更改为 UDP"})
 	case "tcp":
 		cfg.SetProtocol("tcp")
+		dnslog.RecordAdminEvent(c, dnslog.AdminActionProtocolChange, "protocol", gin.H{"protocol": before}, gin.H{"protocol": "tcp"})
 		log.Info("协议已更改为 TCP")
 		response.Success(c, gin.H{"message": "协议已更改为 TCP"})
 	default:
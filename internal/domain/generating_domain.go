@@ -34,7 +34,7 @@ func GeneratingDomain() string {
 }
 
 // GenerateAndInitDomain 生成域名并写入 token 状态表（INIT）
-func GenerateAndInitDomainWithContext(ctx context.Context) (string, string, error) {
+func GenerateAndInitDomainWithContext(ctx context.Context, owner dnslog.TokenOwner) (string, string, error) {
 	cfg := config.Get()
 	nowMs := time.Now().UnixMilli()
 	ttlMs := int64(cfg.TokenTTLSeconds) * 1000
@@ -57,7 +57,7 @@ func GenerateAndInitDomainWithContext(ctx context.Context) (string, string, erro
 		domain := fmt.Sprintf("%s.%s", token, root)
 		expiresAt := nowMs + ttlMs
 
-		if err := dnslog.CreateTokenInitWithContext(ctx, token, domain, nowMs, expiresAt); err != nil {
+		if err := dnslog.CreateTokenInitWithContext(ctx, token, domain, owner, nowMs, expiresAt); err != nil {
 			if dnslog.IsDuplicateKeyError(err) {
 				log.Warn("token 冲突，准备重试", zap.Error(err))
 				continue
@@ -72,7 +72,7 @@ func GenerateAndInitDomainWithContext(ctx context.Context) (string, string, erro
 
 // GenerateAndInitDomain 生成域名并写入 token 状态表（INIT）
 func GenerateAndInitDomain() (string, string, error) {
-	return GenerateAndInitDomainWithContext(context.Background())
+	return GenerateAndInitDomainWithContext(context.Background(), dnslog.TokenOwner{})
 }
 
 func selectRootDomain(cfg *config.Config) string {
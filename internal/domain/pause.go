@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"sync/atomic"
 
+	"github.com/genwilliam/dnslog_for_go/internal/dnslog"
 	"github.com/genwilliam/dnslog_for_go/pkg/log"
 	"github.com/genwilliam/dnslog_for_go/pkg/response"
 
@@ -27,7 +28,15 @@ func InitPause(c *gin.Context) {
 		return
 	}
 
+	before := paused.Load()
 	PauseHandler(req) // 传入请求数据
+	if after := paused.Load(); after != before {
+		action := dnslog.AdminActionSystemStart
+		if after {
+			action = dnslog.AdminActionSystemPause
+		}
+		dnslog.RecordAdminEvent(c, action, "system", gin.H{"paused": before}, gin.H{"paused": after})
+	}
 
 	if paused.Load() {
 		response.Success(c, gin.H{"message": "System paused"})
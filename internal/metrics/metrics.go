@@ -23,8 +23,22 @@ var (
 			Help: "Total token hits recorded",
 		},
 	)
+	AutoBansTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "dnslog_auto_bans_total",
+			Help: "Total client IPs banned automatically for repeated rate-limit violations",
+		},
+		[]string{"scope"},
+	)
+	InteractionsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "dnslog_interactions_total",
+			Help: "Total non-DNS interactions captured",
+		},
+		[]string{"protocol"},
+	)
 )
 
 func Init() {
-	prometheus.MustRegister(APIRequestsTotal, DNSQueriesTotal, TokenHitsTotal)
+	prometheus.MustRegister(APIRequestsTotal, DNSQueriesTotal, TokenHitsTotal, AutoBansTotal, InteractionsTotal)
 }
@@ -16,9 +16,28 @@ import (
 
 const apiKeyHeader = "X-API-Key"
 
-func APIKeyAuth(cfg *config.Config) gin.HandlerFunc {
+// RoutePermissions 路由所需 scope，key 为 "METHOD /full/path"（与 gin FullPath 一致）
+type RoutePermissions map[string]string
+
+// Require 登记路由所需 scope
+func (p RoutePermissions) Require(method, path, scope string) {
+	p[method+" "+path] = scope
+}
+
+// Scope 返回路由所需 scope；未登记的路由只允许 "*" 访问，登记为 "" 表示仅需登录
+func (p RoutePermissions) Scope(method, path string) (string, bool) {
+	scope, ok := p[method+" "+path]
+	return scope, ok
+}
+
+func APIKeyAuth(cfg *config.Config, perms RoutePermissions) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		key := strings.TrimSpace(c.GetHeader(apiKeyHeader))
+		cookie, _ := c.Cookie(dnslog.SessionCookieName)
 		if cfg == nil || !cfg.APIKeyRequired {
+			if key == "" && cookie != "" {
+				identifySession(c, cookie)
+			}
 			c.Next()
 			return
 		}
@@ -31,23 +50,36 @@ func APIKeyAuth(cfg *config.Config) gin.HandlerFunc {
 			}
 		}
 
-		key := strings.TrimSpace(c.GetHeader(apiKeyHeader))
-		if key == "" {
-			debugAuth(c, "", "", false, false)
-			response.Error(c, http.StatusUnauthorized, response.CodeMissingAPIKey)
-			c.Abort()
+		// 未携带 API Key 时使用控制台会话
+		if key == "" && cookie != "" {
+			if sessionAuth(c, cookie, perms) {
+				c.Next()
+			}
 			return
 		}
-
-		if !isValidAPIKey(key) {
-			debugAuth(c, key, dnslog.HashAPIKey(key), false, false)
-			response.Error(c, http.StatusUnauthorized, response.CodeInvalidKey)
-			c.Abort()
-			return
+		var apiKey dnslog.APIKey
+		var hash string
+		var err error
+		if key == "" {
+			// 双向 TLS：已验证的客户端证书映射到 key
+			id, ok := dnslog.ClientCertKeyID(c.Request.TLS)
+			if !ok {
+				debugAuth(c, "", "", false, false)
+				response.Error(c, http.StatusUnauthorized, response.CodeMissingAPIKey)
+				c.Abort()
+				return
+			}
+			apiKey, err = dnslog.GetAPIKeyByIDWithContext(c.Request.Context(), id)
+		} else {
+			if !isValidAPIKey(key) {
+				debugAuth(c, key, dnslog.HashAPIKey(key), false, false)
+				response.Error(c, http.StatusUnauthorized, response.CodeInvalidKey)
+				c.Abort()
+				return
+			}
+			hash = dnslog.HashAPIKey(key)
+			apiKey, err = dnslog.GetAPIKeyByHashWithContext(c.Request.Context(), hash)
 		}
-
-		hash := dnslog.HashAPIKey(key)
-		apiKey, err := dnslog.GetAPIKeyByHashWithContext(c.Request.Context(), hash)
 		if err != nil {
 			debugAuth(c, key, hash, false, false)
 			response.Error(c, http.StatusUnauthorized, response.CodeInvalidKey)
@@ -60,12 +92,37 @@ func APIKeyAuth(cfg *config.Config) gin.HandlerFunc {
 			c.Abort()
 			return
 		}
+		now := time.Now()
+		if apiKey.Expired(now.UnixMilli()) {
+			debugAuth(c, key, hash, true, false)
+			response.Error(c, http.StatusUnauthorized, response.CodeExpiredAPIKey)
+			c.Abort()
+			return
+		}
+
+		required, ok := perms.Scope(c.Request.Method, c.FullPath())
+		if !ok {
+			required = dnslog.ScopeAll
+		}
+		// 登记为空 scope 的路由任何已认证主体均可访问
+		if required != "" && !dnslog.HasScope(apiKey.Scopes, required) {
+			debugAuth(c, key, hash, true, true)
+			response.Error(c, http.StatusForbidden, response.CodeInsufficientScope)
+			c.Abort()
+			return
+		}
 
 		debugAuth(c, key, hash, true, true)
 		c.Set("api_key_id", apiKey.ID)
+		c.Set("api_key_scopes", apiKey.Scopes)
+		c.Set("api_key_tenant", apiKey.Tenant)
 		c.Next()
 
-		_ = dnslog.TouchAPIKeyLastUsed(apiKey.ID, time.Now().UnixMilli())
+		// 按间隔节流写 last_used_at，避免每个请求都写库
+		interval := time.Duration(cfg.APIKeyTouchIntervalSeconds) * time.Second
+		if now.UnixMilli()-apiKey.LastUsedAt >= interval.Milliseconds() {
+			_ = dnslog.TouchAPIKeyLastUsed(apiKey.ID, now.UnixMilli())
+		}
 	}
 }
 
@@ -17,7 +17,7 @@ func IPBlacklist(cfg *config.Config) gin.HandlerFunc {
 			return
 		}
 		ip := c.ClientIP()
-		blocked, _ := dnslog.IsIPBlacklistedWithContext(c.Request.Context(), ip)
+		blocked, _ := dnslog.IsIPBlacklistedWithContext(c.Request.Context(), ip, dnslog.BlacklistScopeHTTP)
 		if blocked {
 			response.Error(c, http.StatusForbidden, response.CodeForbidden)
 			c.Abort()
@@ -0,0 +1,201 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/genwilliam/dnslog_for_go/config"
+	"github.com/genwilliam/dnslog_for_go/pkg/log"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const (
+	corsAllowMethods  = "GET, POST, PUT, DELETE, OPTIONS"
+	corsAllowHeaders  = "Origin, Content-Type, Accept, Authorization, X-API-Key, X-CSRF-Token, X-Change-Reason"
+	corsExposeHeaders = "Content-Length, Content-Disposition, X-Trace-ID, RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, Retry-After"
+)
+
+// corsOrigins 一组允许的来源：精确匹配、"*"，或 https://*.example.com 形式的子域通配
+type corsOrigins struct {
+	any      bool
+	exact    map[string]bool
+	suffixes []string // scheme://.example.com
+}
+
+func (o *corsOrigins) add(pattern string) error {
+	pattern = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(pattern), "/"))
+	if pattern == "" {
+		return nil
+	}
+	if pattern == "*" {
+		o.any = true
+		return nil
+	}
+	u, err := url.Parse(pattern)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.Path != "" {
+		return fmt.Errorf("invalid cors origin %q", pattern)
+	}
+	if strings.HasPrefix(u.Host, "*.") {
+		o.suffixes = append(o.suffixes, u.Scheme+"://"+u.Host[1:])
+		return nil
+	}
+	if strings.Contains(u.Host, "*") {
+		return fmt.Errorf("invalid cors origin %q", pattern)
+	}
+	if o.exact == nil {
+		o.exact = make(map[string]bool)
+	}
+	o.exact[pattern] = true
+	return nil
+}
+
+func (o *corsOrigins) allows(origin string) bool {
+	if o.any || o.exact[origin] {
+		return true
+	}
+	for _, s := range o.suffixes {
+		// scheme://.example.com 匹配 scheme://a.example.com[:port]
+		scheme, domain, _ := strings.Cut(s, "://")
+		rest, ok := strings.CutPrefix(origin, scheme+"://")
+		if !ok {
+			continue
+		}
+		host := rest
+		if i := strings.LastIndex(rest, ":"); i >= 0 && !strings.Contains(domain, ":") {
+			host = rest[:i]
+		}
+		if strings.HasSuffix(host, domain) && len(host) > len(domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// corsPolicy 默认来源列表加按路由前缀覆盖的分组策略（最长前缀优先）
+type corsPolicy struct {
+	defaults    corsOrigins
+	groups      map[string]*corsOrigins
+	prefixes    []string // 按长度降序
+	credentials bool
+	maxAge      string
+}
+
+func newCORSPolicy(cfg *config.Config) (*corsPolicy, error) {
+	p := &corsPolicy{groups: make(map[string]*corsOrigins), credentials: cfg.CORSAllowCredentials}
+	if cfg.CORSMaxAgeSeconds > 0 {
+		p.maxAge = strconv.Itoa(cfg.CORSMaxAgeSeconds)
+	}
+	for _, o := range cfg.CORSAllowedOrigins {
+		if err := p.defaults.add(o); err != nil {
+			return nil, err
+		}
+	}
+	for _, entry := range cfg.CORSGroupOrigins {
+		prefix, origin, ok := strings.Cut(entry, "=")
+		prefix = strings.TrimSuffix(strings.TrimSpace(prefix), "/")
+		if !ok || !strings.HasPrefix(prefix, "/") {
+			return nil, fmt.Errorf("invalid cors group policy %q", entry)
+		}
+		g, exists := p.groups[prefix]
+		if !exists {
+			g = &corsOrigins{}
+			p.groups[prefix] = g
+			p.prefixes = append(p.prefixes, prefix)
+		}
+		// "<prefix>=" 表示该分组不允许任何跨域来源
+		if err := g.add(origin); err != nil {
+			return nil, err
+		}
+	}
+	// 允许凭据时必须回显具体来源，不能对任意来源开放
+	anyOrigin := p.defaults.any
+	for _, g := range p.groups {
+		anyOrigin = anyOrigin || g.any
+	}
+	if p.credentials && anyOrigin {
+		return nil, fmt.Errorf("cors origin \"*\" cannot be combined with corsAllowCredentials")
+	}
+	sort.Slice(p.prefixes, func(i, j int) bool { return len(p.prefixes[i]) > len(p.prefixes[j]) })
+	return p, nil
+}
+
+// originsFor 返回 path 适用的来源列表；/api 前缀与无前缀路由共用同一套分组
+func (p *corsPolicy) originsFor(path string) *corsOrigins {
+	if path == "/api" || strings.HasPrefix(path, "/api/") {
+		path = strings.TrimPrefix(path, "/api")
+	}
+	for _, prefix := range p.prefixes {
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return p.groups[prefix]
+		}
+	}
+	return &p.defaults
+}
+
+// CORS 按配置的来源白名单处理跨域请求与预检；默认不允许任何跨域来源。
+// 被拒绝的来源记录日志（含 trace ID），预检请求返回 403
+func CORS(cfg *config.Config) (gin.HandlerFunc, error) {
+	policy, err := newCORSPolicy(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return func(c *gin.Context) {
+		origin := strings.ToLower(strings.TrimSuffix(c.GetHeader("Origin"), "/"))
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+		if origin == "" || sameOrigin(origin, c.Request.Host) {
+			c.Next()
+			return
+		}
+
+		c.Writer.Header().Add("Vary", "Origin")
+		origins := policy.originsFor(c.Request.URL.Path)
+		if !origins.allows(origin) {
+			log.Warn("cors origin rejected",
+				zap.String("trace_id", c.GetString("trace_id")),
+				zap.String("origin", origin),
+				zap.String("method", c.Request.Method),
+				zap.String("path", c.Request.URL.Path),
+				zap.Bool("preflight", preflight),
+			)
+			if preflight {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			c.Next()
+			return
+		}
+
+		h := c.Writer.Header()
+		if origins.any {
+			h.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			h.Set("Access-Control-Allow-Origin", origin)
+		}
+		if policy.credentials {
+			h.Set("Access-Control-Allow-Credentials", "true")
+		}
+		if preflight {
+			h.Set("Access-Control-Allow-Methods", corsAllowMethods)
+			h.Set("Access-Control-Allow-Headers", corsAllowHeaders)
+			if policy.maxAge != "" {
+				h.Set("Access-Control-Max-Age", policy.maxAge)
+			}
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+		h.Set("Access-Control-Expose-Headers", corsExposeHeaders)
+		c.Next()
+	}, nil
+}
+
+// sameOrigin 浏览器同源请求也会带 Origin（如 POST），与 Host 一致时不做跨域处理
+func sameOrigin(origin, host string) bool {
+	_, rest, ok := strings.Cut(origin, "://")
+	return ok && host != "" && strings.EqualFold(rest, host)
+}
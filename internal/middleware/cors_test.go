@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/genwilliam/dnslog_for_go/config"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newCORSEngine(t *testing.T, cfg *config.Config) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	cors, err := CORS(cfg)
+	require.NoError(t, err)
+	r := gin.New()
+	r.Use(TraceID(), cors)
+	r.GET("/api/tokens", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.GET("/api/keys", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return r
+}
+
+func doCORS(r *gin.Engine, method, path, origin string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	req.Host = "dnslog.example.com"
+	if origin != "" {
+		req.Header.Set("Origin", origin)
+	}
+	if method == http.MethodOptions {
+		req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestCORSStrictDefault(t *testing.T) {
+	r := newCORSEngine(t, &config.Config{})
+
+	w := doCORS(r, http.MethodGet, "/api/tokens", "https://evil.com")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+
+	w = doCORS(r, http.MethodOptions, "/api/tokens", "https://evil.com")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// 同源请求不受影响
+	w = doCORS(r, http.MethodGet, "/api/tokens", "https://dnslog.example.com")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestCORSAllowlistAndGroups(t *testing.T) {
+	r := newCORSEngine(t, &config.Config{
+		CORSAllowedOrigins:   []string{"https://*.example.org", "http://localhost:5173"},
+		CORSGroupOrigins:     []string{"/keys=https://admin.example.org"},
+		CORSAllowCredentials: true,
+		CORSMaxAgeSeconds:    600,
+	})
+
+	w := doCORS(r, http.MethodOptions, "/api/tokens", "https://app.example.org")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "https://app.example.org", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", w.Header().Get("Access-Control-Allow-Credentials"))
+	assert.Equal(t, "600", w.Header().Get("Access-Control-Max-Age"))
+
+	w = doCORS(r, http.MethodGet, "/tokens", "http://localhost:5173")
+	assert.Equal(t, "http://localhost:5173", w.Header().Get("Access-Control-Allow-Origin"))
+
+	// 分组策略替换默认列表
+	w = doCORS(r, http.MethodOptions, "/api/keys", "https://app.example.org")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = doCORS(r, http.MethodGet, "/api/keys", "https://admin.example.org")
+	assert.Equal(t, "https://admin.example.org", w.Header().Get("Access-Control-Allow-Origin"))
+
+	w = doCORS(r, http.MethodGet, "/api/tokens", "https://example.org.evil.com")
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestCORSConfigValidation(t *testing.T) {
+	_, err := CORS(&config.Config{CORSAllowedOrigins: []string{"*"}, CORSAllowCredentials: true})
+	assert.Error(t, err)
+	_, err = CORS(&config.Config{CORSAllowedOrigins: []string{"example.com"}})
+	assert.Error(t, err)
+	_, err = CORS(&config.Config{CORSGroupOrigins: []string{"keys=https://a.com"}})
+	assert.Error(t, err)
+}
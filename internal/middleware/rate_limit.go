@@ -3,56 +3,188 @@ package middleware
 import (
 	"context"
 	"fmt"
+	"math"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/genwilliam/dnslog_for_go/config"
+	"github.com/genwilliam/dnslog_for_go/internal/dnslog"
 	"github.com/genwilliam/dnslog_for_go/internal/infra"
+	"github.com/genwilliam/dnslog_for_go/pkg/log"
+	"github.com/genwilliam/dnslog_for_go/pkg/ratelimit"
 	"github.com/genwilliam/dnslog_for_go/pkg/response"
 
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 )
 
+// rateLimitPolicy 额度选择顺序：key 专属额度（所有路由共用）> 路由分组额度（分组内共用）> 默认额度（按路由）
+type rateLimitPolicy struct {
+	def      ratelimit.Quota
+	groups   map[string]ratelimit.Quota
+	prefixes []string // 按长度降序
+	keys     map[int64]ratelimit.Quota
+}
+
+var (
+	rateLimitPolicyValue atomic.Pointer[rateLimitPolicy]
+	localLimiter         = ratelimit.NewLocal()
+	rateLimitDegraded    atomic.Bool
+)
+
+// InitRateLimit 解析分组与 key 额度配置，格式分别为 "<路由前缀>=<次数>/<秒>" 与 "<key id>=<次数>/<秒>"
+func InitRateLimit(cfg *config.Config) error {
+	p, err := newRateLimitPolicy(cfg)
+	if err != nil {
+		return err
+	}
+	rateLimitPolicyValue.Store(p)
+	return nil
+}
+
+func newRateLimitPolicy(cfg *config.Config) (*rateLimitPolicy, error) {
+	p := defaultRateLimitPolicy(cfg)
+	for _, entry := range cfg.RateLimitGroupQuotas {
+		prefix, quota, ok := strings.Cut(entry, "=")
+		prefix = strings.TrimSuffix(strings.TrimSpace(prefix), "/")
+		if !ok || !strings.HasPrefix(prefix, "/") {
+			return nil, fmt.Errorf("invalid rate limit group quota %q", entry)
+		}
+		q, err := ratelimit.ParseQuota(quota)
+		if err != nil {
+			return nil, err
+		}
+		if _, exists := p.groups[prefix]; !exists {
+			p.prefixes = append(p.prefixes, prefix)
+		}
+		p.groups[prefix] = q
+	}
+	sort.Slice(p.prefixes, func(i, j int) bool { return len(p.prefixes[i]) > len(p.prefixes[j]) })
+	for _, entry := range cfg.RateLimitKeyQuotas {
+		id, quota, ok := strings.Cut(entry, "=")
+		keyID, err := strconv.ParseInt(strings.TrimSpace(id), 10, 64)
+		if !ok || err != nil || keyID <= 0 {
+			return nil, fmt.Errorf("invalid rate limit key quota %q", entry)
+		}
+		q, err := ratelimit.ParseQuota(quota)
+		if err != nil {
+			return nil, err
+		}
+		p.keys[keyID] = q
+	}
+	return p, nil
+}
+
+// defaultRateLimitPolicy 只包含默认额度
+func defaultRateLimitPolicy(cfg *config.Config) *rateLimitPolicy {
+	p := &rateLimitPolicy{
+		def:    ratelimit.Quota{Limit: cfg.RateLimitMaxRequests, Period: time.Duration(cfg.RateLimitWindowSeconds) * time.Second},
+		groups: make(map[string]ratelimit.Quota),
+		keys:   make(map[int64]ratelimit.Quota),
+	}
+	if p.def.Limit <= 0 {
+		p.def.Limit = 60
+	}
+	if p.def.Period <= 0 {
+		p.def.Period = time.Minute
+	}
+	return p
+}
+
+// quotaFor 返回额度与计数桶名称
+func (p *rateLimitPolicy) quotaFor(route string, keyID int64) (string, ratelimit.Quota) {
+	if q, ok := p.keys[keyID]; ok && keyID > 0 {
+		return "key", q
+	}
+	path := route
+	if path == "/api" || strings.HasPrefix(path, "/api/") {
+		path = strings.TrimPrefix(path, "/api")
+	}
+	for _, prefix := range p.prefixes {
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return "group:" + prefix, p.groups[prefix]
+		}
+	}
+	return route, p.def
+}
+
+// rateLimitPrincipal 限流主体：API Key 按哈希（不在 Redis 中保存明文），会话按用户，其余按客户端 IP
+func rateLimitPrincipal(c *gin.Context) (string, int64) {
+	if v, ok := c.Get("api_key_id"); ok {
+		id, _ := v.(int64)
+		if key := strings.TrimSpace(c.GetHeader(apiKeyHeader)); key != "" {
+			return "k:" + dnslog.HashAPIKey(key), id
+		}
+		return "id:" + strconv.FormatInt(id, 10), id
+	}
+	if v, ok := c.Get("user_id"); ok {
+		id, _ := v.(int64)
+		return "u:" + strconv.FormatInt(id, 10), 0
+	}
+	return "ip:" + c.ClientIP(), 0
+}
+
+// RateLimit 按主体与额度限流，并返回 RateLimit-* 头；未调用 InitRateLimit 时只使用默认额度
 func RateLimit(cfg *config.Config) gin.HandlerFunc {
+	var fallback *rateLimitPolicy
+	if cfg != nil {
+		fallback = defaultRateLimitPolicy(cfg)
+	}
 	return func(c *gin.Context) {
 		if cfg == nil || !cfg.RateLimitEnabled {
 			c.Next()
 			return
 		}
+		policy := rateLimitPolicyValue.Load()
+		if policy == nil {
+			policy = fallback
+		}
 
-		client := infra.GetRedis()
-		if client == nil {
-			response.Error(c, http.StatusServiceUnavailable, response.CodeRateLimitOff)
+		principal, keyID := rateLimitPrincipal(c)
+		bucket, quota := policy.quotaFor(c.FullPath(), keyID)
+		key := fmt.Sprintf("rl:%s:%s", bucket, principal)
+		res := allowRequest(key, quota)
+
+		h := c.Writer.Header()
+		h.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+		h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		h.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.ResetAfter)))
+		if !res.Allowed {
+			h.Set("Retry-After", strconv.Itoa(max(ceilSeconds(res.RetryAfter), 1)))
+			dnslog.RecordRateLimitViolation(c.Request.Context(), cfg, c.ClientIP(), dnslog.BlacklistScopeHTTP, c.GetString("trace_id"))
+			response.Error(c, http.StatusTooManyRequests, response.CodeRateLimited)
 			c.Abort()
 			return
 		}
 
-		scope := c.GetHeader(apiKeyHeader)
-		if scope == "" {
-			scope = c.ClientIP()
-		}
+		c.Next()
+	}
+}
 
-		key := fmt.Sprintf("rl:%s:%s", c.FullPath(), scope)
-		limit := cfg.RateLimitMaxRequests
-		window := time.Duration(cfg.RateLimitWindowSeconds) * time.Second
+// allowRequest 优先使用 Redis 共享额度；Redis 未配置或出错时退回进程内限流
+func allowRequest(key string, quota ratelimit.Quota) ratelimit.Result {
+	now := time.Now()
+	if client := infra.GetRedis(); client != nil {
 		ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
 		defer cancel()
-
-		count, err := client.Incr(ctx, key).Result()
-		if err != nil {
-			response.Error(c, http.StatusServiceUnavailable, response.CodeRateLimitError)
-			c.Abort()
-			return
+		res, err := ratelimit.AllowRedis(ctx, client, key, quota, now)
+		if err == nil {
+			if rateLimitDegraded.CompareAndSwap(true, false) {
+				log.Info("rate limit redis recovered")
+			}
+			return res
 		}
-		if count == 1 {
-			_ = client.Expire(ctx, key, window).Err()
+		if rateLimitDegraded.CompareAndSwap(false, true) {
+			log.Warn("rate limit redis unavailable, using local limiter", zap.Error(err))
 		}
-		if int(count) > limit {
-			response.Error(c, http.StatusTooManyRequests, response.CodeRateLimited)
-			c.Abort()
-			return
-		}
-
-		c.Next()
 	}
+	return localLimiter.Allow(key, quota, now)
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
 }
@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/genwilliam/dnslog_for_go/config"
+	"github.com/genwilliam/dnslog_for_go/pkg/ratelimit"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimitPolicyQuotaFor(t *testing.T) {
+	p, err := newRateLimitPolicy(&config.Config{
+		RateLimitMaxRequests:   60,
+		RateLimitWindowSeconds: 60,
+		RateLimitGroupQuotas:   []string{"/audit=10/60", "/audit/export=2/3600"},
+		RateLimitKeyQuotas:     []string{"5=600/60"},
+	})
+	require.NoError(t, err)
+
+	bucket, q := p.quotaFor("/api/records", 1)
+	assert.Equal(t, "/api/records", bucket)
+	assert.Equal(t, ratelimit.Quota{Limit: 60, Period: time.Minute}, q)
+
+	bucket, q = p.quotaFor("/api/audit/events", 1)
+	assert.Equal(t, "group:/audit", bucket)
+	assert.Equal(t, 10, q.Limit)
+
+	bucket, q = p.quotaFor("/audit/export", 1)
+	assert.Equal(t, "group:/audit/export", bucket)
+	assert.Equal(t, 2, q.Limit)
+
+	bucket, q = p.quotaFor("/api/audit", 5)
+	assert.Equal(t, "key", bucket)
+	assert.Equal(t, 600, q.Limit)
+
+	for _, bad := range [][]string{{"audit=1/60"}, {"/audit=1"}} {
+		_, err := newRateLimitPolicy(&config.Config{RateLimitGroupQuotas: bad})
+		assert.Error(t, err)
+	}
+	_, err = newRateLimitPolicy(&config.Config{RateLimitKeyQuotas: []string{"abc=1/60"}})
+	assert.Error(t, err)
+}
+
+func TestRateLimitHeadersWithLocalFallback(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{RateLimitEnabled: true, RateLimitMaxRequests: 2, RateLimitWindowSeconds: 60}
+	r := gin.New()
+	r.Use(RateLimit(cfg))
+	r.GET("/limited", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	do := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/limited", nil)
+		req.RemoteAddr = "203.0.113.9:1234"
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do()
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2", w.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", w.Header().Get("RateLimit-Remaining"))
+
+	w = do()
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+
+	w = do()
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "30", w.Header().Get("Retry-After"))
+	assert.Contains(t, w.Body.String(), "rate_limited")
+}
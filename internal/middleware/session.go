@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/genwilliam/dnslog_for_go/internal/dnslog"
+	"github.com/genwilliam/dnslog_for_go/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// sessionAuth 使用控制台会话 cookie 鉴权：非幂等请求须携带与会话匹配的 X-CSRF-Token，
+// 用户角色展开为 scope 后与 API Key 走同一套路由权限检查
+func sessionAuth(c *gin.Context, cookie string, perms RoutePermissions) bool {
+	sess, user, err := dnslog.AuthenticateSession(c.Request.Context(), cookie, time.Now().UnixMilli())
+	if err == dnslog.ErrSessionNotFound {
+		response.Error(c, http.StatusUnauthorized, response.CodeInvalidSession)
+		c.Abort()
+		return false
+	}
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError)
+		c.Abort()
+		return false
+	}
+	if dnslog.RequiresCSRF(c.Request.Method) && !dnslog.ValidCSRFToken(sess, c.GetHeader(dnslog.CSRFHeader)) {
+		response.Error(c, http.StatusForbidden, response.CodeCSRFTokenInvalid)
+		c.Abort()
+		return false
+	}
+
+	scopes := dnslog.RoleScopes(user.Roles)
+	required, ok := perms.Scope(c.Request.Method, c.FullPath())
+	if !ok {
+		required = dnslog.ScopeAll
+	}
+	if required != "" && !dnslog.HasScope(scopes, required) {
+		response.Error(c, http.StatusForbidden, response.CodeInsufficientScope)
+		c.Abort()
+		return false
+	}
+
+	setSessionContext(c, sess, user)
+	c.Set("api_key_scopes", scopes)
+	c.Set("api_key_tenant", user.Tenant)
+	return true
+}
+
+// identifySession 未启用鉴权时仅识别会话用户（供 /auth 接口使用），不限制权限
+func identifySession(c *gin.Context, cookie string) {
+	sess, user, err := dnslog.AuthenticateSession(c.Request.Context(), cookie, time.Now().UnixMilli())
+	if err != nil {
+		return
+	}
+	if dnslog.RequiresCSRF(c.Request.Method) && !dnslog.ValidCSRFToken(sess, c.GetHeader(dnslog.CSRFHeader)) {
+		return
+	}
+	setSessionContext(c, sess, user)
+}
+
+func setSessionContext(c *gin.Context, sess dnslog.UserSession, user dnslog.User) {
+	c.Set("user_id", user.ID)
+	c.Set("username", user.Username)
+	c.Set("session_hash", sess.SessionHash)
+}
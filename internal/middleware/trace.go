@@ -6,9 +6,13 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-// TraceID sets a trace_id for the request lifecycle.
+// TraceID sets a trace_id for the request lifecycle; an ID set by an earlier TraceID is kept.
 func TraceID() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if c.GetString("trace_id") != "" {
+			c.Next()
+			return
+		}
 		traceID := utils.GenerateTraceID()
 		c.Set("trace_id", traceID)
 		c.Writer.Header().Set("X-Trace-ID", traceID)
@@ -28,6 +28,8 @@ func ConfigHandler(c *gin.Context) {
 		"token_ttl":         cfg.TokenTTLSeconds,
 		"apiKeyRequired":    cfg.APIKeyRequired,   // camelCase 兼容
 		"api_key_required":  cfg.APIKeyRequired,   // snake_case 兼容
+		"oidc_enabled":      cfg.OIDCEnabled,
+		"tls_enabled":       cfg.TLSEnabled,
 		"dns_port":          dnsPort,
 	})
 }
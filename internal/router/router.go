@@ -26,36 +26,52 @@ import (
 func StartServer(cfg *config.Config) {
 	r := gin.Default()
 
-	// 添加跨域中间件
-	r.Use(func(c *gin.Context) {
-		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE, UPDATE")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Origin, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, X-API-Key")
-		c.Writer.Header().Set("Access-Control-Expose-Headers", "Content-Length")
-		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-
-		// 处理浏览器的预检请求（OPTIONS）
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(http.StatusNoContent)
-			return
-		}
-
-		c.Next()
-	})
+	// 跨域策略在路由匹配前执行，未注册 OPTIONS 的路由也能处理预检
+	cors, err := middleware.CORS(cfg)
+	if err != nil {
+		log.Fatal("init cors failed", zap.Error(err))
+		return
+	}
+	r.Use(middleware.TraceID(), cors)
 
-	if cfg.RateLimitEnabled || cfg.DNSRateLimitEnabled || cfg.AuditEnabled || cfg.WebhookEnabled {
+	// DNS 限流与 queueBackend=redis 必须依赖 Redis；Redis 不可用时 HTTP 限流与 auto 模式队列回退为进程内实现
+	queueBackend := cfg.QueueBackend
+	queueNeedsRedis := (cfg.AuditEnabled || cfg.WebhookEnabled) && queueBackend != dnslog.QueueBackendMemory
+	if cfg.RateLimitEnabled || cfg.DNSRateLimitEnabled || queueNeedsRedis {
 		if _, err := infra.InitRedis(cfg); err != nil {
-			log.Fatal("init redis failed", zap.Error(err))
-			return
+			if cfg.DNSRateLimitEnabled || queueBackend == dnslog.QueueBackendRedis {
+				log.Fatal("init redis failed", zap.Error(err))
+				return
+			}
+			log.Warn("redis unavailable, using in-process rate limit and queues", zap.Error(err))
 		}
 	}
+	if cfg.AutoBanEnabled && infra.GetRedis() == nil {
+		log.Warn("redis unavailable, auto ban counts violations per instance")
+	}
 	if cfg.MetricsEnabled {
 		metrics.Init()
 	}
+	if err := middleware.InitRateLimit(cfg); err != nil {
+		log.Fatal("init rate limit failed", zap.Error(err))
+		return
+	}
+	dnslog.StartBlacklistSync(cfg)
+	dnslog.StartTokenHitSync()
 	if cfg.AuditEnabled {
 		dnslog.StartAuditWorker()
 	}
+	if cfg.OIDCEnabled {
+		if err := dnslog.InitOIDC(cfg); err != nil {
+			log.Fatal("init oidc failed", zap.Error(err))
+			return
+		}
+	}
 	if cfg.WebhookEnabled {
+		if err := dnslog.InitWebhookEgressPolicy(cfg); err != nil {
+			log.Fatal("init webhook egress policy failed", zap.Error(err))
+			return
+		}
 		dnslog.StartWebhookWorkers()
 	}
 
@@ -75,11 +91,47 @@ func StartServer(cfg *config.Config) {
 		IdleTimeout:       60 * time.Second,
 	}
 
+	// 启用 TLS 时证书来自文件（支持 SIGHUP 与文件变化热加载）或 ACME 自动申请
+	stopReload := make(chan struct{})
+	defer close(stopReload)
+	var redirectSrv *http.Server
+	if cfg.TLSEnabled {
+		tlsCfg, startCerts, err := newTLSConfig(cfg)
+		if err != nil {
+			log.Fatal("init tls failed", zap.Error(err))
+			return
+		}
+		srv.TLSConfig = tlsCfg
+		startCerts(stopReload)
+		if cfg.HTTPRedirectAddr != "" {
+			redirectSrv = newRedirectServer(cfg.HTTPRedirectAddr, cfg.HTTPListenAddr)
+			go func() {
+				log.Info("HTTPS redirect started", zap.String("addr", cfg.HTTPRedirectAddr))
+				if err := redirectSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					log.Error("HTTPS redirect run failed", zap.Error(err))
+				}
+			}()
+		}
+	}
+
+	// HTTP/HTTPS、SMTP、LDAP、RMI 与原始端口回连捕获，HTTPS 与 STARTTLS 复用 API 的证书
+	dnslog.StartHTTPCapture(cfg, srv.TLSConfig)
+	dnslog.StartSMTPCapture(cfg, srv.TLSConfig)
+	dnslog.StartLDAPCapture(cfg)
+	dnslog.StartRMICapture(cfg)
+	dnslog.StartRawCapture(cfg)
+
 	// 启动 HTTP 服务器
 	go func() {
-		log.Info("Server started", zap.String("addr", cfg.HTTPListenAddr))
+		log.Info("Server started", zap.String("addr", cfg.HTTPListenAddr), zap.Bool("tls", cfg.TLSEnabled))
 		log.Info("Please visit /dnslog to access the DNS log system")
-		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		var err error
+		if cfg.TLSEnabled {
+			err = srv.ListenAndServeTLS("", "")
+		} else {
+			err = srv.ListenAndServe()
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
 			log.Error("Server run failed", zap.Error(err))
 		}
 	}()
@@ -100,6 +152,13 @@ func StartServer(cfg *config.Config) {
 	if err := srv.Shutdown(ctx); err != nil {
 		log.Error("Server shutdown failed", zap.Error(err))
 	}
+	if redirectSrv != nil {
+		_ = redirectSrv.Shutdown(ctx)
+	}
+	dnslog.ShutdownHTTPCapture(ctx)
+	dnslog.ShutdownSMTPCapture(ctx)
+	dnslog.ShutdownTCPCapture(ctx)
+	dnslog.ShutdownUDPCapture()
 
 	log.Info("Server exited")
 }
@@ -118,11 +177,26 @@ func registerAPIRoutes(r *gin.Engine, cfg *config.Config, prefix string) {
 		middleware.TraceID(),
 		middleware.Audit(cfg),
 		middleware.IPBlacklist(cfg),
-		middleware.APIKeyAuth(cfg),
+		middleware.APIKeyAuth(cfg, routePermissions(prefix)),
 		middleware.RateLimit(cfg),
 		middleware.Metrics(),
 	)
 
+	// 登录接口本身不需要凭据，仍经过审计、黑名单与限流
+	public := base.Group("/")
+	public.Use(
+		middleware.TraceID(),
+		middleware.Audit(cfg),
+		middleware.IPBlacklist(cfg),
+		middleware.RateLimit(cfg),
+		middleware.Metrics(),
+	)
+	public.POST("/auth/login", dnslog.LoginHandler)
+	if cfg.OIDCEnabled {
+		public.GET("/auth/oidc/login", dnslog.OIDCLoginHandler)
+		public.GET("/auth/oidc/callback", dnslog.OIDCCallbackHandler)
+	}
+
 	secured.POST("/submit", domain.SubmitDomain) // legacy: DNSLog 记录查询接口（观测模式）
 	secured.GET("/random-domain", domain.RandomDomain)
 	secured.POST("/tokens", domain.RandomDomain)
@@ -134,22 +208,43 @@ func registerAPIRoutes(r *gin.Engine, cfg *config.Config, prefix string) {
 	secured.GET("/records", dnslog.ListRecordsHandler)
 	secured.GET("/tokens", dnslog.ListTokensHandler)
 	secured.GET("/tokens/:token", dnslog.GetTokenStatusHandler)
+	secured.GET("/tokens/:token/wait", dnslog.WaitTokenHitHandler)
 	secured.GET("/tokens/:token/records", dnslog.GetTokenRecordsHandler)
+	secured.GET("/tokens/:token/interactions", dnslog.GetTokenInteractionsHandler)
+	secured.GET("/tokens/:token/interactions/:id/raw", dnslog.GetInteractionRawHandler)
 	secured.POST("/tokens/:token/webhook", dnslog.SetTokenWebhookHandler)
 	secured.GET("/tokens/:token/webhook", dnslog.GetTokenWebhookHandler)
 	secured.POST("/tokens/:token/webhook/disable", dnslog.DisableTokenWebhookHandler)
 	secured.DELETE("/tokens/:token/webhook", dnslog.DisableTokenWebhookHandler)
+	secured.POST("/tokens/:token/response", dnslog.SetTokenHTTPResponseHandler)
+	secured.GET("/tokens/:token/response", dnslog.GetTokenHTTPResponseHandler)
+	secured.DELETE("/tokens/:token/response", dnslog.DeleteTokenHTTPResponseHandler)
 	secured.POST("/keys", dnslog.CreateAPIKeyHandler)
 	if cfg != nil && cfg.BootstrapEnabled {
 		base.POST("/keys/bootstrap", dnslog.CreateAPIKeyWithBootstrapHandler)
 	}
 	secured.GET("/keys", dnslog.ListAPIKeysHandler)
 	secured.POST("/keys/:id/disable", dnslog.DisableAPIKeyHandler)
+	secured.POST("/keys/:id/rotate", dnslog.RotateAPIKeyHandler)
 	secured.DELETE("/keys/:id", dnslog.DisableAPIKeyHandler)
+	secured.POST("/auth/logout", dnslog.LogoutHandler)
+	secured.GET("/auth/me", dnslog.MeHandler)
+	secured.POST("/auth/password", dnslog.ChangePasswordHandler)
+	secured.POST("/auth/totp/setup", dnslog.SetupTOTPHandler)
+	secured.POST("/auth/totp/enable", dnslog.EnableTOTPHandler)
+	secured.POST("/auth/totp/disable", dnslog.DisableTOTPHandler)
+	secured.POST("/users", dnslog.CreateUserHandler)
+	secured.GET("/users", dnslog.ListUsersHandler)
+	secured.PUT("/users/:id/roles", dnslog.SetUserRolesHandler)
+	secured.POST("/users/:id/disable", dnslog.DisableUserHandler)
+	secured.DELETE("/users/:id", dnslog.DisableUserHandler)
 	secured.POST("/blacklist", dnslog.AddBlacklistHandler)
 	secured.GET("/blacklist", dnslog.ListBlacklistHandler)
 	secured.POST("/blacklist/:id/disable", dnslog.DisableBlacklistHandler)
 	secured.DELETE("/blacklist/:id", dnslog.DisableBlacklistHandler)
+	secured.GET("/audit", dnslog.ListAuditLogsHandler)
+	secured.GET("/audit/export", dnslog.ExportAuditLogsHandler)
+	secured.GET("/audit/events", dnslog.ListAdminEventsHandler)
 
 	if cfg.PublicConfig {
 		base.GET("/config", ConfigHandler)
@@ -165,3 +260,62 @@ func registerAPIRoutes(r *gin.Engine, cfg *config.Config, prefix string) {
 		}
 	}
 }
+
+// routePermissions 路由权限表：未登记的 secured 路由仅持有 "*" 的 key 可访问；scope 为空表示任意已登录主体
+func routePermissions(prefix string) middleware.RoutePermissions {
+	perms := middleware.RoutePermissions{}
+	for _, p := range []struct{ method, path, scope string }{
+		{http.MethodPost, "/submit", dnslog.ScopeRecordsRead},
+		{http.MethodGet, "/random-domain", dnslog.ScopeTokensWrite},
+		{http.MethodPost, "/tokens", dnslog.ScopeTokensWrite},
+		{http.MethodPost, "/change", dnslog.ScopeAdminConfig},
+		{http.MethodPost, "/change-pact", dnslog.ScopeAdminConfig},
+		{http.MethodPost, "/pause", dnslog.ScopeAdminConfig},
+		{http.MethodPost, "/start", dnslog.ScopeAdminConfig},
+
+		{http.MethodGet, "/records", dnslog.ScopeRecordsRead},
+		{http.MethodGet, "/tokens", dnslog.ScopeTokensRead},
+		{http.MethodGet, "/tokens/:token", dnslog.ScopeTokensRead},
+		{http.MethodGet, "/tokens/:token/wait", dnslog.ScopeTokensRead},
+		{http.MethodGet, "/tokens/:token/records", dnslog.ScopeRecordsRead},
+		{http.MethodGet, "/tokens/:token/interactions", dnslog.ScopeRecordsRead},
+		{http.MethodGet, "/tokens/:token/interactions/:id/raw", dnslog.ScopeRecordsRead},
+		{http.MethodPost, "/tokens/:token/webhook", dnslog.ScopeTokensWrite},
+		{http.MethodGet, "/tokens/:token/webhook", dnslog.ScopeTokensRead},
+		{http.MethodPost, "/tokens/:token/webhook/disable", dnslog.ScopeTokensWrite},
+		{http.MethodDelete, "/tokens/:token/webhook", dnslog.ScopeTokensWrite},
+		{http.MethodPost, "/tokens/:token/response", dnslog.ScopeTokensWrite},
+		{http.MethodGet, "/tokens/:token/response", dnslog.ScopeTokensRead},
+		{http.MethodDelete, "/tokens/:token/response", dnslog.ScopeTokensWrite},
+
+		{http.MethodPost, "/keys", dnslog.ScopeAdminKeys},
+		{http.MethodGet, "/keys", dnslog.ScopeAdminKeys},
+		{http.MethodPost, "/keys/:id/disable", dnslog.ScopeAdminKeys},
+		{http.MethodPost, "/keys/:id/rotate", dnslog.ScopeAdminKeys},
+		{http.MethodDelete, "/keys/:id", dnslog.ScopeAdminKeys},
+		{http.MethodPost, "/auth/logout", ""},
+		{http.MethodGet, "/auth/me", ""},
+		{http.MethodPost, "/auth/password", ""},
+		{http.MethodPost, "/auth/totp/setup", ""},
+		{http.MethodPost, "/auth/totp/enable", ""},
+		{http.MethodPost, "/auth/totp/disable", ""},
+		{http.MethodPost, "/users", dnslog.ScopeAdminUsers},
+		{http.MethodGet, "/users", dnslog.ScopeAdminUsers},
+		{http.MethodPut, "/users/:id/roles", dnslog.ScopeAdminUsers},
+		{http.MethodPost, "/users/:id/disable", dnslog.ScopeAdminUsers},
+		{http.MethodDelete, "/users/:id", dnslog.ScopeAdminUsers},
+		{http.MethodPost, "/blacklist", dnslog.ScopeAdminBlacklist},
+		{http.MethodGet, "/blacklist", dnslog.ScopeAdminBlacklist},
+		{http.MethodPost, "/blacklist/:id/disable", dnslog.ScopeAdminBlacklist},
+		{http.MethodDelete, "/blacklist/:id", dnslog.ScopeAdminBlacklist},
+		{http.MethodGet, "/audit", dnslog.ScopeAuditRead},
+		{http.MethodGet, "/audit/export", dnslog.ScopeAuditRead},
+		{http.MethodGet, "/audit/events", dnslog.ScopeAuditRead},
+
+		{http.MethodGet, "/config", dnslog.ScopeConfigRead},
+		{http.MethodGet, "/metrics", dnslog.ScopeMetricsRead},
+	} {
+		perms.Require(p.method, prefix+p.path, p.scope)
+	}
+	return perms
+}
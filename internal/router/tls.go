@@ -0,0 +1,204 @@
+package router
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/genwilliam/dnslog_for_go/config"
+	"github.com/genwilliam/dnslog_for_go/internal/dnslog"
+	"github.com/genwilliam/dnslog_for_go/pkg/acmedns"
+	"github.com/genwilliam/dnslog_for_go/pkg/log"
+	"github.com/genwilliam/dnslog_for_go/pkg/tlsreload"
+
+	"go.uber.org/zap"
+)
+
+// newTLSConfig 构建 HTTPS 配置；返回的 start 在后台保持证书更新（文件热加载或 ACME 续期）
+func newTLSConfig(cfg *config.Config) (*tls.Config, func(stop <-chan struct{}), error) {
+	minVersion, err := tlsreload.ParseVersion(cfg.TLSMinVersion)
+	if err != nil {
+		return nil, nil, fmt.Errorf("tlsMinVersion %q: %w", cfg.TLSMinVersion, err)
+	}
+	tlsCfg := &tls.Config{MinVersion: minVersion}
+	var start func(stop <-chan struct{})
+	if cfg.ACMEEnabled {
+		mgr, err := newACMEManager(cfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		tlsCfg.GetCertificate = mgr.GetCertificate
+		start = func(stop <-chan struct{}) { startACMERenewal(mgr, stop) }
+	} else {
+		if cfg.TLSCertFile == "" || cfg.TLSKeyFile == "" {
+			return nil, nil, errors.New("tlsCertFile and tlsKeyFile are required when tls is enabled")
+		}
+		reloader, err := tlsreload.New(cfg.TLSCertFile, cfg.TLSKeyFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		tlsCfg.GetCertificate = reloader.GetCertificate
+		start = func(stop <-chan struct{}) { startCertReload(reloader, cfg, stop) }
+	}
+
+	if cfg.TLSClientCAFile == "" {
+		if len(cfg.TLSClientCertKeys) > 0 {
+			return nil, nil, errors.New("tlsClientCertKeys requires tlsClientCAFile")
+		}
+		return tlsCfg, start, nil
+	}
+	pemData, err := os.ReadFile(cfg.TLSClientCAFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read tlsClientCAFile: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pemData) {
+		return nil, nil, errors.New("tlsClientCAFile contains no certificates")
+	}
+	tlsCfg.ClientCAs = pool
+	switch strings.ToLower(cfg.TLSClientAuth) {
+	case "", "optional":
+		// 浏览器访问控制台时可以不带证书，继续使用 key 或会话
+		tlsCfg.ClientAuth = tls.VerifyClientCertIfGiven
+	case "require":
+		tlsCfg.ClientAuth = tls.RequireAndVerifyClientCert
+	default:
+		return nil, nil, fmt.Errorf("invalid tlsClientAuth %q", cfg.TLSClientAuth)
+	}
+	if err := dnslog.InitClientCertKeys(cfg.TLSClientCertKeys); err != nil {
+		return nil, nil, err
+	}
+	return tlsCfg, start, nil
+}
+
+// newRedirectServer 在 addr 上把所有 HTTP 请求 308 跳转到 HTTPS（保留方法与请求体）
+func newRedirectServer(addr, httpsAddr string) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           httpsRedirectHandler(httpsAddr),
+		ReadHeaderTimeout: 5 * time.Second,
+		IdleTimeout:       30 * time.Second,
+	}
+}
+
+func httpsRedirectHandler(httpsAddr string) http.Handler {
+	port := ""
+	if _, p, err := net.SplitHostPort(httpsAddr); err == nil && p != "443" && p != "" {
+		port = p
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if host == "" {
+			http.Error(w, "missing host", http.StatusBadRequest)
+			return
+		}
+		if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+		if port != "" {
+			host += ":" + port
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+	})
+}
+
+// startCertReload 收到 SIGHUP 时立即重新加载证书；tlsReloadSeconds > 0 时另外定期检查文件修改时间
+func startCertReload(reloader *tlsreload.Reloader, cfg *config.Config, stop <-chan struct{}) {
+	onReload := func(err error) {
+		if err != nil {
+			log.Error("reload tls certificate failed, keeping previous certificate", zap.Error(err))
+			return
+		}
+		log.Info("tls certificate reloaded", zap.String("cert", cfg.TLSCertFile))
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(hup)
+		for {
+			select {
+			case <-stop:
+				return
+			case <-hup:
+				onReload(reloader.Reload())
+			}
+		}
+	}()
+
+	if cfg.TLSReloadSeconds > 0 {
+		go reloader.Watch(time.Duration(cfg.TLSReloadSeconds)*time.Second, stop, onReload)
+	}
+}
+
+// newACMEManager 为每个根域申请 <root> 与 *.<root> 证书，DNS-01 挑战由本服务的 DNS 应答
+func newACMEManager(cfg *config.Config) (*acmedns.Manager, error) {
+	domains := acmeDomains(cfg)
+	if len(domains) == 0 {
+		return nil, errors.New("acme requires rootDomain or rootDomains")
+	}
+	httpClient := http.DefaultClient
+	if cfg.ACMECAFile != "" {
+		pemData, err := os.ReadFile(cfg.ACMECAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read acmeCAFile: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pemData) {
+			return nil, errors.New("acmeCAFile contains no certificates")
+		}
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+		httpClient = &http.Client{Transport: transport, Timeout: 30 * time.Second}
+	}
+	return acmedns.New(acmedns.Config{
+		DirectoryURL: cfg.ACMEDirectoryURL,
+		Email:        cfg.ACMEEmail,
+		Domains:      domains,
+		CacheDir:     cfg.ACMECacheDir,
+		RenewBefore:  time.Duration(cfg.ACMERenewDays) * 24 * time.Hour,
+		HTTPClient:   httpClient,
+	}, dnslog.ACMEChallengeSolver{})
+}
+
+func acmeDomains(cfg *config.Config) []string {
+	seen := make(map[string]bool)
+	var domains []string
+	for _, root := range append([]string{cfg.RootDomain}, cfg.RootDomains...) {
+		root = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(root), "."))
+		if root == "" || seen[root] {
+			continue
+		}
+		seen[root] = true
+		domains = append(domains, root, "*."+root)
+	}
+	return domains
+}
+
+// startACMERenewal 启动时缺少证书则立即申请，之后每 12 小时检查一次，失败 10 分钟后重试
+func startACMERenewal(mgr *acmedns.Manager, stop <-chan struct{}) {
+	if notAfter := mgr.NotAfter(); !notAfter.IsZero() {
+		log.Info("acme certificate loaded from cache", zap.Time("not_after", notAfter))
+	}
+	go mgr.Run(12*time.Hour, 10*time.Minute, stop, func(err error) {
+		if err != nil {
+			log.Error("acme certificate request failed", zap.Error(err))
+			return
+		}
+		log.Info("acme certificate issued", zap.Time("not_after", mgr.NotAfter()))
+	})
+}
@@ -0,0 +1,42 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/genwilliam/dnslog_for_go/config"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPSRedirectHandler(t *testing.T) {
+	cases := []struct {
+		httpsAddr, host, target, want string
+	}{
+		{":443", "example.com", "/api/tokens?x=1", "https://example.com/api/tokens?x=1"},
+		{":8443", "example.com:8080", "/dnslog", "https://example.com:8443/dnslog"},
+		{"0.0.0.0:8443", "[::1]:8080", "/", "https://[::1]:8443/"},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodPost, tc.target, nil)
+		req.Host = tc.host
+		w := httptest.NewRecorder()
+		httpsRedirectHandler(tc.httpsAddr).ServeHTTP(w, req)
+		assert.Equal(t, http.StatusPermanentRedirect, w.Code)
+		assert.Equal(t, tc.want, w.Header().Get("Location"))
+	}
+}
+
+func TestNewTLSConfigValidation(t *testing.T) {
+	_, _, err := newTLSConfig(&config.Config{TLSMinVersion: "1.0"})
+	assert.Error(t, err)
+
+	_, _, err = newTLSConfig(&config.Config{TLSMinVersion: "1.2"})
+	assert.Error(t, err, "缺少证书文件")
+}
+
+func TestACMEDomains(t *testing.T) {
+	cfg := &config.Config{RootDomain: "Demo.com.", RootDomains: []string{"demo.com", "example.org"}}
+	assert.Equal(t, []string{"demo.com", "*.demo.com", "example.org", "*.example.org"}, acmeDomains(cfg))
+}
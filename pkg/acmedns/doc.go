@@ -0,0 +1,3 @@
+// Package acmedns obtains and renews certificates from an ACME CA using DNS-01 challenges
+// that are answered by the caller's own authoritative DNS server.
+package acmedns
@@ -0,0 +1,327 @@
+package acmedns
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/crypto/acme"
+)
+
+var ErrNoCertificate = errors.New("acmedns: certificate not yet issued")
+
+const (
+	accountKeyFile = "account.key"
+	certFile       = "cert.pem"
+	keyFile        = "key.pem"
+)
+
+// Solver 发布与撤销 DNS-01 的 TXT 记录；同一 fqdn 可能同时存在多个值（根域与通配符共用）
+type Solver interface {
+	Present(ctx context.Context, fqdn, value string) error
+	CleanUp(ctx context.Context, fqdn, value string) error
+}
+
+// Config 证书申请参数
+type Config struct {
+	DirectoryURL string
+	Email        string
+	Domains      []string // 如 ["example.com", "*.example.com"]
+	CacheDir     string   // 保存账户私钥与证书
+	RenewBefore  time.Duration
+	HTTPClient   *http.Client // 访问 ACME 目录使用的客户端，测试 CA（如 Pebble）需信任其根证书
+}
+
+// Manager 持有当前证书，过期前自动续期
+type Manager struct {
+	cfg    Config
+	solver Solver
+
+	cert atomic.Pointer[tls.Certificate]
+	mu   sync.Mutex // 串行化申请
+}
+
+// New 创建 Manager，并加载缓存目录中已有的证书（不存在时不报错）
+func New(cfg Config, solver Solver) (*Manager, error) {
+	if cfg.DirectoryURL == "" {
+		return nil, errors.New("acmedns: directory url is required")
+	}
+	if len(cfg.Domains) == 0 {
+		return nil, errors.New("acmedns: no domains")
+	}
+	if cfg.CacheDir == "" {
+		return nil, errors.New("acmedns: cache dir is required")
+	}
+	if solver == nil {
+		return nil, errors.New("acmedns: solver is required")
+	}
+	if cfg.RenewBefore <= 0 {
+		cfg.RenewBefore = 30 * 24 * time.Hour
+	}
+	if err := os.MkdirAll(cfg.CacheDir, 0o700); err != nil {
+		return nil, fmt.Errorf("acmedns: create cache dir: %w", err)
+	}
+	m := &Manager{cfg: cfg, solver: solver}
+	cert, err := tls.LoadX509KeyPair(m.path(certFile), m.path(keyFile))
+	if err == nil && cert.Leaf != nil && coversDomains(cert.Leaf, cfg.Domains) {
+		m.cert.Store(&cert)
+	}
+	return m, nil
+}
+
+// GetCertificate 供 tls.Config 使用
+func (m *Manager) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	if cert := m.cert.Load(); cert != nil {
+		return cert, nil
+	}
+	return nil, ErrNoCertificate
+}
+
+// NotAfter 返回当前证书的过期时间，没有证书时为零值
+func (m *Manager) NotAfter() time.Time {
+	if cert := m.cert.Load(); cert != nil && cert.Leaf != nil {
+		return cert.Leaf.NotAfter
+	}
+	return time.Time{}
+}
+
+// NeedsRenewal 没有证书或距过期不足 RenewBefore 时返回 true
+func (m *Manager) NeedsRenewal(now time.Time) bool {
+	notAfter := m.NotAfter()
+	return notAfter.IsZero() || now.Add(m.cfg.RenewBefore).After(notAfter)
+}
+
+// Run 立即检查一次，此后每隔 interval 检查；失败时按 retry 间隔重试，直到 stop 关闭。
+// onResult 接收每次申请的结果（不需要续期时不会调用）
+func (m *Manager) Run(interval, retry time.Duration, stop <-chan struct{}, onResult func(error)) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-stop
+		cancel()
+	}()
+
+	wait := time.Duration(0)
+	for {
+		select {
+		case <-stop:
+			return
+		case <-time.After(wait):
+		}
+		wait = interval
+		if !m.NeedsRenewal(time.Now()) {
+			continue
+		}
+		err := m.Obtain(ctx)
+		if onResult != nil {
+			onResult(err)
+		}
+		if err != nil {
+			wait = retry
+		}
+	}
+}
+
+// Obtain 完成一次完整的 ACME 订单并替换当前证书
+func (m *Manager) Obtain(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	client, err := m.client(ctx)
+	if err != nil {
+		return err
+	}
+	order, err := client.AuthorizeOrder(ctx, acme.DomainIDs(m.cfg.Domains...))
+	if err != nil {
+		return fmt.Errorf("acmedns: create order: %w", err)
+	}
+
+	// 先发布全部 TXT 再逐个 Accept：example.com 与 *.example.com 共用同一个 _acme-challenge 名称
+	type pending struct {
+		authzURL string
+		fqdn     string
+		value    string
+	}
+	var todo []pending
+	defer func() {
+		cleanCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		for _, p := range todo {
+			_ = m.solver.CleanUp(cleanCtx, p.fqdn, p.value)
+		}
+	}()
+	var accept []*acme.Challenge
+	for _, authzURL := range order.AuthzURLs {
+		authz, err := client.GetAuthorization(ctx, authzURL)
+		if err != nil {
+			return fmt.Errorf("acmedns: get authorization: %w", err)
+		}
+		if authz.Status == acme.StatusValid {
+			continue
+		}
+		var chal *acme.Challenge
+		for _, c := range authz.Challenges {
+			if c.Type == "dns-01" {
+				chal = c
+				break
+			}
+		}
+		if chal == nil {
+			return fmt.Errorf("acmedns: no dns-01 challenge for %s", authz.Identifier.Value)
+		}
+		value, err := client.DNS01ChallengeRecord(chal.Token)
+		if err != nil {
+			return err
+		}
+		fqdn := challengeName(authz.Identifier.Value)
+		if err := m.solver.Present(ctx, fqdn, value); err != nil {
+			return fmt.Errorf("acmedns: present challenge: %w", err)
+		}
+		todo = append(todo, pending{authzURL: authzURL, fqdn: fqdn, value: value})
+		accept = append(accept, chal)
+	}
+	for _, chal := range accept {
+		if _, err := client.Accept(ctx, chal); err != nil {
+			return fmt.Errorf("acmedns: accept challenge: %w", err)
+		}
+	}
+	for _, p := range todo {
+		if _, err := client.WaitAuthorization(ctx, p.authzURL); err != nil {
+			return fmt.Errorf("acmedns: authorization failed: %w", err)
+		}
+	}
+
+	order, err = client.WaitOrder(ctx, order.URI)
+	if err != nil {
+		return fmt.Errorf("acmedns: order failed: %w", err)
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: m.cfg.Domains[0]},
+		DNSNames: m.cfg.Domains,
+	}, key)
+	if err != nil {
+		return err
+	}
+	chain, _, err := client.CreateOrderCert(ctx, order.FinalizeURL, csr, true)
+	if err != nil {
+		return fmt.Errorf("acmedns: finalize order: %w", err)
+	}
+	leaf, err := x509.ParseCertificate(chain[0])
+	if err != nil {
+		return fmt.Errorf("acmedns: parse certificate: %w", err)
+	}
+	if err := m.save(chain, key); err != nil {
+		return err
+	}
+	m.cert.Store(&tls.Certificate{Certificate: chain, PrivateKey: key, Leaf: leaf})
+	return nil
+}
+
+// client 加载或生成账户私钥并注册账户（已存在时复用）
+func (m *Manager) client(ctx context.Context) (*acme.Client, error) {
+	key, err := m.accountKey()
+	if err != nil {
+		return nil, err
+	}
+	client := &acme.Client{Key: key, DirectoryURL: m.cfg.DirectoryURL, HTTPClient: m.cfg.HTTPClient}
+	acct := &acme.Account{}
+	if m.cfg.Email != "" {
+		acct.Contact = []string{"mailto:" + m.cfg.Email}
+	}
+	if _, err := client.Register(ctx, acct, acme.AcceptTOS); err != nil && !errors.Is(err, acme.ErrAccountAlreadyExists) {
+		return nil, fmt.Errorf("acmedns: register account: %w", err)
+	}
+	return client, nil
+}
+
+func (m *Manager) accountKey() (crypto.Signer, error) {
+	data, err := os.ReadFile(m.path(accountKeyFile))
+	if err == nil {
+		block, _ := pem.Decode(data)
+		if block == nil {
+			return nil, errors.New("acmedns: invalid account key")
+		}
+		return x509.ParseECPrivateKey(block.Bytes)
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeFileAtomic(m.path(accountKeyFile), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func (m *Manager) save(chain [][]byte, key *ecdsa.PrivateKey) error {
+	var certPEM []byte
+	for _, der := range chain {
+		certPEM = append(certPEM, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})...)
+	}
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return err
+	}
+	// 先写私钥再写证书，进程中途退出时 LoadX509KeyPair 会因不匹配而忽略缓存
+	if err := writeFileAtomic(m.path(keyFile), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})); err != nil {
+		return err
+	}
+	return writeFileAtomic(m.path(certFile), certPEM)
+}
+
+func (m *Manager) path(name string) string {
+	return filepath.Join(m.cfg.CacheDir, name)
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// challengeName 返回 DNS-01 记录名（不带末尾的点）
+func challengeName(domain string) string {
+	return "_acme-challenge." + strings.TrimPrefix(strings.TrimSuffix(domain, "."), "*.")
+}
+
+// coversDomains 判断证书是否包含全部所需域名
+func coversDomains(leaf *x509.Certificate, domains []string) bool {
+	names := make(map[string]bool, len(leaf.DNSNames))
+	for _, n := range leaf.DNSNames {
+		names[strings.ToLower(n)] = true
+	}
+	for _, d := range domains {
+		if !names[strings.ToLower(d)] {
+			return false
+		}
+	}
+	return true
+}
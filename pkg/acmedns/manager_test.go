@@ -0,0 +1,157 @@
+package acmedns
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memSolver struct {
+	mu     sync.Mutex
+	values map[string][]string
+}
+
+func (s *memSolver) Present(_ context.Context, fqdn, value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.values == nil {
+		s.values = make(map[string][]string)
+	}
+	s.values[fqdn] = append(s.values[fqdn], value)
+	return nil
+}
+
+func (s *memSolver) CleanUp(_ context.Context, fqdn, value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kept := s.values[fqdn][:0]
+	for _, v := range s.values[fqdn] {
+		if v != value {
+			kept = append(kept, v)
+		}
+	}
+	s.values[fqdn] = kept
+	return nil
+}
+
+func (s *memSolver) lookup(fqdn string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.values[fqdn]...)
+}
+
+func writeSelfSigned(t *testing.T, dir string, names []string, notAfter time.Time) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: names[0]},
+		DNSNames:     names,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, certFile), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, keyFile), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+}
+
+func TestNewLoadsCachedCertificate(t *testing.T) {
+	dir := t.TempDir()
+	domains := []string{"demo.com", "*.demo.com"}
+	notAfter := time.Now().Add(60 * 24 * time.Hour).Truncate(time.Second)
+	writeSelfSigned(t, dir, domains, notAfter)
+
+	m, err := New(Config{DirectoryURL: "https://acme.invalid/dir", Domains: domains, CacheDir: dir}, &memSolver{})
+	require.NoError(t, err)
+	cert, err := m.GetCertificate(nil)
+	require.NoError(t, err)
+	assert.Equal(t, notAfter.UTC(), cert.Leaf.NotAfter.UTC())
+	assert.False(t, m.NeedsRenewal(time.Now()))
+	assert.True(t, m.NeedsRenewal(time.Now().Add(31*24*time.Hour)))
+
+	// 域名变化后缓存证书不再适用
+	m, err = New(Config{DirectoryURL: "https://acme.invalid/dir", Domains: []string{"*.other.com"}, CacheDir: dir}, &memSolver{})
+	require.NoError(t, err)
+	_, err = m.GetCertificate(nil)
+	assert.ErrorIs(t, err, ErrNoCertificate)
+	assert.True(t, m.NeedsRenewal(time.Now()))
+}
+
+func TestChallengeName(t *testing.T) {
+	assert.Equal(t, "_acme-challenge.demo.com", challengeName("*.demo.com"))
+	assert.Equal(t, "_acme-challenge.demo.com", challengeName("demo.com."))
+}
+
+// TestObtainWithPebble 针对本地 Pebble 完成一次完整申请。需要先启动：
+//
+//	pebble -config test/config/pebble-config.json -dnsserver 127.0.0.1:8053
+//
+// 并设置 ACMEDNS_PEBBLE_DIRECTORY=https://127.0.0.1:14000/dir、
+// ACMEDNS_PEBBLE_CA=<pebble.minica.pem>、ACMEDNS_PEBBLE_DNS=127.0.0.1:8053
+func TestObtainWithPebble(t *testing.T) {
+	directory := os.Getenv("ACMEDNS_PEBBLE_DIRECTORY")
+	if directory == "" {
+		t.Skip("ACMEDNS_PEBBLE_DIRECTORY not set")
+	}
+	caPEM, err := os.ReadFile(os.Getenv("ACMEDNS_PEBBLE_CA"))
+	require.NoError(t, err)
+	pool := x509.NewCertPool()
+	require.True(t, pool.AppendCertsFromPEM(caPEM))
+
+	solver := &memSolver{}
+	mux := dns.NewServeMux()
+	mux.HandleFunc(".", func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		q := r.Question[0]
+		if q.Qtype == dns.TypeTXT {
+			for _, v := range solver.lookup(strings.TrimSuffix(strings.ToLower(q.Name), ".")) {
+				m.Answer = append(m.Answer, &dns.TXT{
+					Hdr: dns.RR_Header{Name: q.Name, Rrtype: dns.TypeTXT, Class: dns.ClassINET},
+					Txt: []string{v},
+				})
+			}
+		}
+		_ = w.WriteMsg(m)
+	})
+	srv := &dns.Server{Addr: os.Getenv("ACMEDNS_PEBBLE_DNS"), Net: "udp", Handler: mux}
+	go func() { _ = srv.ListenAndServe() }()
+	t.Cleanup(func() { _ = srv.Shutdown() })
+
+	m, err := New(Config{
+		DirectoryURL: directory,
+		Domains:      []string{"demo.test", "*.demo.test"},
+		CacheDir:     t.TempDir(),
+		HTTPClient:   &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}},
+	}, solver)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	require.NoError(t, m.Obtain(ctx))
+	cert, err := m.GetCertificate(nil)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"demo.test", "*.demo.test"}, cert.Leaf.DNSNames)
+	assert.Empty(t, solver.lookup("_acme-challenge.demo.test"), "challenge values are cleaned up")
+}
@@ -0,0 +1,220 @@
+package ldapd
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+var errMalformed = errors.New("ldapd: malformed BER")
+
+// element 一个 BER TLV；只支持单字节 tag（LDAP 不使用高位 tag 号）
+type element struct {
+	tag     byte
+	content []byte
+}
+
+func (e element) constructed() bool { return e.tag&0x20 != 0 }
+
+// children 解析构造类型的子元素
+func (e element) children() ([]element, error) {
+	var out []element
+	rest := e.content
+	for len(rest) > 0 {
+		el, n, err := parseElement(rest)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, el)
+		rest = rest[n:]
+	}
+	return out, nil
+}
+
+func (e element) int() (int64, error) {
+	if len(e.content) == 0 || len(e.content) > 8 {
+		return 0, errMalformed
+	}
+	v := int64(int8(e.content[0]))
+	for _, b := range e.content[1:] {
+		v = v<<8 | int64(b)
+	}
+	return v, nil
+}
+
+// parseElement 从 buf 开头解析一个元素，返回消耗的字节数
+func parseElement(buf []byte) (element, int, error) {
+	if len(buf) < 2 {
+		return element{}, 0, errMalformed
+	}
+	tag := buf[0]
+	if tag&0x1f == 0x1f {
+		return element{}, 0, errMalformed
+	}
+	length, hdr, err := parseLength(buf[1:])
+	if err != nil {
+		return element{}, 0, err
+	}
+	end := 1 + hdr + length
+	if end > len(buf) {
+		return element{}, 0, errMalformed
+	}
+	return element{tag: tag, content: buf[1+hdr : end]}, end, nil
+}
+
+func parseLength(buf []byte) (int, int, error) {
+	if len(buf) == 0 {
+		return 0, 0, errMalformed
+	}
+	first := buf[0]
+	if first < 0x80 {
+		return int(first), 1, nil
+	}
+	n := int(first & 0x7f)
+	if n == 0 || n > 3 || len(buf) < 1+n {
+		return 0, 0, errMalformed
+	}
+	length := 0
+	for _, b := range buf[1 : 1+n] {
+		length = length<<8 | int(b)
+	}
+	return length, 1 + n, nil
+}
+
+// readMessage 从流中读取一个完整的顶层元素，超过 maxSize 时返回错误
+func readMessage(r *bufio.Reader, maxSize int) (element, error) {
+	tag, err := r.ReadByte()
+	if err != nil {
+		return element{}, err
+	}
+	first, err := r.ReadByte()
+	if err != nil {
+		return element{}, err
+	}
+	hdr := []byte{first}
+	if first >= 0x80 {
+		n := int(first & 0x7f)
+		if n == 0 || n > 3 {
+			return element{}, errMalformed
+		}
+		more := make([]byte, n)
+		if _, err := io.ReadFull(r, more); err != nil {
+			return element{}, err
+		}
+		hdr = append(hdr, more...)
+	}
+	length, _, err := parseLength(hdr)
+	if err != nil {
+		return element{}, err
+	}
+	if length > maxSize {
+		return element{}, errMalformed
+	}
+	content := make([]byte, length)
+	if _, err := io.ReadFull(r, content); err != nil {
+		return element{}, err
+	}
+	return element{tag: tag, content: content}, nil
+}
+
+func encode(tag byte, content []byte) []byte {
+	out := []byte{tag}
+	switch n := len(content); {
+	case n < 0x80:
+		out = append(out, byte(n))
+	case n <= 0xff:
+		out = append(out, 0x81, byte(n))
+	case n <= 0xffff:
+		out = append(out, 0x82, byte(n>>8), byte(n))
+	default:
+		out = append(out, 0x83, byte(n>>16), byte(n>>8), byte(n))
+	}
+	return append(out, content...)
+}
+
+func encodeInt(tag byte, v int64) []byte {
+	var b []byte
+	for {
+		b = append([]byte{byte(v)}, b...)
+		if (v < 0x80 && v >= -0x80) || len(b) == 8 {
+			break
+		}
+		v >>= 8
+	}
+	return encode(tag, b)
+}
+
+func concat(parts ...[]byte) []byte {
+	var out []byte
+	for _, p := range parts {
+		out = append(out, p...)
+	}
+	return out
+}
+
+// renderFilter 将搜索过滤器还原为 RFC 4515 字符串形式，仅用于记录
+func renderFilter(e element) string {
+	switch e.tag {
+	case 0xa0, 0xa1:
+		children, err := e.children()
+		if err != nil {
+			return "?"
+		}
+		op := "&"
+		if e.tag == 0xa1 {
+			op = "|"
+		}
+		var b strings.Builder
+		b.WriteString("(" + op)
+		for _, c := range children {
+			b.WriteString(renderFilter(c))
+		}
+		b.WriteString(")")
+		return b.String()
+	case 0xa2:
+		children, err := e.children()
+		if err != nil || len(children) != 1 {
+			return "?"
+		}
+		return "(!" + renderFilter(children[0]) + ")"
+	case 0xa3, 0xa5, 0xa6, 0xa8:
+		children, err := e.children()
+		if err != nil || len(children) != 2 {
+			return "?"
+		}
+		op := map[byte]string{0xa3: "=", 0xa5: ">=", 0xa6: "<=", 0xa8: "~="}[e.tag]
+		return "(" + string(children[0].content) + op + string(children[1].content) + ")"
+	case 0xa4:
+		children, err := e.children()
+		if err != nil || len(children) != 2 {
+			return "?"
+		}
+		subs, err := children[1].children()
+		if err != nil {
+			return "?"
+		}
+		var initial, final string
+		var middle []string
+		for _, s := range subs {
+			switch s.tag {
+			case 0x80:
+				initial = string(s.content)
+			case 0x81:
+				middle = append(middle, string(s.content))
+			case 0x82:
+				final = string(s.content)
+			}
+		}
+		value := initial + "*"
+		for _, a := range middle {
+			value += a + "*"
+		}
+		return "(" + string(children[0].content) + "=" + value + final + ")"
+	case 0x87:
+		return "(" + string(e.content) + "=*)"
+	default:
+		return "(?tag=" + strconv.Itoa(int(e.tag)) + ")"
+	}
+}
@@ -0,0 +1,4 @@
+// Package ldapd implements just enough of LDAPv3 (RFC 4511) to observe callbacks: it
+// answers bind requests with success and search requests with either an empty result
+// or a referral chosen by the caller. It stores no directory data.
+package ldapd
@@ -0,0 +1,165 @@
+package ldapd
+
+import (
+	"bufio"
+	"net"
+	"time"
+)
+
+const (
+	OpBind   = "bind"
+	OpSearch = "search"
+)
+
+// LDAP 结果码
+const (
+	resultSuccess       = 0
+	resultProtocolError = 2
+	resultReferral      = 10
+)
+
+// Request 一次绑定或搜索请求
+type Request struct {
+	RemoteAddr net.Addr
+	Op         string
+	MessageID  int64
+	Version    int64    // 仅 bind
+	DN         string   // bind 的 name 或 search 的 baseObject
+	Scope      int64    // 仅 search：0 base、1 one、2 sub
+	Filter     string   // 仅 search，RFC 4515 形式
+	Attributes []string // 仅 search
+}
+
+// Handler 观察每个请求；对 search 返回的非空 URL 列表作为 referral 返回，否则返回空结果
+type Handler func(req *Request) []string
+
+// Server 处理单个连接上的 LDAP 会话；零值字段使用默认值
+type Server struct {
+	Timeout        time.Duration // 等待下一个请求的超时，默认 30 秒
+	MaxMessageSize int           // 单个请求最大字节数，默认 64 KiB
+	Handler        Handler
+}
+
+func (s *Server) timeout() time.Duration {
+	if s.Timeout > 0 {
+		return s.Timeout
+	}
+	return 30 * time.Second
+}
+
+func (s *Server) maxMessageSize() int {
+	if s.MaxMessageSize > 0 {
+		return s.MaxMessageSize
+	}
+	return 64 << 10
+}
+
+// ServeConn 处理连接直到客户端 unbind、断开或出现无法解析的请求；不负责关闭 conn
+func (s *Server) ServeConn(conn net.Conn) {
+	r := bufio.NewReader(conn)
+	for {
+		_ = conn.SetReadDeadline(time.Now().Add(s.timeout()))
+		msg, err := readMessage(r, s.maxMessageSize())
+		if err != nil || msg.tag != 0x30 {
+			return
+		}
+		parts, err := msg.children()
+		if err != nil || len(parts) < 2 || parts[0].tag != 0x02 {
+			return
+		}
+		id, err := parts[0].int()
+		if err != nil {
+			return
+		}
+		op := parts[1]
+
+		var resp []byte
+		switch op.tag {
+		case 0x60: // BindRequest
+			req, ok := parseBind(op)
+			if !ok {
+				return
+			}
+			req.RemoteAddr, req.MessageID = conn.RemoteAddr(), id
+			s.handle(req)
+			resp = ldapResult(0x61, resultSuccess, nil)
+		case 0x63: // SearchRequest
+			req, ok := parseSearch(op)
+			if !ok {
+				return
+			}
+			req.RemoteAddr, req.MessageID = conn.RemoteAddr(), id
+			if referrals := s.handle(req); len(referrals) > 0 {
+				resp = ldapResult(0x65, resultReferral, referrals)
+			} else {
+				resp = ldapResult(0x65, resultSuccess, nil)
+			}
+		case 0x42: // UnbindRequest
+			return
+		case 0x50: // AbandonRequest 无需应答
+			continue
+		case 0x77: // ExtendedRequest（如 StartTLS）不支持
+			resp = ldapResult(0x78, resultProtocolError, nil)
+		default:
+			return
+		}
+		_ = conn.SetWriteDeadline(time.Now().Add(s.timeout()))
+		if _, err := conn.Write(encode(0x30, concat(encodeInt(0x02, id), resp))); err != nil {
+			return
+		}
+	}
+}
+
+func (s *Server) handle(req *Request) []string {
+	if s.Handler == nil {
+		return nil
+	}
+	return s.Handler(req)
+}
+
+func parseBind(op element) (*Request, bool) {
+	fields, err := op.children()
+	if err != nil || len(fields) < 2 || fields[0].tag != 0x02 || fields[1].tag != 0x04 {
+		return nil, false
+	}
+	version, err := fields[0].int()
+	if err != nil {
+		return nil, false
+	}
+	return &Request{Op: OpBind, Version: version, DN: string(fields[1].content)}, true
+}
+
+func parseSearch(op element) (*Request, bool) {
+	fields, err := op.children()
+	if err != nil || len(fields) < 7 || fields[0].tag != 0x04 || fields[1].tag != 0x0a {
+		return nil, false
+	}
+	scope, err := fields[1].int()
+	if err != nil {
+		return nil, false
+	}
+	req := &Request{Op: OpSearch, DN: string(fields[0].content), Scope: scope, Filter: renderFilter(fields[6])}
+	if len(fields) > 7 && fields[7].constructed() {
+		attrs, err := fields[7].children()
+		if err != nil {
+			return nil, false
+		}
+		for _, a := range attrs {
+			req.Attributes = append(req.Attributes, string(a.content))
+		}
+	}
+	return req, true
+}
+
+// ldapResult 编码 LDAPResult；referrals 非空时附带 referral 字段
+func ldapResult(tag byte, code int64, referrals []string) []byte {
+	content := concat(encodeInt(0x0a, code), encode(0x04, nil), encode(0x04, nil))
+	if len(referrals) > 0 {
+		var urls []byte
+		for _, u := range referrals {
+			urls = append(urls, encode(0x04, []byte(u))...)
+		}
+		content = append(content, encode(0xa3, urls)...)
+	}
+	return encode(tag, content)
+}
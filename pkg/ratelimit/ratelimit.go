@@ -0,0 +1,143 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var ErrInvalidQuota = errors.New("ratelimit: invalid quota")
+
+// Quota 每 Period 最多 Limit 次，允许一次性用完（突发上限等于 Limit）
+type Quota struct {
+	Limit  int
+	Period time.Duration
+}
+
+// ParseQuota 解析 "<limit>/<seconds>"，如 "600/60"
+func ParseQuota(s string) (Quota, error) {
+	limit, seconds, ok := strings.Cut(strings.TrimSpace(s), "/")
+	if !ok {
+		return Quota{}, fmt.Errorf("%w: %q", ErrInvalidQuota, s)
+	}
+	l, err1 := strconv.Atoi(strings.TrimSpace(limit))
+	p, err2 := strconv.Atoi(strings.TrimSpace(seconds))
+	if err1 != nil || err2 != nil || l <= 0 || p <= 0 {
+		return Quota{}, fmt.Errorf("%w: %q", ErrInvalidQuota, s)
+	}
+	return Quota{Limit: l, Period: time.Duration(p) * time.Second}, nil
+}
+
+func (q Quota) interval() int64 {
+	iv := q.Period.Milliseconds() / int64(q.Limit)
+	if iv <= 0 {
+		iv = 1
+	}
+	return iv
+}
+
+// Result 一次判定的结果
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration // 被拒绝时距下次可用的时间
+	ResetAfter time.Duration // 额度完全恢复所需时间
+}
+
+// decide GCRA：tat 为理论到达时间，返回新的 tat 与结果
+func decide(tat, now int64, q Quota) (int64, Result) {
+	interval := q.interval()
+	if tat < now {
+		tat = now
+	}
+	newTAT := tat + interval
+	allowAt := newTAT - interval*int64(q.Limit)
+	if allowAt > now {
+		return tat, Result{
+			Limit:      q.Limit,
+			RetryAfter: time.Duration(allowAt-now) * time.Millisecond,
+			ResetAfter: time.Duration(tat-now) * time.Millisecond,
+		}
+	}
+	return newTAT, Result{
+		Allowed:    true,
+		Limit:      q.Limit,
+		Remaining:  int((now - allowAt) / interval),
+		ResetAfter: time.Duration(newTAT-now) * time.Millisecond,
+	}
+}
+
+// gcraScript 与 decide 逻辑一致；返回 {allowed, remaining, retry_ms, reset_ms}
+var gcraScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local interval = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+local tat = tonumber(redis.call('GET', KEYS[1]) or now)
+if tat < now then tat = now end
+local new_tat = tat + interval
+local allow_at = new_tat - interval * limit
+if allow_at > now then
+  return {0, 0, allow_at - now, tat - now}
+end
+redis.call('SET', KEYS[1], new_tat, 'PX', new_tat - now)
+return {1, math.floor((now - allow_at) / interval), 0, new_tat - now}
+`)
+
+// AllowRedis 在 Redis 中对 key 执行一次判定，多实例共享额度
+func AllowRedis(ctx context.Context, client redis.Scripter, key string, q Quota, now time.Time) (Result, error) {
+	vals, err := gcraScript.Run(ctx, client, []string{key}, now.UnixMilli(), q.interval(), q.Limit).Int64Slice()
+	if err != nil {
+		return Result{}, err
+	}
+	if len(vals) != 4 {
+		return Result{}, fmt.Errorf("ratelimit: unexpected script result %v", vals)
+	}
+	return Result{
+		Allowed:    vals[0] == 1,
+		Limit:      q.Limit,
+		Remaining:  int(vals[1]),
+		RetryAfter: time.Duration(vals[2]) * time.Millisecond,
+		ResetAfter: time.Duration(vals[3]) * time.Millisecond,
+	}, nil
+}
+
+// Local 进程内限流器，Redis 不可用时使用（额度按实例计算）
+type Local struct {
+	mu        sync.Mutex
+	tats      map[string]int64
+	lastSweep int64
+}
+
+func NewLocal() *Local {
+	return &Local{tats: make(map[string]int64)}
+}
+
+func (l *Local) Allow(key string, q Quota, now time.Time) Result {
+	nowMs := now.UnixMilli()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sweep(nowMs)
+	tat, res := decide(l.tats[key], nowMs, q)
+	l.tats[key] = tat
+	return res
+}
+
+// sweep 每分钟清理一次额度已完全恢复的条目
+func (l *Local) sweep(nowMs int64) {
+	if nowMs-l.lastSweep < 60_000 {
+		return
+	}
+	l.lastSweep = nowMs
+	for k, tat := range l.tats {
+		if tat <= nowMs {
+			delete(l.tats, k)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseQuota(t *testing.T) {
+	q, err := ParseQuota(" 600/60 ")
+	require.NoError(t, err)
+	assert.Equal(t, Quota{Limit: 600, Period: time.Minute}, q)
+
+	for _, in := range []string{"", "10", "0/60", "10/0", "a/b", "-1/60"} {
+		_, err := ParseQuota(in)
+		assert.ErrorIs(t, err, ErrInvalidQuota, in)
+	}
+}
+
+func TestLocalAllow(t *testing.T) {
+	l := NewLocal()
+	q := Quota{Limit: 3, Period: 3 * time.Second}
+	now := time.Unix(1700000000, 0)
+
+	for i := 2; i >= 0; i-- {
+		res := l.Allow("k", q, now)
+		require.True(t, res.Allowed)
+		assert.Equal(t, i, res.Remaining)
+	}
+	res := l.Allow("k", q, now)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, time.Second, res.RetryAfter)
+	assert.Equal(t, 3*time.Second, res.ResetAfter)
+
+	// 其他 key 不受影响
+	assert.True(t, l.Allow("other", q, now).Allowed)
+
+	// 滑动恢复：1 秒后恢复一次额度
+	res = l.Allow("k", q, now.Add(time.Second))
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 0, res.Remaining)
+
+	res = l.Allow("k", q, now.Add(10*time.Second))
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 2, res.Remaining)
+}
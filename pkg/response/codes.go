@@ -19,4 +19,34 @@ const (
 	CodeWebhookSecretKeyRequired = "webhook_secret_key_required"
 	CodeConflict        = "conflict"
 	CodeAPIKeyAlreadyInitialized = "api_key_already_initialized"
+	CodeWebhookURLInvalid        = "webhook_url_invalid"
+	CodeWebhookSchemeNotAllowed  = "webhook_scheme_not_allowed"
+	CodeWebhookHostDenied        = "webhook_host_denied"
+	CodeWebhookIPDenied          = "webhook_ip_denied"
+	CodeWebhookResolveFailed     = "webhook_resolve_failed"
+	CodeWebhookHeadersInvalid    = "webhook_headers_invalid"
+	CodeWebhookAuthInvalid       = "webhook_auth_invalid"
+	CodeInsufficientScope        = "insufficient_scope"
+	CodeInvalidScope             = "invalid_scope"
+	CodeScopeExceeded            = "scope_exceeded"
+	CodeExpiredAPIKey            = "expired_key"
+	CodeAPIKeyNotRotatable       = "api_key_not_rotatable"
+	CodeInvalidTenant            = "invalid_tenant"
+	CodeInvalidCredentials       = "invalid_credentials"
+	CodeInvalidSession           = "invalid_session"
+	CodeCSRFTokenInvalid         = "csrf_token_invalid"
+	CodeTOTPRequired             = "totp_required"
+	CodeInvalidTOTP              = "invalid_totp"
+	CodeTOTPNotSetup             = "totp_not_setup"
+	CodeWeakPassword             = "weak_password"
+	CodeInvalidRole              = "invalid_role"
+	CodeUserExists               = "user_exists"
+	CodeOIDCUnavailable          = "oidc_unavailable"
+	CodeOIDCLoginFailed          = "oidc_login_failed"
+	CodeOIDCNoRole               = "oidc_no_role"
+	CodeInvalidBlacklistIP       = "invalid_blacklist_ip"
+	CodeInvalidBlacklistScope    = "invalid_blacklist_scope"
+	CodeTokenResponseInvalid     = "token_response_invalid"
+	CodeEncryptionKeyRequired    = "encryption_key_required"
+	CodeExportTooLarge           = "export_too_large"
 )
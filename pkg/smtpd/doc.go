@@ -0,0 +1,4 @@
+// Package smtpd implements a minimal receive-only SMTP server (RFC 5321) for capturing
+// mail: it accepts messages for recipients approved by the caller and hands each
+// envelope to a handler. It never relays or delivers mail.
+package smtpd
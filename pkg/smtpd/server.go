@@ -0,0 +1,497 @@
+package smtpd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrServerClosed = errors.New("smtpd: server closed")
+
+const (
+	maxLineLength  = 4096
+	maxBadCommands = 10
+)
+
+// Envelope 一封已接收的邮件
+type Envelope struct {
+	RemoteAddr net.Addr
+	Helo       string
+	From       string // 空字符串表示空发件人 <>
+	To         []string
+	Data       []byte // 去除点转义后的原始报文，超过 MaxSize 的部分被丢弃
+	Size       int64  // 原始报文长度
+	TLS        bool
+}
+
+// Server 只接收不转发的 SMTP 服务；零值字段使用默认值
+type Server struct {
+	Hostname    string
+	MaxSize     int64         // 保存的报文最大字节数，默认 1 MiB
+	MaxRcpts    int           // 单封邮件最多收件人，默认 100
+	Timeout     time.Duration // 等待单条命令的超时，默认 1 分钟
+	DataTimeout time.Duration // 接收 DATA 的总超时，默认 10 分钟
+	TLSConfig   *tls.Config   // 非空时支持 STARTTLS
+
+	MaxConns      int // 同时处理的连接上限，默认 1024；超出时回复 421 并断开
+	MaxConnsPerIP int // 同一来源 IP 同时处理的连接上限，默认 16
+
+	AcceptConn func(remote net.Addr) bool              // 返回 false 时以 554 拒绝连接
+	AcceptRcpt func(remote net.Addr, rcpt string) bool // 返回 false 时以 550 拒绝该收件人
+	Handler    func(env *Envelope) error
+
+	mu        sync.Mutex
+	listeners map[net.Listener]struct{}
+	conns     map[net.Conn]struct{}
+	perIP     map[string]int
+	closed    bool
+	wg        sync.WaitGroup
+}
+
+// ListenAndServe 监听 addr 并处理连接，直到 Shutdown
+func (s *Server) ListenAndServe(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ln)
+}
+
+// Serve 在 ln 上接受连接；Shutdown 后返回 ErrServerClosed
+func (s *Server) Serve(ln net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		ln.Close()
+		return ErrServerClosed
+	}
+	if s.listeners == nil {
+		s.listeners = make(map[net.Listener]struct{})
+	}
+	s.listeners[ln] = struct{}{}
+	s.mu.Unlock()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			delete(s.listeners, ln)
+			s.mu.Unlock()
+			if closed {
+				return ErrServerClosed
+			}
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() {
+				time.Sleep(50 * time.Millisecond)
+				continue
+			}
+			return err
+		}
+		admitted, closed := s.track(conn)
+		if closed {
+			conn.Close()
+			return ErrServerClosed
+		}
+		if !admitted {
+			_ = conn.SetWriteDeadline(time.Now().Add(time.Second))
+			_, _ = conn.Write([]byte("421 4.7.0 too many connections\r\n"))
+			conn.Close()
+			continue
+		}
+		go func() {
+			defer s.untrack(conn)
+			s.handle(conn)
+		}()
+	}
+}
+
+// Shutdown 停止监听并等待进行中的会话结束；ctx 到期后强制关闭剩余连接
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.closed = true
+	for ln := range s.listeners {
+		ln.Close()
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		for c := range s.conns {
+			c.Close()
+		}
+		s.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// track 登记连接；超过并发上限时 admitted 为 false，Shutdown 之后 closed 为 true
+func (s *Server) track(conn net.Conn) (admitted, closed bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return false, true
+	}
+	if s.conns == nil {
+		s.conns = make(map[net.Conn]struct{})
+		s.perIP = make(map[string]int)
+	}
+	ip := remoteIP(conn)
+	if len(s.conns) >= s.maxConns() || s.perIP[ip] >= s.maxConnsPerIP() {
+		return false, false
+	}
+	s.conns[conn] = struct{}{}
+	s.perIP[ip]++
+	s.wg.Add(1)
+	return true, false
+}
+
+func (s *Server) untrack(conn net.Conn) {
+	conn.Close()
+	ip := remoteIP(conn)
+	s.mu.Lock()
+	delete(s.conns, conn)
+	if s.perIP[ip]--; s.perIP[ip] <= 0 {
+		delete(s.perIP, ip)
+	}
+	s.mu.Unlock()
+	s.wg.Done()
+}
+
+func remoteIP(conn net.Conn) string {
+	addr := conn.RemoteAddr().String()
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
+
+func (s *Server) hostname() string {
+	if s.Hostname != "" {
+		return s.Hostname
+	}
+	return "localhost"
+}
+
+func (s *Server) maxSize() int64 {
+	if s.MaxSize > 0 {
+		return s.MaxSize
+	}
+	return 1 << 20
+}
+
+func (s *Server) maxConns() int {
+	if s.MaxConns > 0 {
+		return s.MaxConns
+	}
+	return 1024
+}
+
+func (s *Server) maxConnsPerIP() int {
+	if s.MaxConnsPerIP > 0 {
+		return s.MaxConnsPerIP
+	}
+	return 16
+}
+
+func (s *Server) maxRcpts() int {
+	if s.MaxRcpts > 0 {
+		return s.MaxRcpts
+	}
+	return 100
+}
+
+func (s *Server) timeout() time.Duration {
+	if s.Timeout > 0 {
+		return s.Timeout
+	}
+	return time.Minute
+}
+
+func (s *Server) dataTimeout() time.Duration {
+	if s.DataTimeout > 0 {
+		return s.DataTimeout
+	}
+	return 10 * time.Minute
+}
+
+type session struct {
+	srv  *Server
+	conn net.Conn
+	r    *bufio.Reader
+	w    *bufio.Writer
+	tls  bool
+
+	helo    string
+	hasFrom bool
+	from    string
+	to      []string
+}
+
+func (s *Server) handle(conn net.Conn) {
+	sess := &session{
+		srv:  s,
+		conn: conn,
+		r:    bufio.NewReaderSize(conn, maxLineLength),
+		w:    bufio.NewWriter(conn),
+	}
+	if s.AcceptConn != nil && !s.AcceptConn(conn.RemoteAddr()) {
+		sess.reply(554, "5.7.1 access denied")
+		return
+	}
+	sess.reply(220, s.hostname()+" ESMTP ready")
+
+	bad := 0
+	for {
+		line, err := sess.readLine()
+		if errors.Is(err, bufio.ErrBufferFull) {
+			sess.reply(500, "5.5.2 line too long")
+			return
+		}
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		ok, quit := sess.command(strings.ToUpper(verb), strings.TrimSpace(arg))
+		if quit {
+			return
+		}
+		if !ok {
+			if bad++; bad >= maxBadCommands {
+				sess.reply(421, "4.7.0 too many errors")
+				return
+			}
+		}
+	}
+}
+
+// command 处理一条命令；ok=false 计入错误次数，quit=true 时结束会话
+func (sess *session) command(verb, arg string) (ok, quit bool) {
+	srv := sess.srv
+	switch verb {
+	case "HELO", "EHLO":
+		if arg == "" {
+			sess.reply(501, "5.5.4 domain required")
+			return false, false
+		}
+		sess.helo = arg
+		sess.reset()
+		if verb == "HELO" {
+			sess.reply(250, srv.hostname())
+			return true, false
+		}
+		lines := []string{srv.hostname(), fmt.Sprintf("SIZE %d", srv.maxSize()), "8BITMIME"}
+		if srv.TLSConfig != nil && !sess.tls {
+			lines = append(lines, "STARTTLS")
+		}
+		sess.replyLines(250, lines)
+		return true, false
+	case "STARTTLS":
+		if srv.TLSConfig == nil || sess.tls {
+			sess.reply(502, "5.5.1 not supported")
+			return false, false
+		}
+		sess.reply(220, "2.0.0 ready to start TLS")
+		tlsConn := tls.Server(sess.conn, srv.TLSConfig)
+		_ = tlsConn.SetDeadline(time.Now().Add(srv.timeout()))
+		if err := tlsConn.Handshake(); err != nil {
+			return false, true
+		}
+		_ = tlsConn.SetDeadline(time.Time{})
+		sess.conn = tlsConn
+		sess.r = bufio.NewReaderSize(tlsConn, maxLineLength)
+		sess.w = bufio.NewWriter(tlsConn)
+		sess.tls = true
+		// RFC 3207：TLS 建立后需重新 EHLO
+		sess.helo = ""
+		sess.reset()
+		return true, false
+	case "MAIL":
+		if sess.helo == "" {
+			sess.reply(503, "5.5.1 send HELO first")
+			return false, false
+		}
+		if sess.hasFrom {
+			sess.reply(503, "5.5.1 nested MAIL command")
+			return false, false
+		}
+		addr, ok := parsePath(arg, "FROM:")
+		if !ok {
+			sess.reply(501, "5.5.4 syntax: MAIL FROM:<address>")
+			return false, false
+		}
+		sess.hasFrom = true
+		sess.from = addr
+		sess.reply(250, "2.1.0 ok")
+		return true, false
+	case "RCPT":
+		if !sess.hasFrom {
+			sess.reply(503, "5.5.1 need MAIL first")
+			return false, false
+		}
+		addr, ok := parsePath(arg, "TO:")
+		if !ok || addr == "" {
+			sess.reply(501, "5.5.4 syntax: RCPT TO:<address>")
+			return false, false
+		}
+		if len(sess.to) >= srv.maxRcpts() {
+			sess.reply(452, "4.5.3 too many recipients")
+			return true, false
+		}
+		if srv.AcceptRcpt != nil && !srv.AcceptRcpt(sess.conn.RemoteAddr(), addr) {
+			sess.reply(550, "5.1.1 mailbox unavailable")
+			return false, false
+		}
+		sess.to = append(sess.to, addr)
+		sess.reply(250, "2.1.5 ok")
+		return true, false
+	case "DATA":
+		if len(sess.to) == 0 {
+			sess.reply(503, "5.5.1 need RCPT first")
+			return false, false
+		}
+		sess.reply(354, "end data with <CR><LF>.<CR><LF>")
+		data, size, err := sess.readData()
+		if err != nil {
+			return false, true
+		}
+		env := &Envelope{
+			RemoteAddr: sess.conn.RemoteAddr(),
+			Helo:       sess.helo,
+			From:       sess.from,
+			To:         sess.to,
+			Data:       data,
+			Size:       size,
+			TLS:        sess.tls,
+		}
+		sess.reset()
+		if srv.Handler != nil {
+			if err := srv.Handler(env); err != nil {
+				sess.reply(451, "4.3.0 temporary failure")
+				return true, false
+			}
+		}
+		sess.reply(250, "2.0.0 ok: queued")
+		return true, false
+	case "RSET":
+		sess.reset()
+		sess.reply(250, "2.0.0 ok")
+		return true, false
+	case "NOOP":
+		sess.reply(250, "2.0.0 ok")
+		return true, false
+	case "VRFY":
+		sess.reply(252, "2.1.5 cannot verify user")
+		return true, false
+	case "QUIT":
+		sess.reply(221, "2.0.0 bye")
+		return true, true
+	default:
+		sess.reply(502, "5.5.2 command not recognized")
+		return false, false
+	}
+}
+
+func (sess *session) reset() {
+	sess.hasFrom = false
+	sess.from = ""
+	sess.to = nil
+}
+
+func (sess *session) reply(code int, msg string) {
+	fmt.Fprintf(sess.w, "%d %s\r\n", code, msg)
+	_ = sess.w.Flush()
+}
+
+func (sess *session) replyLines(code int, lines []string) {
+	for i, line := range lines {
+		sep := "-"
+		if i == len(lines)-1 {
+			sep = " "
+		}
+		fmt.Fprintf(sess.w, "%d%s%s\r\n", code, sep, line)
+	}
+	_ = sess.w.Flush()
+}
+
+// readLine 读取一条命令（不含行尾）；超过缓冲区时返回 bufio.ErrBufferFull
+func (sess *session) readLine() (string, error) {
+	_ = sess.conn.SetReadDeadline(time.Now().Add(sess.srv.timeout()))
+	line, err := sess.r.ReadSlice('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(line), "\r\n"), nil
+}
+
+// readData 读取 DATA 内容直到单独一行的 "."，还原点转义，只保留前 MaxSize 字节
+func (sess *session) readData() ([]byte, int64, error) {
+	_ = sess.conn.SetReadDeadline(time.Now().Add(sess.srv.dataTimeout()))
+	limit := sess.srv.maxSize()
+	var buf bytes.Buffer
+	var size int64
+	lineStart := true
+	for {
+		chunk, err := sess.r.ReadSlice('\n')
+		if err != nil && !errors.Is(err, bufio.ErrBufferFull) {
+			if errors.Is(err, io.EOF) {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, 0, err
+		}
+		complete := err == nil
+		if lineStart && complete && (string(chunk) == ".\r\n" || string(chunk) == ".\n") {
+			return buf.Bytes(), size, nil
+		}
+		if lineStart && len(chunk) > 0 && chunk[0] == '.' {
+			chunk = chunk[1:]
+		}
+		size += int64(len(chunk))
+		if room := limit - int64(buf.Len()); room > 0 {
+			if int64(len(chunk)) > room {
+				chunk = chunk[:room]
+			}
+			buf.Write(chunk)
+		}
+		lineStart = complete
+	}
+}
+
+// parsePath 解析 "FROM:<addr> [参数]"，忽略源路由与 ESMTP 参数
+func parsePath(arg, prefix string) (string, bool) {
+	if len(arg) < len(prefix) || !strings.EqualFold(arg[:len(prefix)], prefix) {
+		return "", false
+	}
+	arg = strings.TrimSpace(arg[len(prefix):])
+	if !strings.HasPrefix(arg, "<") {
+		return "", false
+	}
+	end := strings.IndexByte(arg, '>')
+	if end < 0 {
+		return "", false
+	}
+	addr := arg[1:end]
+	if strings.HasPrefix(addr, "@") {
+		_, addr, _ = strings.Cut(addr, ":")
+	}
+	if strings.ContainsAny(addr, " \t") {
+		return "", false
+	}
+	return addr, true
+}
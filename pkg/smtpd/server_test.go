@@ -0,0 +1,125 @@
+package smtpd
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/smtp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func startServer(t *testing.T, srv *Server) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go srv.Serve(ln)
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		_ = srv.Shutdown(ctx)
+	})
+	return ln.Addr().String()
+}
+
+func TestServerReceivesMail(t *testing.T) {
+	got := make(chan *Envelope, 1)
+	srv := &Server{
+		Hostname: "mx.demo.com",
+		MaxSize:  64,
+		AcceptRcpt: func(_ net.Addr, rcpt string) bool {
+			return strings.HasSuffix(rcpt, "@abc123.demo.com")
+		},
+		Handler: func(env *Envelope) error {
+			got <- env
+			return nil
+		},
+	}
+	addr := startServer(t, srv)
+
+	body := "Subject: hi\r\n\r\n.leading dot\r\n" + strings.Repeat("x", 100) + "\r\n"
+	err := smtp.SendMail(addr, nil, "a@b.com", []string{"canary@abc123.demo.com"}, []byte(body))
+	require.NoError(t, err)
+
+	env := <-got
+	assert.Equal(t, "a@b.com", env.From)
+	assert.Equal(t, []string{"canary@abc123.demo.com"}, env.To)
+	assert.Equal(t, int64(len(body)), env.Size)
+	assert.Len(t, env.Data, 64)
+	assert.True(t, strings.HasPrefix(string(env.Data), "Subject: hi\r\n\r\n.leading dot\r\n"))
+
+	err = smtp.SendMail(addr, nil, "a@b.com", []string{"x@other.com"}, []byte(body))
+	assert.ErrorContains(t, err, "550")
+}
+
+func TestServerCommandErrors(t *testing.T) {
+	srv := &Server{Hostname: "mx.demo.com"}
+	conn, err := net.Dial("tcp", startServer(t, srv))
+	require.NoError(t, err)
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	expect := func(code string) {
+		t.Helper()
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(line, code), line)
+	}
+	send := func(cmd, code string) {
+		t.Helper()
+		_, err := conn.Write([]byte(cmd + "\r\n"))
+		require.NoError(t, err)
+		expect(code)
+	}
+
+	expect("220")
+	send("MAIL FROM:<a@b.com>", "503")
+	send("HELO client", "250")
+	send("RCPT TO:<x@y.com>", "503")
+	send("MAIL FROM:<>", "250")
+	send("MAIL FROM:<a@b.com>", "503")
+	send("DATA", "503")
+	send("RSET", "250")
+	send("BOGUS", "502")
+	send("QUIT", "221")
+}
+
+func TestServerLimitsConnectionsPerIP(t *testing.T) {
+	addr := startServer(t, &Server{Hostname: "mx.demo.com", MaxConnsPerIP: 1})
+	first, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer first.Close()
+	line, err := bufio.NewReader(first).ReadString('\n')
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(line, "220"), line)
+
+	second, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer second.Close()
+	line, err = bufio.NewReader(second).ReadString('\n')
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(line, "421"), line)
+}
+
+func TestParsePath(t *testing.T) {
+	cases := []struct {
+		arg, prefix, addr string
+		ok                bool
+	}{
+		{"FROM:<a@b.com>", "FROM:", "a@b.com", true},
+		{"from: <a@b.com> SIZE=100", "FROM:", "a@b.com", true},
+		{"FROM:<>", "FROM:", "", true},
+		{"TO:<@relay.com:x@y.com>", "TO:", "x@y.com", true},
+		{"TO:x@y.com", "TO:", "", false},
+		{"TO:<x@y.com", "TO:", "", false},
+		{"FROM:<a@b.com>", "TO:", "", false},
+	}
+	for _, tc := range cases {
+		addr, ok := parsePath(tc.arg, tc.prefix)
+		assert.Equal(t, tc.ok, ok, tc.arg)
+		assert.Equal(t, tc.addr, addr, tc.arg)
+	}
+}
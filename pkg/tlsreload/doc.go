@@ -0,0 +1,2 @@
+// Package tlsreload serves a TLS certificate from disk and swaps it in place when the files change.
+package tlsreload
@@ -0,0 +1,113 @@
+package tlsreload
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var ErrUnknownVersion = errors.New("tlsreload: unknown tls version")
+
+// Reloader 持有当前证书；重新加载失败时继续使用旧证书
+type Reloader struct {
+	certFile string
+	keyFile  string
+
+	cert atomic.Pointer[tls.Certificate]
+
+	mu      sync.Mutex
+	modTime time.Time
+}
+
+// New 加载证书与私钥，文件无效时返回错误
+func New(certFile, keyFile string) (*Reloader, error) {
+	r := &Reloader{certFile: certFile, keyFile: keyFile}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload 重新读取证书文件
+func (r *Reloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	mod, err := r.latestModTime()
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("tlsreload: load key pair: %w", err)
+	}
+	r.cert.Store(&cert)
+	r.modTime = mod
+	return nil
+}
+
+// ReloadIfChanged 文件修改时间变化时重新加载，返回是否发生了重新加载
+func (r *Reloader) ReloadIfChanged() (bool, error) {
+	r.mu.Lock()
+	mod, err := r.latestModTime()
+	changed := err == nil && !mod.Equal(r.modTime)
+	r.mu.Unlock()
+	if err != nil || !changed {
+		return false, err
+	}
+	return true, r.Reload()
+}
+
+// Watch 按 interval 轮询文件变化，直到 stop 关闭；onReload 接收每次重新加载的结果
+func (r *Reloader) Watch(interval time.Duration, stop <-chan struct{}, onReload func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if changed, err := r.ReloadIfChanged(); (changed || err != nil) && onReload != nil {
+				onReload(err)
+			}
+		}
+	}
+}
+
+// GetCertificate 供 tls.Config 使用
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return r.cert.Load(), nil
+}
+
+func (r *Reloader) latestModTime() (time.Time, error) {
+	var latest time.Time
+	for _, f := range []string{r.certFile, r.keyFile} {
+		st, err := os.Stat(f)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("tlsreload: %w", err)
+		}
+		if st.ModTime().After(latest) {
+			latest = st.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+// ParseVersion 解析 "1.0"～"1.3"，空值视为 1.2
+func ParseVersion(v string) (uint16, error) {
+	switch strings.TrimPrefix(strings.ToLower(strings.TrimSpace(v)), "tls") {
+	case "1.0", "10":
+		return tls.VersionTLS10, nil
+	case "1.1", "11":
+		return tls.VersionTLS11, nil
+	case "", "1.2", "12":
+		return tls.VersionTLS12, nil
+	case "1.3", "13":
+		return tls.VersionTLS13, nil
+	}
+	return 0, ErrUnknownVersion
+}
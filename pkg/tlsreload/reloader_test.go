@@ -0,0 +1,91 @@
+package tlsreload
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeCert(t *testing.T, dir, cn string, mod time.Time) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	require.NoError(t, os.Chtimes(certFile, mod, mod))
+	require.NoError(t, os.Chtimes(keyFile, mod, mod))
+	return certFile, keyFile
+}
+
+func commonName(t *testing.T, r *Reloader) string {
+	cert, err := r.GetCertificate(nil)
+	require.NoError(t, err)
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+	return leaf.Subject.CommonName
+}
+
+func TestReloader(t *testing.T) {
+	dir := t.TempDir()
+	base := time.Now().Add(-time.Minute)
+	certFile, keyFile := writeCert(t, dir, "first", base)
+
+	r, err := New(certFile, keyFile)
+	require.NoError(t, err)
+	assert.Equal(t, "first", commonName(t, r))
+
+	changed, err := r.ReloadIfChanged()
+	require.NoError(t, err)
+	assert.False(t, changed)
+
+	writeCert(t, dir, "second", base.Add(time.Second))
+	changed, err = r.ReloadIfChanged()
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, "second", commonName(t, r))
+
+	// 文件损坏时保留旧证书
+	require.NoError(t, os.WriteFile(certFile, []byte("garbage"), 0o600))
+	assert.Error(t, r.Reload())
+	assert.Equal(t, "second", commonName(t, r))
+}
+
+func TestNewInvalid(t *testing.T) {
+	_, err := New(filepath.Join(t.TempDir(), "missing.pem"), "missing.key")
+	assert.Error(t, err)
+}
+
+func TestParseVersion(t *testing.T) {
+	for in, want := range map[string]uint16{"": tls.VersionTLS12, "1.3": tls.VersionTLS13, "TLS1.2": tls.VersionTLS12, "1.0": tls.VersionTLS10} {
+		v, err := ParseVersion(in)
+		require.NoError(t, err, in)
+		assert.Equal(t, want, v, in)
+	}
+	_, err := ParseVersion("1.4")
+	assert.ErrorIs(t, err, ErrUnknownVersion)
+}
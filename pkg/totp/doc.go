@@ -0,0 +1,2 @@
+// Package totp implements RFC 6238 time-based one-time passwords (SHA-1, 6 digits, 30s step).
+package totp
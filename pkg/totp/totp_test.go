@@ -0,0 +1,55 @@
+package totp
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// RFC 6238 附录 B 的 SHA-1 测试向量（取低 6 位）
+func TestCodeRFC6238(t *testing.T) {
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	cases := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+	for _, tc := range cases {
+		got, err := Code(secret, Step(time.Unix(tc.unix, 0)))
+		assert.NoError(t, err)
+		assert.Equal(t, tc.want, got, tc.unix)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := GenerateSecret()
+	assert.NoError(t, err)
+	now := time.Unix(1700000000, 0)
+
+	code, _ := Code(secret, Step(now)-1)
+	step, err := Validate(secret, code, now)
+	assert.NoError(t, err)
+	assert.Equal(t, Step(now)-1, step)
+
+	code, _ = Code(secret, Step(now)-3)
+	_, err = Validate(secret, code, now)
+	assert.ErrorIs(t, err, ErrInvalidCode)
+
+	_, err = Validate(secret, "12345", now)
+	assert.ErrorIs(t, err, ErrInvalidCode)
+	_, err = Validate("!!!", "123456", now)
+	assert.ErrorIs(t, err, ErrInvalidSecret)
+}
+
+func TestURI(t *testing.T) {
+	uri := URI("dnslog", "alice", "JBSWY3DPEHPK3PXP")
+	assert.Contains(t, uri, "otpauth://totp/dnslog:alice?")
+	assert.Contains(t, uri, "secret=JBSWY3DPEHPK3PXP")
+}
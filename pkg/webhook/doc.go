@@ -0,0 +1,2 @@
+// Package webhook signs and verifies dnslog webhook deliveries; receivers can import it directly.
+package webhook
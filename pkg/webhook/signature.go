@@ -0,0 +1,180 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	HeaderEventID         = "X-Event-ID"
+	HeaderTimestamp       = "X-Webhook-Timestamp"
+	HeaderSignature       = "X-Webhook-Signature"
+	HeaderLegacySignature = "X-Signature"
+
+	// SignatureVersion 为 HeaderSignature 中每一项签名的版本前缀
+	SignatureVersion = "v2"
+
+	// DefaultTolerance 接收端允许的时间戳偏差
+	DefaultTolerance = 5 * time.Minute
+)
+
+var (
+	ErrMissingTimestamp    = errors.New("webhook: missing timestamp")
+	ErrInvalidTimestamp    = errors.New("webhook: invalid timestamp")
+	ErrTimestampOutOfRange = errors.New("webhook: timestamp outside tolerance")
+	ErrMissingSignature    = errors.New("webhook: missing signature")
+	ErrNoSecrets           = errors.New("webhook: no secrets configured")
+	ErrSignatureMismatch   = errors.New("webhook: no matching signature")
+)
+
+// Signature 表示 HeaderSignature 中的一项：密钥 ID + 十六进制 HMAC
+type Signature struct {
+	KeyID string
+	Value string
+}
+
+// KeyID 由 secret 派生出稳定的密钥 ID（sha256 前 16 位十六进制），不泄露 secret 本身
+func KeyID(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+// SignLegacy 计算旧版 X-Signature：HMAC-SHA256(payload, secret)
+func SignLegacy(payload []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Sign 计算 v2 签名：HMAC-SHA256("<timestamp>.<payload>", secret)
+func Sign(timestamp int64, payload []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// FormatSignatureHeader 为每个非空 secret 生成一项签名，格式 "v2=<kid>:<hex>, v2=<kid>:<hex>"
+func FormatSignatureHeader(timestamp int64, payload []byte, secrets ...string) string {
+	parts := make([]string, 0, len(secrets))
+	for _, s := range secrets {
+		if s == "" {
+			continue
+		}
+		parts = append(parts, SignatureVersion+"="+KeyID(s)+":"+Sign(timestamp, payload, s))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// ParseSignatureHeader 解析 HeaderSignature，忽略未知版本的项
+func ParseSignatureHeader(val string) []Signature {
+	var out []Signature
+	for _, item := range strings.Split(val, ",") {
+		item = strings.TrimSpace(item)
+		version, rest, ok := strings.Cut(item, "=")
+		if !ok || version != SignatureVersion {
+			continue
+		}
+		kid, sig, ok := strings.Cut(rest, ":")
+		if !ok || sig == "" {
+			continue
+		}
+		out = append(out, Signature{KeyID: kid, Value: sig})
+	}
+	return out
+}
+
+// SetHeaders 为出站请求写入时间戳与签名头
+func SetHeaders(h http.Header, timestamp int64, payload []byte, secrets ...string) {
+	sig := FormatSignatureHeader(timestamp, payload, secrets...)
+	if sig == "" {
+		return
+	}
+	h.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	h.Set(HeaderSignature, sig)
+}
+
+// Verifier 校验收到的 webhook；Secrets 可同时放入新旧两个 secret 以覆盖轮换期
+type Verifier struct {
+	Secrets   []string
+	Tolerance time.Duration
+	Now       func() time.Time
+}
+
+// NewVerifier 使用默认时间容忍度创建 Verifier
+func NewVerifier(secrets ...string) *Verifier {
+	return &Verifier{Secrets: secrets, Tolerance: DefaultTolerance}
+}
+
+// Verify 校验时间戳与签名，返回命中的密钥 ID
+func (v *Verifier) Verify(h http.Header, payload []byte) (string, error) {
+	if len(v.Secrets) == 0 {
+		return "", ErrNoSecrets
+	}
+	tsStr := h.Get(HeaderTimestamp)
+	if tsStr == "" {
+		return "", ErrMissingTimestamp
+	}
+	ts, err := strconv.ParseInt(tsStr, 10, 64)
+	if err != nil {
+		return "", ErrInvalidTimestamp
+	}
+
+	now := time.Now
+	if v.Now != nil {
+		now = v.Now
+	}
+	tolerance := v.Tolerance
+	if tolerance <= 0 {
+		tolerance = DefaultTolerance
+	}
+	skew := now().Sub(time.Unix(ts, 0))
+	if skew < 0 {
+		skew = -skew
+	}
+	if skew > tolerance {
+		return "", ErrTimestampOutOfRange
+	}
+
+	sigs := ParseSignatureHeader(h.Get(HeaderSignature))
+	if len(sigs) == 0 {
+		return "", ErrMissingSignature
+	}
+	for _, secret := range v.Secrets {
+		if secret == "" {
+			continue
+		}
+		kid := KeyID(secret)
+		expected := Sign(ts, payload, secret)
+		for _, s := range sigs {
+			if s.KeyID != kid {
+				continue
+			}
+			if hmac.Equal([]byte(s.Value), []byte(expected)) {
+				return kid, nil
+			}
+		}
+	}
+	return "", ErrSignatureMismatch
+}
+
+// VerifyRequest 读取并校验请求体，校验后 r.Body 仍可再次读取
+func (v *Verifier) VerifyRequest(r *http.Request) ([]byte, string, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	_ = r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	kid, err := v.Verify(r.Header, body)
+	return body, kid, err
+}
@@ -0,0 +1,67 @@
+package webhook
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerify(t *testing.T) {
+	payload := []byte(`{"token":"abc"}`)
+	now := time.Unix(1700000000, 0)
+	fixed := func() time.Time { return now }
+
+	signed := func(ts int64, secrets ...string) http.Header {
+		h := http.Header{}
+		SetHeaders(h, ts, payload, secrets...)
+		return h
+	}
+
+	t.Run("current secret", func(t *testing.T) {
+		v := &Verifier{Secrets: []string{"new"}, Now: fixed}
+		kid, err := v.Verify(signed(now.Unix(), "new"), payload)
+		assert.NoError(t, err)
+		assert.Equal(t, KeyID("new"), kid)
+	})
+
+	t.Run("rotation overlap", func(t *testing.T) {
+		v := &Verifier{Secrets: []string{"old"}, Now: fixed}
+		kid, err := v.Verify(signed(now.Unix(), "new", "old"), payload)
+		assert.NoError(t, err)
+		assert.Equal(t, KeyID("old"), kid)
+	})
+
+	t.Run("replayed", func(t *testing.T) {
+		v := &Verifier{Secrets: []string{"new"}, Now: fixed}
+		_, err := v.Verify(signed(now.Add(-10*time.Minute).Unix(), "new"), payload)
+		assert.ErrorIs(t, err, ErrTimestampOutOfRange)
+	})
+
+	t.Run("tampered body", func(t *testing.T) {
+		v := &Verifier{Secrets: []string{"new"}, Now: fixed}
+		_, err := v.Verify(signed(now.Unix(), "new"), []byte(`{"token":"xyz"}`))
+		assert.ErrorIs(t, err, ErrSignatureMismatch)
+	})
+
+	t.Run("timestamp swapped", func(t *testing.T) {
+		v := &Verifier{Secrets: []string{"new"}, Now: fixed}
+		h := signed(now.Unix()-1, "new")
+		h.Set(HeaderTimestamp, strconv.FormatInt(now.Unix(), 10))
+		_, err := v.Verify(h, payload)
+		assert.ErrorIs(t, err, ErrSignatureMismatch)
+	})
+
+	t.Run("missing headers", func(t *testing.T) {
+		v := &Verifier{Secrets: []string{"new"}, Now: fixed}
+		_, err := v.Verify(http.Header{}, payload)
+		assert.ErrorIs(t, err, ErrMissingTimestamp)
+	})
+}
+
+func TestParseSignatureHeader(t *testing.T) {
+	sigs := ParseSignatureHeader("v2=aaa:111, v3=bbb:222, v2=ccc:333, junk")
+	assert.Equal(t, []Signature{{KeyID: "aaa", Value: "111"}, {KeyID: "ccc", Value: "333"}}, sigs)
+}